@@ -0,0 +1,256 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: phoneusage/v1/cycle.proto
+
+package phoneusagev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Cycle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CycleId       string                 `protobuf:"bytes,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	StartDate     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cycle) Reset() {
+	*x = Cycle{}
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cycle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cycle) ProtoMessage() {}
+
+func (x *Cycle) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cycle.ProtoReflect.Descriptor instead.
+func (*Cycle) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_cycle_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Cycle) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+func (x *Cycle) GetStartDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartDate
+	}
+	return nil
+}
+
+func (x *Cycle) GetEndDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndDate
+	}
+	return nil
+}
+
+type GetCycleHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Mdn           string                 `protobuf:"bytes,2,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCycleHistoryRequest) Reset() {
+	*x = GetCycleHistoryRequest{}
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCycleHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCycleHistoryRequest) ProtoMessage() {}
+
+func (x *GetCycleHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCycleHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetCycleHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_cycle_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetCycleHistoryRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetCycleHistoryRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+type GetCycleHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cycles        []*Cycle               `protobuf:"bytes,1,rep,name=cycles,proto3" json:"cycles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCycleHistoryResponse) Reset() {
+	*x = GetCycleHistoryResponse{}
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCycleHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCycleHistoryResponse) ProtoMessage() {}
+
+func (x *GetCycleHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_cycle_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCycleHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetCycleHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_cycle_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCycleHistoryResponse) GetCycles() []*Cycle {
+	if x != nil {
+		return x.Cycles
+	}
+	return nil
+}
+
+var File_phoneusage_v1_cycle_proto protoreflect.FileDescriptor
+
+const file_phoneusage_v1_cycle_proto_rawDesc = "" +
+	"\n" +
+	"\x19phoneusage/v1/cycle.proto\x12\rphoneusage.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x94\x01\n" +
+	"\x05Cycle\x12\x19\n" +
+	"\bcycle_id\x18\x01 \x01(\tR\acycleId\x129\n" +
+	"\n" +
+	"start_date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x125\n" +
+	"\bend_date\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aendDate\"C\n" +
+	"\x16GetCycleHistoryRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03mdn\x18\x02 \x01(\tR\x03mdn\"G\n" +
+	"\x17GetCycleHistoryResponse\x12,\n" +
+	"\x06cycles\x18\x01 \x03(\v2\x14.phoneusage.v1.CycleR\x06cycles2\xc5\x01\n" +
+	"\fCycleService\x12`\n" +
+	"\x0fGetCycleHistory\x12%.phoneusage.v1.GetCycleHistoryRequest\x1a&.phoneusage.v1.GetCycleHistoryResponse\x12S\n" +
+	"\x12ExportCycleHistory\x12%.phoneusage.v1.GetCycleHistoryRequest\x1a\x14.phoneusage.v1.Cycle0\x01BLZJgithub.com/bowe99/phone-usage-service/api/proto/phoneusage/v1;phoneusagev1b\x06proto3"
+
+var (
+	file_phoneusage_v1_cycle_proto_rawDescOnce sync.Once
+	file_phoneusage_v1_cycle_proto_rawDescData []byte
+)
+
+func file_phoneusage_v1_cycle_proto_rawDescGZIP() []byte {
+	file_phoneusage_v1_cycle_proto_rawDescOnce.Do(func() {
+		file_phoneusage_v1_cycle_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_phoneusage_v1_cycle_proto_rawDesc), len(file_phoneusage_v1_cycle_proto_rawDesc)))
+	})
+	return file_phoneusage_v1_cycle_proto_rawDescData
+}
+
+var file_phoneusage_v1_cycle_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_phoneusage_v1_cycle_proto_goTypes = []any{
+	(*Cycle)(nil),                   // 0: phoneusage.v1.Cycle
+	(*GetCycleHistoryRequest)(nil),  // 1: phoneusage.v1.GetCycleHistoryRequest
+	(*GetCycleHistoryResponse)(nil), // 2: phoneusage.v1.GetCycleHistoryResponse
+	(*timestamppb.Timestamp)(nil),   // 3: google.protobuf.Timestamp
+}
+var file_phoneusage_v1_cycle_proto_depIdxs = []int32{
+	3, // 0: phoneusage.v1.Cycle.start_date:type_name -> google.protobuf.Timestamp
+	3, // 1: phoneusage.v1.Cycle.end_date:type_name -> google.protobuf.Timestamp
+	0, // 2: phoneusage.v1.GetCycleHistoryResponse.cycles:type_name -> phoneusage.v1.Cycle
+	1, // 3: phoneusage.v1.CycleService.GetCycleHistory:input_type -> phoneusage.v1.GetCycleHistoryRequest
+	1, // 4: phoneusage.v1.CycleService.ExportCycleHistory:input_type -> phoneusage.v1.GetCycleHistoryRequest
+	2, // 5: phoneusage.v1.CycleService.GetCycleHistory:output_type -> phoneusage.v1.GetCycleHistoryResponse
+	0, // 6: phoneusage.v1.CycleService.ExportCycleHistory:output_type -> phoneusage.v1.Cycle
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_phoneusage_v1_cycle_proto_init() }
+func file_phoneusage_v1_cycle_proto_init() {
+	if File_phoneusage_v1_cycle_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_phoneusage_v1_cycle_proto_rawDesc), len(file_phoneusage_v1_cycle_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_phoneusage_v1_cycle_proto_goTypes,
+		DependencyIndexes: file_phoneusage_v1_cycle_proto_depIdxs,
+		MessageInfos:      file_phoneusage_v1_cycle_proto_msgTypes,
+	}.Build()
+	File_phoneusage_v1_cycle_proto = out.File
+	file_phoneusage_v1_cycle_proto_goTypes = nil
+	file_phoneusage_v1_cycle_proto_depIdxs = nil
+}
@@ -0,0 +1,171 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: phoneusage/v1/cycle.proto
+
+package phoneusagev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CycleService_GetCycleHistory_FullMethodName    = "/phoneusage.v1.CycleService/GetCycleHistory"
+	CycleService_ExportCycleHistory_FullMethodName = "/phoneusage.v1.CycleService/ExportCycleHistory"
+)
+
+// CycleServiceClient is the client API for CycleService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CycleService exposes the billing cycles of a line.
+type CycleServiceClient interface {
+	GetCycleHistory(ctx context.Context, in *GetCycleHistoryRequest, opts ...grpc.CallOption) (*GetCycleHistoryResponse, error)
+	// ExportCycleHistory streams the same cycles as GetCycleHistory, newest
+	// first, straight from the database cursor.
+	ExportCycleHistory(ctx context.Context, in *GetCycleHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cycle], error)
+}
+
+type cycleServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCycleServiceClient(cc grpc.ClientConnInterface) CycleServiceClient {
+	return &cycleServiceClient{cc}
+}
+
+func (c *cycleServiceClient) GetCycleHistory(ctx context.Context, in *GetCycleHistoryRequest, opts ...grpc.CallOption) (*GetCycleHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCycleHistoryResponse)
+	err := c.cc.Invoke(ctx, CycleService_GetCycleHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cycleServiceClient) ExportCycleHistory(ctx context.Context, in *GetCycleHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cycle], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CycleService_ServiceDesc.Streams[0], CycleService_ExportCycleHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetCycleHistoryRequest, Cycle]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CycleService_ExportCycleHistoryClient = grpc.ServerStreamingClient[Cycle]
+
+// CycleServiceServer is the server API for CycleService service.
+// All implementations must embed UnimplementedCycleServiceServer
+// for forward compatibility.
+//
+// CycleService exposes the billing cycles of a line.
+type CycleServiceServer interface {
+	GetCycleHistory(context.Context, *GetCycleHistoryRequest) (*GetCycleHistoryResponse, error)
+	// ExportCycleHistory streams the same cycles as GetCycleHistory, newest
+	// first, straight from the database cursor.
+	ExportCycleHistory(*GetCycleHistoryRequest, grpc.ServerStreamingServer[Cycle]) error
+	mustEmbedUnimplementedCycleServiceServer()
+}
+
+// UnimplementedCycleServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCycleServiceServer struct{}
+
+func (UnimplementedCycleServiceServer) GetCycleHistory(context.Context, *GetCycleHistoryRequest) (*GetCycleHistoryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCycleHistory not implemented")
+}
+func (UnimplementedCycleServiceServer) ExportCycleHistory(*GetCycleHistoryRequest, grpc.ServerStreamingServer[Cycle]) error {
+	return status.Error(codes.Unimplemented, "method ExportCycleHistory not implemented")
+}
+func (UnimplementedCycleServiceServer) mustEmbedUnimplementedCycleServiceServer() {}
+func (UnimplementedCycleServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeCycleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CycleServiceServer will
+// result in compilation errors.
+type UnsafeCycleServiceServer interface {
+	mustEmbedUnimplementedCycleServiceServer()
+}
+
+func RegisterCycleServiceServer(s grpc.ServiceRegistrar, srv CycleServiceServer) {
+	// If the following call panics, it indicates UnimplementedCycleServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CycleService_ServiceDesc, srv)
+}
+
+func _CycleService_GetCycleHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCycleHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CycleServiceServer).GetCycleHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CycleService_GetCycleHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CycleServiceServer).GetCycleHistory(ctx, req.(*GetCycleHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CycleService_ExportCycleHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetCycleHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CycleServiceServer).ExportCycleHistory(m, &grpc.GenericServerStream[GetCycleHistoryRequest, Cycle]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CycleService_ExportCycleHistoryServer = grpc.ServerStreamingServer[Cycle]
+
+// CycleService_ServiceDesc is the grpc.ServiceDesc for CycleService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CycleService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "phoneusage.v1.CycleService",
+	HandlerType: (*CycleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCycleHistory",
+			Handler:    _CycleService_GetCycleHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportCycleHistory",
+			Handler:       _CycleService_ExportCycleHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "phoneusage/v1/cycle.proto",
+}
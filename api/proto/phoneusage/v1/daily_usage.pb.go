@@ -0,0 +1,856 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: phoneusage/v1/daily_usage.proto
+
+package phoneusagev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DailyUsage struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Date   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	UsedMb float64                `protobuf:"fixed64,2,opt,name=used_mb,json=usedMb,proto3" json:"used_mb,omitempty"`
+	// Only set by ExportUsage, which spans owners
+	UserId        string `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Mdn           string `protobuf:"bytes,4,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DailyUsage) Reset() {
+	*x = DailyUsage{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DailyUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyUsage) ProtoMessage() {}
+
+func (x *DailyUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyUsage.ProtoReflect.Descriptor instead.
+func (*DailyUsage) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DailyUsage) GetDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Date
+	}
+	return nil
+}
+
+func (x *DailyUsage) GetUsedMb() float64 {
+	if x != nil {
+		return x.UsedMb
+	}
+	return 0
+}
+
+func (x *DailyUsage) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DailyUsage) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+type LineOwnerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Mdn           string                 `protobuf:"bytes,2,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LineOwnerRequest) Reset() {
+	*x = LineOwnerRequest{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LineOwnerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LineOwnerRequest) ProtoMessage() {}
+
+func (x *LineOwnerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LineOwnerRequest.ProtoReflect.Descriptor instead.
+func (*LineOwnerRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LineOwnerRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LineOwnerRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+type RecordUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Mdn           string                 `protobuf:"bytes,2,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	UsageDate     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=usage_date,json=usageDate,proto3" json:"usage_date,omitempty"`
+	UsedMb        float64                `protobuf:"fixed64,4,opt,name=used_mb,json=usedMb,proto3" json:"used_mb,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordUsageRequest) Reset() {
+	*x = RecordUsageRequest{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordUsageRequest) ProtoMessage() {}
+
+func (x *RecordUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordUsageRequest.ProtoReflect.Descriptor instead.
+func (*RecordUsageRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RecordUsageRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+func (x *RecordUsageRequest) GetUsageDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UsageDate
+	}
+	return nil
+}
+
+func (x *RecordUsageRequest) GetUsedMb() float64 {
+	if x != nil {
+		return x.UsedMb
+	}
+	return 0
+}
+
+type GetCurrentCycleUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Usage         []*DailyUsage          `protobuf:"bytes,1,rep,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCurrentCycleUsageResponse) Reset() {
+	*x = GetCurrentCycleUsageResponse{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentCycleUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentCycleUsageResponse) ProtoMessage() {}
+
+func (x *GetCurrentCycleUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentCycleUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentCycleUsageResponse) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCurrentCycleUsageResponse) GetUsage() []*DailyUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type GetCycleSummaryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mdn           string                 `protobuf:"bytes,1,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	CycleId       string                 `protobuf:"bytes,2,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCycleSummaryRequest) Reset() {
+	*x = GetCycleSummaryRequest{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCycleSummaryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCycleSummaryRequest) ProtoMessage() {}
+
+func (x *GetCycleSummaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCycleSummaryRequest.ProtoReflect.Descriptor instead.
+func (*GetCycleSummaryRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetCycleSummaryRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+func (x *GetCycleSummaryRequest) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+type CycleSummary struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	CycleId   string                 `protobuf:"bytes,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	StartDate *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	TotalMb   float64                `protobuf:"fixed64,4,opt,name=total_mb,json=totalMb,proto3" json:"total_mb,omitempty"`
+	// Unset when no usage has been recorded in the cycle
+	PeakDate      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=peak_date,json=peakDate,proto3" json:"peak_date,omitempty"`
+	PeakMb        float64                `protobuf:"fixed64,6,opt,name=peak_mb,json=peakMb,proto3" json:"peak_mb,omitempty"`
+	DayCount      int32                  `protobuf:"varint,7,opt,name=day_count,json=dayCount,proto3" json:"day_count,omitempty"`
+	LastUpdated   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CycleSummary) Reset() {
+	*x = CycleSummary{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleSummary) ProtoMessage() {}
+
+func (x *CycleSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleSummary.ProtoReflect.Descriptor instead.
+func (*CycleSummary) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CycleSummary) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+func (x *CycleSummary) GetStartDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartDate
+	}
+	return nil
+}
+
+func (x *CycleSummary) GetEndDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndDate
+	}
+	return nil
+}
+
+func (x *CycleSummary) GetTotalMb() float64 {
+	if x != nil {
+		return x.TotalMb
+	}
+	return 0
+}
+
+func (x *CycleSummary) GetPeakDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PeakDate
+	}
+	return nil
+}
+
+func (x *CycleSummary) GetPeakMb() float64 {
+	if x != nil {
+		return x.PeakMb
+	}
+	return 0
+}
+
+func (x *CycleSummary) GetDayCount() int32 {
+	if x != nil {
+		return x.DayCount
+	}
+	return 0
+}
+
+func (x *CycleSummary) GetLastUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return nil
+}
+
+type GetUsageTrendsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Mdn   string                 `protobuf:"bytes,1,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	// Number of cycles to compare, 6 when unset
+	Cycles        int32 `protobuf:"varint,2,opt,name=cycles,proto3" json:"cycles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageTrendsRequest) Reset() {
+	*x = GetUsageTrendsRequest{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageTrendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageTrendsRequest) ProtoMessage() {}
+
+func (x *GetUsageTrendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageTrendsRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageTrendsRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetUsageTrendsRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+func (x *GetUsageTrendsRequest) GetCycles() int32 {
+	if x != nil {
+		return x.Cycles
+	}
+	return 0
+}
+
+type CycleTrend struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CycleId        string                 `protobuf:"bytes,1,opt,name=cycle_id,json=cycleId,proto3" json:"cycle_id,omitempty"`
+	StartDate      *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	Partial        bool                   `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	DaysElapsed    int32                  `protobuf:"varint,5,opt,name=days_elapsed,json=daysElapsed,proto3" json:"days_elapsed,omitempty"`
+	TotalMb        float64                `protobuf:"fixed64,6,opt,name=total_mb,json=totalMb,proto3" json:"total_mb,omitempty"`
+	AlignedMb      *float64               `protobuf:"fixed64,7,opt,name=aligned_mb,json=alignedMb,proto3,oneof" json:"aligned_mb,omitempty"`
+	AverageDailyMb float64                `protobuf:"fixed64,8,opt,name=average_daily_mb,json=averageDailyMb,proto3" json:"average_daily_mb,omitempty"`
+	DeltaMb        *float64               `protobuf:"fixed64,9,opt,name=delta_mb,json=deltaMb,proto3,oneof" json:"delta_mb,omitempty"`
+	PercentChange  *float64               `protobuf:"fixed64,10,opt,name=percent_change,json=percentChange,proto3,oneof" json:"percent_change,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CycleTrend) Reset() {
+	*x = CycleTrend{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CycleTrend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CycleTrend) ProtoMessage() {}
+
+func (x *CycleTrend) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CycleTrend.ProtoReflect.Descriptor instead.
+func (*CycleTrend) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CycleTrend) GetCycleId() string {
+	if x != nil {
+		return x.CycleId
+	}
+	return ""
+}
+
+func (x *CycleTrend) GetStartDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartDate
+	}
+	return nil
+}
+
+func (x *CycleTrend) GetEndDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndDate
+	}
+	return nil
+}
+
+func (x *CycleTrend) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *CycleTrend) GetDaysElapsed() int32 {
+	if x != nil {
+		return x.DaysElapsed
+	}
+	return 0
+}
+
+func (x *CycleTrend) GetTotalMb() float64 {
+	if x != nil {
+		return x.TotalMb
+	}
+	return 0
+}
+
+func (x *CycleTrend) GetAlignedMb() float64 {
+	if x != nil && x.AlignedMb != nil {
+		return *x.AlignedMb
+	}
+	return 0
+}
+
+func (x *CycleTrend) GetAverageDailyMb() float64 {
+	if x != nil {
+		return x.AverageDailyMb
+	}
+	return 0
+}
+
+func (x *CycleTrend) GetDeltaMb() float64 {
+	if x != nil && x.DeltaMb != nil {
+		return *x.DeltaMb
+	}
+	return 0
+}
+
+func (x *CycleTrend) GetPercentChange() float64 {
+	if x != nil && x.PercentChange != nil {
+		return *x.PercentChange
+	}
+	return 0
+}
+
+type UsageTrends struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mdn           string                 `protobuf:"bytes,1,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	AlignedDays   int32                  `protobuf:"varint,2,opt,name=aligned_days,json=alignedDays,proto3" json:"aligned_days,omitempty"`
+	Cycles        []*CycleTrend          `protobuf:"bytes,3,rep,name=cycles,proto3" json:"cycles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UsageTrends) Reset() {
+	*x = UsageTrends{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UsageTrends) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UsageTrends) ProtoMessage() {}
+
+func (x *UsageTrends) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UsageTrends.ProtoReflect.Descriptor instead.
+func (*UsageTrends) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UsageTrends) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+func (x *UsageTrends) GetAlignedDays() int32 {
+	if x != nil {
+		return x.AlignedDays
+	}
+	return 0
+}
+
+func (x *UsageTrends) GetCycles() []*CycleTrend {
+	if x != nil {
+		return x.Cycles
+	}
+	return nil
+}
+
+type ExportUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mdn           string                 `protobuf:"bytes,1,opt,name=mdn,proto3" json:"mdn,omitempty"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportUsageRequest) Reset() {
+	*x = ExportUsageRequest{}
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportUsageRequest) ProtoMessage() {}
+
+func (x *ExportUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_phoneusage_v1_daily_usage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportUsageRequest.ProtoReflect.Descriptor instead.
+func (*ExportUsageRequest) Descriptor() ([]byte, []int) {
+	return file_phoneusage_v1_daily_usage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExportUsageRequest) GetMdn() string {
+	if x != nil {
+		return x.Mdn
+	}
+	return ""
+}
+
+func (x *ExportUsageRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ExportUsageRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+var File_phoneusage_v1_daily_usage_proto protoreflect.FileDescriptor
+
+const file_phoneusage_v1_daily_usage_proto_rawDesc = "" +
+	"\n" +
+	"\x1fphoneusage/v1/daily_usage.proto\x12\rphoneusage.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x80\x01\n" +
+	"\n" +
+	"DailyUsage\x12.\n" +
+	"\x04date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04date\x12\x17\n" +
+	"\aused_mb\x18\x02 \x01(\x01R\x06usedMb\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03mdn\x18\x04 \x01(\tR\x03mdn\"=\n" +
+	"\x10LineOwnerRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03mdn\x18\x02 \x01(\tR\x03mdn\"\x93\x01\n" +
+	"\x12RecordUsageRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03mdn\x18\x02 \x01(\tR\x03mdn\x129\n" +
+	"\n" +
+	"usage_date\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tusageDate\x12\x17\n" +
+	"\aused_mb\x18\x04 \x01(\x01R\x06usedMb\"O\n" +
+	"\x1cGetCurrentCycleUsageResponse\x12/\n" +
+	"\x05usage\x18\x01 \x03(\v2\x19.phoneusage.v1.DailyUsageR\x05usage\"E\n" +
+	"\x16GetCycleSummaryRequest\x12\x10\n" +
+	"\x03mdn\x18\x01 \x01(\tR\x03mdn\x12\x19\n" +
+	"\bcycle_id\x18\x02 \x01(\tR\acycleId\"\xe4\x02\n" +
+	"\fCycleSummary\x12\x19\n" +
+	"\bcycle_id\x18\x01 \x01(\tR\acycleId\x129\n" +
+	"\n" +
+	"start_date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x125\n" +
+	"\bend_date\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aendDate\x12\x19\n" +
+	"\btotal_mb\x18\x04 \x01(\x01R\atotalMb\x127\n" +
+	"\tpeak_date\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bpeakDate\x12\x17\n" +
+	"\apeak_mb\x18\x06 \x01(\x01R\x06peakMb\x12\x1b\n" +
+	"\tday_count\x18\a \x01(\x05R\bdayCount\x12=\n" +
+	"\flast_updated\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated\"A\n" +
+	"\x15GetUsageTrendsRequest\x12\x10\n" +
+	"\x03mdn\x18\x01 \x01(\tR\x03mdn\x12\x16\n" +
+	"\x06cycles\x18\x02 \x01(\x05R\x06cycles\"\xba\x03\n" +
+	"\n" +
+	"CycleTrend\x12\x19\n" +
+	"\bcycle_id\x18\x01 \x01(\tR\acycleId\x129\n" +
+	"\n" +
+	"start_date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x125\n" +
+	"\bend_date\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aendDate\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\x12!\n" +
+	"\fdays_elapsed\x18\x05 \x01(\x05R\vdaysElapsed\x12\x19\n" +
+	"\btotal_mb\x18\x06 \x01(\x01R\atotalMb\x12\"\n" +
+	"\n" +
+	"aligned_mb\x18\a \x01(\x01H\x00R\talignedMb\x88\x01\x01\x12(\n" +
+	"\x10average_daily_mb\x18\b \x01(\x01R\x0eaverageDailyMb\x12\x1e\n" +
+	"\bdelta_mb\x18\t \x01(\x01H\x01R\adeltaMb\x88\x01\x01\x12*\n" +
+	"\x0epercent_change\x18\n" +
+	" \x01(\x01H\x02R\rpercentChange\x88\x01\x01B\r\n" +
+	"\v_aligned_mbB\v\n" +
+	"\t_delta_mbB\x11\n" +
+	"\x0f_percent_change\"u\n" +
+	"\vUsageTrends\x12\x10\n" +
+	"\x03mdn\x18\x01 \x01(\tR\x03mdn\x12!\n" +
+	"\faligned_days\x18\x02 \x01(\x05R\valignedDays\x121\n" +
+	"\x06cycles\x18\x03 \x03(\v2\x19.phoneusage.v1.CycleTrendR\x06cycles\"\x82\x01\n" +
+	"\x12ExportUsageRequest\x12\x10\n" +
+	"\x03mdn\x18\x01 \x01(\tR\x03mdn\x12.\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02to2\xf1\x04\n" +
+	"\x11DailyUsageService\x12K\n" +
+	"\vRecordUsage\x12!.phoneusage.v1.RecordUsageRequest\x1a\x19.phoneusage.v1.DailyUsage\x12d\n" +
+	"\x14GetCurrentCycleUsage\x12\x1f.phoneusage.v1.LineOwnerRequest\x1a+.phoneusage.v1.GetCurrentCycleUsageResponse\x12V\n" +
+	"\x16GetCurrentCycleSummary\x12\x1f.phoneusage.v1.LineOwnerRequest\x1a\x1b.phoneusage.v1.CycleSummary\x12U\n" +
+	"\x0fGetCycleSummary\x12%.phoneusage.v1.GetCycleSummaryRequest\x1a\x1b.phoneusage.v1.CycleSummary\x12R\n" +
+	"\x0eGetUsageTrends\x12$.phoneusage.v1.GetUsageTrendsRequest\x1a\x1a.phoneusage.v1.UsageTrends\x12W\n" +
+	"\x17ExportCurrentCycleUsage\x12\x1f.phoneusage.v1.LineOwnerRequest\x1a\x19.phoneusage.v1.DailyUsage0\x01\x12M\n" +
+	"\vExportUsage\x12!.phoneusage.v1.ExportUsageRequest\x1a\x19.phoneusage.v1.DailyUsage0\x01BLZJgithub.com/bowe99/phone-usage-service/api/proto/phoneusage/v1;phoneusagev1b\x06proto3"
+
+var (
+	file_phoneusage_v1_daily_usage_proto_rawDescOnce sync.Once
+	file_phoneusage_v1_daily_usage_proto_rawDescData []byte
+)
+
+func file_phoneusage_v1_daily_usage_proto_rawDescGZIP() []byte {
+	file_phoneusage_v1_daily_usage_proto_rawDescOnce.Do(func() {
+		file_phoneusage_v1_daily_usage_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_phoneusage_v1_daily_usage_proto_rawDesc), len(file_phoneusage_v1_daily_usage_proto_rawDesc)))
+	})
+	return file_phoneusage_v1_daily_usage_proto_rawDescData
+}
+
+var file_phoneusage_v1_daily_usage_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_phoneusage_v1_daily_usage_proto_goTypes = []any{
+	(*DailyUsage)(nil),                   // 0: phoneusage.v1.DailyUsage
+	(*LineOwnerRequest)(nil),             // 1: phoneusage.v1.LineOwnerRequest
+	(*RecordUsageRequest)(nil),           // 2: phoneusage.v1.RecordUsageRequest
+	(*GetCurrentCycleUsageResponse)(nil), // 3: phoneusage.v1.GetCurrentCycleUsageResponse
+	(*GetCycleSummaryRequest)(nil),       // 4: phoneusage.v1.GetCycleSummaryRequest
+	(*CycleSummary)(nil),                 // 5: phoneusage.v1.CycleSummary
+	(*GetUsageTrendsRequest)(nil),        // 6: phoneusage.v1.GetUsageTrendsRequest
+	(*CycleTrend)(nil),                   // 7: phoneusage.v1.CycleTrend
+	(*UsageTrends)(nil),                  // 8: phoneusage.v1.UsageTrends
+	(*ExportUsageRequest)(nil),           // 9: phoneusage.v1.ExportUsageRequest
+	(*timestamppb.Timestamp)(nil),        // 10: google.protobuf.Timestamp
+}
+var file_phoneusage_v1_daily_usage_proto_depIdxs = []int32{
+	10, // 0: phoneusage.v1.DailyUsage.date:type_name -> google.protobuf.Timestamp
+	10, // 1: phoneusage.v1.RecordUsageRequest.usage_date:type_name -> google.protobuf.Timestamp
+	0,  // 2: phoneusage.v1.GetCurrentCycleUsageResponse.usage:type_name -> phoneusage.v1.DailyUsage
+	10, // 3: phoneusage.v1.CycleSummary.start_date:type_name -> google.protobuf.Timestamp
+	10, // 4: phoneusage.v1.CycleSummary.end_date:type_name -> google.protobuf.Timestamp
+	10, // 5: phoneusage.v1.CycleSummary.peak_date:type_name -> google.protobuf.Timestamp
+	10, // 6: phoneusage.v1.CycleSummary.last_updated:type_name -> google.protobuf.Timestamp
+	10, // 7: phoneusage.v1.CycleTrend.start_date:type_name -> google.protobuf.Timestamp
+	10, // 8: phoneusage.v1.CycleTrend.end_date:type_name -> google.protobuf.Timestamp
+	7,  // 9: phoneusage.v1.UsageTrends.cycles:type_name -> phoneusage.v1.CycleTrend
+	10, // 10: phoneusage.v1.ExportUsageRequest.from:type_name -> google.protobuf.Timestamp
+	10, // 11: phoneusage.v1.ExportUsageRequest.to:type_name -> google.protobuf.Timestamp
+	2,  // 12: phoneusage.v1.DailyUsageService.RecordUsage:input_type -> phoneusage.v1.RecordUsageRequest
+	1,  // 13: phoneusage.v1.DailyUsageService.GetCurrentCycleUsage:input_type -> phoneusage.v1.LineOwnerRequest
+	1,  // 14: phoneusage.v1.DailyUsageService.GetCurrentCycleSummary:input_type -> phoneusage.v1.LineOwnerRequest
+	4,  // 15: phoneusage.v1.DailyUsageService.GetCycleSummary:input_type -> phoneusage.v1.GetCycleSummaryRequest
+	6,  // 16: phoneusage.v1.DailyUsageService.GetUsageTrends:input_type -> phoneusage.v1.GetUsageTrendsRequest
+	1,  // 17: phoneusage.v1.DailyUsageService.ExportCurrentCycleUsage:input_type -> phoneusage.v1.LineOwnerRequest
+	9,  // 18: phoneusage.v1.DailyUsageService.ExportUsage:input_type -> phoneusage.v1.ExportUsageRequest
+	0,  // 19: phoneusage.v1.DailyUsageService.RecordUsage:output_type -> phoneusage.v1.DailyUsage
+	3,  // 20: phoneusage.v1.DailyUsageService.GetCurrentCycleUsage:output_type -> phoneusage.v1.GetCurrentCycleUsageResponse
+	5,  // 21: phoneusage.v1.DailyUsageService.GetCurrentCycleSummary:output_type -> phoneusage.v1.CycleSummary
+	5,  // 22: phoneusage.v1.DailyUsageService.GetCycleSummary:output_type -> phoneusage.v1.CycleSummary
+	8,  // 23: phoneusage.v1.DailyUsageService.GetUsageTrends:output_type -> phoneusage.v1.UsageTrends
+	0,  // 24: phoneusage.v1.DailyUsageService.ExportCurrentCycleUsage:output_type -> phoneusage.v1.DailyUsage
+	0,  // 25: phoneusage.v1.DailyUsageService.ExportUsage:output_type -> phoneusage.v1.DailyUsage
+	19, // [19:26] is the sub-list for method output_type
+	12, // [12:19] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_phoneusage_v1_daily_usage_proto_init() }
+func file_phoneusage_v1_daily_usage_proto_init() {
+	if File_phoneusage_v1_daily_usage_proto != nil {
+		return
+	}
+	file_phoneusage_v1_daily_usage_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_phoneusage_v1_daily_usage_proto_rawDesc), len(file_phoneusage_v1_daily_usage_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_phoneusage_v1_daily_usage_proto_goTypes,
+		DependencyIndexes: file_phoneusage_v1_daily_usage_proto_depIdxs,
+		MessageInfos:      file_phoneusage_v1_daily_usage_proto_msgTypes,
+	}.Build()
+	File_phoneusage_v1_daily_usage_proto = out.File
+	file_phoneusage_v1_daily_usage_proto_goTypes = nil
+	file_phoneusage_v1_daily_usage_proto_depIdxs = nil
+}
@@ -0,0 +1,370 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: phoneusage/v1/daily_usage.proto
+
+package phoneusagev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DailyUsageService_RecordUsage_FullMethodName             = "/phoneusage.v1.DailyUsageService/RecordUsage"
+	DailyUsageService_GetCurrentCycleUsage_FullMethodName    = "/phoneusage.v1.DailyUsageService/GetCurrentCycleUsage"
+	DailyUsageService_GetCurrentCycleSummary_FullMethodName  = "/phoneusage.v1.DailyUsageService/GetCurrentCycleSummary"
+	DailyUsageService_GetCycleSummary_FullMethodName         = "/phoneusage.v1.DailyUsageService/GetCycleSummary"
+	DailyUsageService_GetUsageTrends_FullMethodName          = "/phoneusage.v1.DailyUsageService/GetUsageTrends"
+	DailyUsageService_ExportCurrentCycleUsage_FullMethodName = "/phoneusage.v1.DailyUsageService/ExportCurrentCycleUsage"
+	DailyUsageService_ExportUsage_FullMethodName             = "/phoneusage.v1.DailyUsageService/ExportUsage"
+)
+
+// DailyUsageServiceClient is the client API for DailyUsageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// DailyUsageService records and reports the daily data usage of a line.
+// Dates are timestamps at midnight UTC.
+type DailyUsageServiceClient interface {
+	// RecordUsage stores the usage of a day, replacing any earlier value.
+	RecordUsage(ctx context.Context, in *RecordUsageRequest, opts ...grpc.CallOption) (*DailyUsage, error)
+	GetCurrentCycleUsage(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (*GetCurrentCycleUsageResponse, error)
+	GetCurrentCycleSummary(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (*CycleSummary, error)
+	GetCycleSummary(ctx context.Context, in *GetCycleSummaryRequest, opts ...grpc.CallOption) (*CycleSummary, error)
+	GetUsageTrends(ctx context.Context, in *GetUsageTrendsRequest, opts ...grpc.CallOption) (*UsageTrends, error)
+	// ExportCurrentCycleUsage streams the daily usage of the current cycle.
+	ExportCurrentCycleUsage(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyUsage], error)
+	// ExportUsage streams every daily usage record of a line between two dates
+	// (inclusive), across all owners of the line.
+	ExportUsage(ctx context.Context, in *ExportUsageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyUsage], error)
+}
+
+type dailyUsageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDailyUsageServiceClient(cc grpc.ClientConnInterface) DailyUsageServiceClient {
+	return &dailyUsageServiceClient{cc}
+}
+
+func (c *dailyUsageServiceClient) RecordUsage(ctx context.Context, in *RecordUsageRequest, opts ...grpc.CallOption) (*DailyUsage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DailyUsage)
+	err := c.cc.Invoke(ctx, DailyUsageService_RecordUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dailyUsageServiceClient) GetCurrentCycleUsage(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (*GetCurrentCycleUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCurrentCycleUsageResponse)
+	err := c.cc.Invoke(ctx, DailyUsageService_GetCurrentCycleUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dailyUsageServiceClient) GetCurrentCycleSummary(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (*CycleSummary, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CycleSummary)
+	err := c.cc.Invoke(ctx, DailyUsageService_GetCurrentCycleSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dailyUsageServiceClient) GetCycleSummary(ctx context.Context, in *GetCycleSummaryRequest, opts ...grpc.CallOption) (*CycleSummary, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CycleSummary)
+	err := c.cc.Invoke(ctx, DailyUsageService_GetCycleSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dailyUsageServiceClient) GetUsageTrends(ctx context.Context, in *GetUsageTrendsRequest, opts ...grpc.CallOption) (*UsageTrends, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UsageTrends)
+	err := c.cc.Invoke(ctx, DailyUsageService_GetUsageTrends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dailyUsageServiceClient) ExportCurrentCycleUsage(ctx context.Context, in *LineOwnerRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyUsage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DailyUsageService_ServiceDesc.Streams[0], DailyUsageService_ExportCurrentCycleUsage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LineOwnerRequest, DailyUsage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DailyUsageService_ExportCurrentCycleUsageClient = grpc.ServerStreamingClient[DailyUsage]
+
+func (c *dailyUsageServiceClient) ExportUsage(ctx context.Context, in *ExportUsageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyUsage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DailyUsageService_ServiceDesc.Streams[1], DailyUsageService_ExportUsage_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportUsageRequest, DailyUsage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DailyUsageService_ExportUsageClient = grpc.ServerStreamingClient[DailyUsage]
+
+// DailyUsageServiceServer is the server API for DailyUsageService service.
+// All implementations must embed UnimplementedDailyUsageServiceServer
+// for forward compatibility.
+//
+// DailyUsageService records and reports the daily data usage of a line.
+// Dates are timestamps at midnight UTC.
+type DailyUsageServiceServer interface {
+	// RecordUsage stores the usage of a day, replacing any earlier value.
+	RecordUsage(context.Context, *RecordUsageRequest) (*DailyUsage, error)
+	GetCurrentCycleUsage(context.Context, *LineOwnerRequest) (*GetCurrentCycleUsageResponse, error)
+	GetCurrentCycleSummary(context.Context, *LineOwnerRequest) (*CycleSummary, error)
+	GetCycleSummary(context.Context, *GetCycleSummaryRequest) (*CycleSummary, error)
+	GetUsageTrends(context.Context, *GetUsageTrendsRequest) (*UsageTrends, error)
+	// ExportCurrentCycleUsage streams the daily usage of the current cycle.
+	ExportCurrentCycleUsage(*LineOwnerRequest, grpc.ServerStreamingServer[DailyUsage]) error
+	// ExportUsage streams every daily usage record of a line between two dates
+	// (inclusive), across all owners of the line.
+	ExportUsage(*ExportUsageRequest, grpc.ServerStreamingServer[DailyUsage]) error
+	mustEmbedUnimplementedDailyUsageServiceServer()
+}
+
+// UnimplementedDailyUsageServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDailyUsageServiceServer struct{}
+
+func (UnimplementedDailyUsageServiceServer) RecordUsage(context.Context, *RecordUsageRequest) (*DailyUsage, error) {
+	return nil, status.Error(codes.Unimplemented, "method RecordUsage not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) GetCurrentCycleUsage(context.Context, *LineOwnerRequest) (*GetCurrentCycleUsageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCurrentCycleUsage not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) GetCurrentCycleSummary(context.Context, *LineOwnerRequest) (*CycleSummary, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCurrentCycleSummary not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) GetCycleSummary(context.Context, *GetCycleSummaryRequest) (*CycleSummary, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCycleSummary not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) GetUsageTrends(context.Context, *GetUsageTrendsRequest) (*UsageTrends, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetUsageTrends not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) ExportCurrentCycleUsage(*LineOwnerRequest, grpc.ServerStreamingServer[DailyUsage]) error {
+	return status.Error(codes.Unimplemented, "method ExportCurrentCycleUsage not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) ExportUsage(*ExportUsageRequest, grpc.ServerStreamingServer[DailyUsage]) error {
+	return status.Error(codes.Unimplemented, "method ExportUsage not implemented")
+}
+func (UnimplementedDailyUsageServiceServer) mustEmbedUnimplementedDailyUsageServiceServer() {}
+func (UnimplementedDailyUsageServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeDailyUsageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DailyUsageServiceServer will
+// result in compilation errors.
+type UnsafeDailyUsageServiceServer interface {
+	mustEmbedUnimplementedDailyUsageServiceServer()
+}
+
+func RegisterDailyUsageServiceServer(s grpc.ServiceRegistrar, srv DailyUsageServiceServer) {
+	// If the following call panics, it indicates UnimplementedDailyUsageServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DailyUsageService_ServiceDesc, srv)
+}
+
+func _DailyUsageService_RecordUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DailyUsageServiceServer).RecordUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DailyUsageService_RecordUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DailyUsageServiceServer).RecordUsage(ctx, req.(*RecordUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DailyUsageService_GetCurrentCycleUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LineOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DailyUsageServiceServer).GetCurrentCycleUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DailyUsageService_GetCurrentCycleUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DailyUsageServiceServer).GetCurrentCycleUsage(ctx, req.(*LineOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DailyUsageService_GetCurrentCycleSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LineOwnerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DailyUsageServiceServer).GetCurrentCycleSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DailyUsageService_GetCurrentCycleSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DailyUsageServiceServer).GetCurrentCycleSummary(ctx, req.(*LineOwnerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DailyUsageService_GetCycleSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCycleSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DailyUsageServiceServer).GetCycleSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DailyUsageService_GetCycleSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DailyUsageServiceServer).GetCycleSummary(ctx, req.(*GetCycleSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DailyUsageService_GetUsageTrends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsageTrendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DailyUsageServiceServer).GetUsageTrends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DailyUsageService_GetUsageTrends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DailyUsageServiceServer).GetUsageTrends(ctx, req.(*GetUsageTrendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DailyUsageService_ExportCurrentCycleUsage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LineOwnerRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DailyUsageServiceServer).ExportCurrentCycleUsage(m, &grpc.GenericServerStream[LineOwnerRequest, DailyUsage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DailyUsageService_ExportCurrentCycleUsageServer = grpc.ServerStreamingServer[DailyUsage]
+
+func _DailyUsageService_ExportUsage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportUsageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DailyUsageServiceServer).ExportUsage(m, &grpc.GenericServerStream[ExportUsageRequest, DailyUsage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DailyUsageService_ExportUsageServer = grpc.ServerStreamingServer[DailyUsage]
+
+// DailyUsageService_ServiceDesc is the grpc.ServiceDesc for DailyUsageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DailyUsageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "phoneusage.v1.DailyUsageService",
+	HandlerType: (*DailyUsageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RecordUsage",
+			Handler:    _DailyUsageService_RecordUsage_Handler,
+		},
+		{
+			MethodName: "GetCurrentCycleUsage",
+			Handler:    _DailyUsageService_GetCurrentCycleUsage_Handler,
+		},
+		{
+			MethodName: "GetCurrentCycleSummary",
+			Handler:    _DailyUsageService_GetCurrentCycleSummary_Handler,
+		},
+		{
+			MethodName: "GetCycleSummary",
+			Handler:    _DailyUsageService_GetCycleSummary_Handler,
+		},
+		{
+			MethodName: "GetUsageTrends",
+			Handler:    _DailyUsageService_GetUsageTrends_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportCurrentCycleUsage",
+			Handler:       _DailyUsageService_ExportCurrentCycleUsage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportUsage",
+			Handler:       _DailyUsageService_ExportUsage_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "phoneusage/v1/daily_usage.proto",
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/infra/config"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
+)
+
+// Anonymizes the personal data of users deleted longer than
+// RETENTION_DELETED_USER_WINDOW ago. Users that are already anonymized are
+// skipped, so the command can be scheduled to run daily.
+func main() {
+	timeout := flag.Duration("timeout", 30*time.Minute, "maximum time the run may take")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	keys, err := secrets.SetupFileKeyProvider(cfg.PII.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load PII keys: %v", err)
+	}
+
+	db, err := database.Connect(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Disconnect(context.Background())
+
+	retentionService := service.SetupRetentionService(
+		repository.SetupUserRepository(db.Database, secrets.SetupEnvelope(keys)),
+		repository.SetupSessionRepository(db.Database),
+		service.RetentionOptions{Window: cfg.Retention.DeletedUserWindow},
+		service.SetupAuditService(repository.SetupAuditRepository(db.Database)),
+	)
+
+	anonymized, failures, err := retentionService.AnonymizeDeletedUsers(ctx)
+	if err != nil {
+		log.Fatalf("Failed to anonymize deleted users: %v", err)
+	}
+
+	for userID, err := range failures {
+		log.Printf("Failed to anonymize user %s: %v", userID, err)
+	}
+	log.Printf("Anonymized %d users, %d failed", anonymized, len(failures))
+}
@@ -3,27 +3,52 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/bowe99/phone-usage-service/internal/api/gql"
 	"github.com/bowe99/phone-usage-service/internal/api/handler"
 	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/api/rpc"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
 	"github.com/bowe99/phone-usage-service/internal/infra/config"
 	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
+	"github.com/bowe99/phone-usage-service/internal/infra/mail"
 	"github.com/bowe99/phone-usage-service/internal/infra/repository"
-	"github.com/gin-gonic/gin"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
+	"github.com/bowe99/phone-usage-service/internal/infra/statement"
 )
 
+// @title Phone Usage Service API
+// @version 1.0
+// @description Users, billing cycles and daily data usage of phone lines.
+// @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key of a machine client. "Authorization: Bearer <key>" is accepted as well.
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description "Bearer <token>" with a session token from POST /api/v1/auth/login or an API key.
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	piiKeys, err := secrets.SetupFileKeyProvider(cfg.PII.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load PII keys: %v", err)
+	}
+
 	log.Println("Connecting to MongoDB...")
 	db, err := database.Connect(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
 	if err != nil {
@@ -38,21 +63,146 @@ func main() {
 	}()
 	log.Println("Successfully connected to MongoDB")
 
-	userRepo := repository.SetupUserRepository(db.Database)
+	userRepo := repository.SetupUserRepository(db.Database, secrets.SetupEnvelope(piiKeys))
 	cycleRepo := repository.SetupCycleRepository(db.Database)
 	usageRepo := repository.SetupDailyUsageRepository(db.Database)
+	summaryRepo := repository.SetupCycleSummaryRepository(db.Database)
+	analyticsRepo := repository.SetupUsageAnalyticsRepository(db.Database)
+	invoiceRepo := repository.SetupInvoiceRepository(db.Database)
+	planRepo := repository.SetupPlanRepository(db.Database)
+	creditRepo := repository.SetupCreditRepository(db.Database)
+	apiKeyRepo := repository.SetupAPIKeyRepository(db.Database)
+	sessionRepo := repository.SetupSessionRepository(db.Database)
+	auditRepo := repository.SetupAuditRepository(db.Database)
+	userTokenRepo := repository.SetupUserTokenRepository(db.Database)
+	loginAttemptRepo := repository.SetupLoginAttemptRepository(db.Database)
+	dataExportRepo := repository.SetupDataExportRepository(db.Database)
+
+	// Change streams need a replica set; standalone servers fall back to
+	// in-process fan-out, which only sees usage recorded by this instance
+	detectCtx, cancelDetect := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
+	changeStreams := db.SupportsChangeStreams(detectCtx)
+	cancelDetect()
+	usageBroker := repository.SetupUsageEventBroker(db.Database, changeStreams)
+	log.Printf("Live usage events use change streams: %t", changeStreams)
 
 	// Initialize services (Application layer)
-	userService := service.SetupUserService(userRepo)
+	auditService := service.SetupAuditService(auditRepo)
+	apiKeyService := service.SetupAPIKeyService(apiKeyRepo, auditService)
+	mfaCipher, err := secrets.SetupAESCipher(cfg.Auth.MFAKey)
+	if err != nil {
+		log.Fatalf("Failed to set up MFA encryption: %v", err)
+	}
+	mfaService := service.SetupMFAService(userRepo, userTokenRepo, mfaCipher, service.MFAOptions{
+		Issuer:        cfg.Auth.MFAIssuer,
+		RequiredRoles: cfg.Auth.MFARequiredRoles,
+		ChallengeTTL:  cfg.Auth.MFAChallengeTTL,
+	}, auditService)
+	lockoutService := service.SetupLockoutService(loginAttemptRepo, userRepo, service.LockoutOptions{
+		MaxAccountFailures: cfg.Auth.Lockout.MaxAccountFailures,
+		MaxIPFailures:      cfg.Auth.Lockout.MaxIPFailures,
+		Window:             cfg.Auth.Lockout.Window,
+		LockDuration:       cfg.Auth.Lockout.Duration,
+		BaseDelay:          cfg.Auth.Lockout.BaseDelay,
+		MaxDelay:           cfg.Auth.Lockout.MaxDelay,
+	}, auditService)
+	authService := service.SetupAuthService(userRepo, sessionRepo, apiKeyService, mfaService, lockoutService, cfg.Auth.SessionTTL)
+	var mailSender domainrepo.MailSender
+	switch cfg.Mail.Sender {
+	case "smtp":
+		mailSender = mail.SetupSMTPSender(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	default:
+		mailSender = mail.SetupLogSender()
+	}
+	accountService := service.SetupAccountService(userRepo, userTokenRepo, sessionRepo, mailSender, service.AccountOptions{
+		VerificationTTL: cfg.Auth.VerificationTTL,
+		ResetTTL:        cfg.Auth.ResetTTL,
+		LinkBaseURL:     cfg.Mail.LinkBaseURL,
+	}, auditService)
+	userService := service.SetupUserService(userRepo, auditService, accountService)
+	retentionService := service.SetupRetentionService(userRepo, sessionRepo, service.RetentionOptions{
+		Window: cfg.Retention.DeletedUserWindow,
+	}, auditService)
 	cycleService := service.SetupCycleService(cycleRepo)
-	usageService := service.SetupDailyUsageService(usageRepo, cycleRepo)
+	usageService := service.SetupDailyUsageService(usageRepo, cycleRepo, summaryRepo, invoiceRepo, usageBroker, auditService)
+	analyticsService := service.SetupUsageAnalyticsService(analyticsRepo)
+	invoiceService := service.SetupInvoiceService(invoiceRepo, cycleRepo, usageRepo, planRepo, creditRepo, cfg.Billing.DefaultPlanID, auditService)
+	statementService := service.SetupStatementService(userRepo, cycleRepo, usageRepo)
+	dataExportService := service.SetupDataExportService(dataExportRepo, userRepo, cycleRepo, usageRepo, invoiceRepo, auditRepo, export.SetupZIPArchiver(), service.DataExportOptions{
+		TTL: cfg.Retention.DataExportTTL,
+	}, auditService)
+	streamService := service.SetupUsageStreamService(usageRepo, cycleRepo, usageBroker, cfg.Stream.AlertThresholdsMB)
+
+	if cfg.Auth.BootstrapAPIKey != "" {
+		bootstrapCtx, cancelBootstrap := context.WithTimeout(context.Background(), cfg.MongoDB.Timeout)
+		err := apiKeyService.EnsureKey(bootstrapCtx, "bootstrap", cfg.Auth.BootstrapAPIKey, model.Scopes)
+		cancelBootstrap()
+		if err != nil {
+			log.Fatalf("Failed to register bootstrap API key: %v", err)
+		}
+	}
+
+	statementRenderer, err := statement.SetupRenderer(cfg.Statement.TemplateDir)
+	if err != nil {
+		log.Fatalf("Failed to load statement templates: %v", err)
+	}
+
+	graphqlExecutor, err := gql.SetupExecutor(userService, cycleService, usageService, cfg.GraphQL.MaxComplexity)
+	if err != nil {
+		log.Fatalf("Failed to set up GraphQL: %v", err)
+	}
 
 	// Initialize handlers (Presentation layer)
-	userHandler := handler.SetupUserHandler(userService)
+	userHandler := handler.SetupUserHandler(userService, retentionService)
 	cycleHandler := handler.SetupCycleHandler(cycleService)
 	usageHandler := handler.SetupDailyUsageHandler(usageService)
+	analyticsHandler := handler.SetupUsageAnalyticsHandler(analyticsService)
+	invoiceHandler := handler.SetupInvoiceHandler(invoiceService)
+	statementHandler := handler.SetupStatementHandler(statementService, statementRenderer)
+	streamHandler := handler.SetupUsageStreamHandler(streamService, cfg.Stream.HeartbeatInterval)
+	graphqlHandler := handler.SetupGraphQLHandler(graphqlExecutor)
+	apiKeyHandler := handler.SetupAPIKeyHandler(apiKeyService)
+	authHandler := handler.SetupAuthHandler(authService, accountService)
+	auditHandler := handler.SetupAuditHandler(auditService)
+	mfaHandler := handler.SetupMFAHandler(mfaService)
+	lockoutHandler := handler.SetupLockoutHandler(lockoutService)
+	dataExportHandler := handler.SetupDataExportHandler(dataExportService)
+
+	rateLimits := router.RateLimits{Default: cfg.RateLimit.Default, Groups: cfg.RateLimit.Groups}
+	switch cfg.RateLimit.Store {
+	case "memory":
+		rateLimits.Store = repository.SetupMemoryRateLimitStore()
+	case "mongo":
+		rateLimits.Store = repository.SetupMongoRateLimitStore(db.Database)
+	}
 
-	r := setupRouter(db, cfg, userHandler, cycleHandler, usageHandler)
+	r := router.SetupRouter(db, router.Config{
+		GinMode:    cfg.Server.GinMode,
+		Auth:       authService,
+		Audit:      auditService,
+		RateLimits: rateLimits,
+	}, router.Handlers{
+		User:        userHandler,
+		Cycle:       cycleHandler,
+		DailyUsage:  usageHandler,
+		Analytics:   analyticsHandler,
+		Invoice:     invoiceHandler,
+		Statement:   statementHandler,
+		UsageStream: streamHandler,
+		GraphQL:     graphqlHandler,
+		APIKey:      apiKeyHandler,
+		Auth:        authHandler,
+		Audit:       auditHandler,
+		MFA:         mfaHandler,
+		Lockout:     lockoutHandler,
+		DataExport:  dataExportHandler,
+	})
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -62,6 +212,19 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	grpcServer, grpcHealth := rpc.SetupServer(authService, auditService, rateLimits, userService, cycleService, usageService)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port %s: %v", cfg.Server.GRPCPort, err)
+	}
+
+	go func() {
+		log.Printf("Starting gRPC server on port %s...", cfg.Server.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+
 	go func() {
 		log.Printf("Starting server on port %s...", cfg.Server.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -77,13 +240,36 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	grpcHealth.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Long-running export streams would otherwise hold GracefulStop open
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	// Data exports cut short are reported failed once their timeout passes;
+	// account mails still being sent get the same grace period
+	exportsDone := make(chan struct{})
+	go func() {
+		dataExportService.Wait()
+		accountService.Wait()
+		close(exportsDone)
+	}()
+	select {
+	case <-exportsDone:
+	case <-ctx.Done():
+	}
+
 	log.Println("Server exited")
 }
-
-func setupRouter(db *database.MongoDB, cfg *config.Config, userHandler *handler.UserHandler, cycleHandler *handler.CycleHandler, dailyUsageHandler *handler.DailyUsageHandler) *gin.Engine {
-	return router.SetupRouter(db, cfg.Server.GinMode, userHandler, cycleHandler, dailyUsageHandler)
-}
\ No newline at end of file
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/config"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+)
+
+// Removes the duplicate daily_usage records that keep the unique index on
+// owner, line and day from being built, and rebuilds the summaries of the
+// lines it touched. Earlier versions could store a day twice when two first
+// writes raced. To upgrade, stop the API, run this, then start the new
+// version, which builds the index; it refuses to start while duplicates
+// remain. Running it again is harmless.
+func main() {
+	timeout := flag.Duration("timeout", 30*time.Minute, "maximum time the run may take")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// The indexes are only built once the duplicates are gone
+	db, err := database.Open(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Disconnect(context.Background())
+
+	usageRepo := repository.SetupDailyUsageRepository(db.Database)
+	summaryRepo := repository.SetupCycleSummaryRepository(db.Database)
+
+	log.Println("Removing duplicate daily usage...")
+	mdns, err := usageRepo.RemoveDuplicates(ctx)
+	if err != nil {
+		log.Fatalf("Failed to remove duplicate daily usage: %v", err)
+	}
+
+	for _, mdn := range mdns {
+		if err := summaryRepo.RebuildByMDN(ctx, mdn); err != nil {
+			log.Fatalf("Failed to rebuild summaries of MDN %s: %v", mdn, err)
+		}
+	}
+
+	log.Printf("Removed duplicate daily usage of %d MDNs", len(mdns))
+}
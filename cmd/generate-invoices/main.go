@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/infra/config"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+)
+
+// Invoices every cycle that ended within the lookback window. Cycles that are
+// already invoiced are skipped, so the command can be scheduled to run daily
+// with overlapping windows.
+func main() {
+	lookback := flag.Duration("lookback", 72*time.Hour, "invoice cycles that ended within this window")
+	timeout := flag.Duration("timeout", 30*time.Minute, "maximum time the run may take")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.Connect(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Disconnect(context.Background())
+
+	invoiceService := service.SetupInvoiceService(
+		repository.SetupInvoiceRepository(db.Database),
+		repository.SetupCycleRepository(db.Database),
+		repository.SetupDailyUsageRepository(db.Database),
+		repository.SetupPlanRepository(db.Database),
+		repository.SetupCreditRepository(db.Database),
+		cfg.Billing.DefaultPlanID,
+		service.SetupAuditService(repository.SetupAuditRepository(db.Database)),
+	)
+
+	now := time.Now()
+	invoiced, failures, err := invoiceService.GenerateInvoicesForEndedCycles(ctx, now.Add(-*lookback), now)
+	if err != nil {
+		log.Fatalf("Failed to generate invoices: %v", err)
+	}
+
+	for cycleID, err := range failures {
+		log.Printf("Failed to invoice cycle %s: %v", cycleID, err)
+	}
+	log.Printf("Invoiced %d cycles, %d failed", invoiced, len(failures))
+}
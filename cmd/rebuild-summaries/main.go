@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/config"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+)
+
+// Recomputes the cycle_summaries collection from the raw daily_usage documents.
+// Run it after bulk imports or whenever the summaries are suspected to have drifted.
+func main() {
+	mdn := flag.String("mdn", "", "only rebuild the cycles of this MDN")
+	cycleID := flag.String("cycle", "", "only rebuild this cycle")
+	timeout := flag.Duration("timeout", 30*time.Minute, "maximum time the rebuild may take")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.Connect(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Disconnect(context.Background())
+
+	summaryRepo := repository.SetupCycleSummaryRepository(db.Database)
+
+	start := time.Now()
+	switch {
+	case *cycleID != "":
+		log.Printf("Rebuilding summary of cycle %s...", *cycleID)
+		err = summaryRepo.Rebuild(ctx, *cycleID)
+	case *mdn != "":
+		log.Printf("Rebuilding summaries of MDN %s...", *mdn)
+		err = summaryRepo.RebuildByMDN(ctx, *mdn)
+	default:
+		log.Println("Rebuilding all cycle summaries...")
+		err = summaryRepo.RebuildAll(ctx)
+	}
+	if err != nil {
+		log.Fatalf("Failed to rebuild cycle summaries: %v", err)
+	}
+
+	log.Printf("Cycle summaries rebuilt in %s", time.Since(start).Round(time.Millisecond))
+}
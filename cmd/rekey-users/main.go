@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/config"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
+)
+
+// Moves the personal data of every user to the current key of PII_KEY_FILE,
+// and encrypts users stored before encryption. Run it after rotating keys;
+// the previous key can be removed from the file once it has finished.
+func main() {
+	timeout := flag.Duration("timeout", 30*time.Minute, "maximum time the run may take")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	keys, err := secrets.SetupFileKeyProvider(cfg.PII.KeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load PII keys: %v", err)
+	}
+
+	db, err := database.Connect(cfg.MongoDB.URI, cfg.MongoDB.Database, cfg.MongoDB.Timeout)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	defer db.Disconnect(context.Background())
+
+	userRepo := repository.SetupUserRepository(db.Database, secrets.SetupEnvelope(keys))
+
+	log.Printf("Rekeying users to key %s...", keys.CurrentKeyID())
+	rekeyed, err := userRepo.Rekey(ctx)
+	if err != nil {
+		log.Fatalf("Failed to rekey users after %d: %v", rekeyed, err)
+	}
+
+	log.Printf("Rekeyed %d users", rekeyed)
+}
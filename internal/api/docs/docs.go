@@ -0,0 +1,14 @@
+// Package docs embeds the OpenAPI document generated from the handler
+// annotations (see `make docs`) and the page that hosts the docs UI.
+package docs
+
+import "embed"
+
+//go:embed openapi.json
+var OpenAPI []byte
+
+// UI holds the Swagger UI entry points; the rest of the bundle is served from
+// github.com/swaggo/files/v2.
+//
+//go:embed ui
+var UI embed.FS
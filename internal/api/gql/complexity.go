@@ -0,0 +1,133 @@
+package gql
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const defaultListSize = 10
+
+// complexity estimates the cost of an operation before it runs. Every field
+// costs one, and the cost of a list field's selection is multiplied by the
+// number of items it can return: its `last` argument when it has one, the
+// listSizes estimate otherwise.
+func complexity(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (int, error) {
+	var operation *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		switch def := definition.(type) {
+		case *ast.OperationDefinition:
+			name := ""
+			if def.Name != nil {
+				name = def.Name.Value
+			}
+			if operationName == "" || name == operationName {
+				operation = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if operation == nil {
+		return 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	c := &complexityCounter{schema: schema, fragments: fragments, variables: variables}
+	return c.selectionSet(operation.SelectionSet, schema.QueryType()), nil
+}
+
+type complexityCounter struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (c *complexityCounter) selectionSet(set *ast.SelectionSet, parent graphql.Type) int {
+	if set == nil {
+		return 0
+	}
+
+	total := 0
+	for _, selection := range set.Selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			total += c.field(sel, parent)
+		case *ast.InlineFragment:
+			total += c.selectionSet(sel.SelectionSet, parent)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[sel.Name.Value]; ok {
+				total += c.selectionSet(fragment.SelectionSet, parent)
+			}
+		}
+	}
+	return total
+}
+
+func (c *complexityCounter) field(field *ast.Field, parent graphql.Type) int {
+	object, ok := parent.(*graphql.Object)
+	if !ok {
+		return 1
+	}
+	definition, ok := object.Fields()[field.Name.Value]
+	if !ok {
+		// Introspection and __typename
+		return 1
+	}
+
+	fieldType, isList := definition.Type, false
+	for {
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+		} else if list, ok := fieldType.(*graphql.List); ok {
+			fieldType, isList = list.OfType, true
+		} else {
+			break
+		}
+	}
+
+	childCost := c.selectionSet(field.SelectionSet, fieldType)
+	if isList {
+		childCost *= c.listSize(object.Name()+"."+field.Name.Value, definition, field.Arguments)
+	}
+	return 1 + childCost
+}
+
+func (c *complexityCounter) listSize(key string, definition *graphql.FieldDefinition, arguments []*ast.Argument) int {
+	for _, arg := range definition.Args {
+		if arg.Name() != "last" {
+			continue
+		}
+		last, _ := arg.DefaultValue.(int)
+		for _, given := range arguments {
+			if given.Name.Value == "last" {
+				last = c.intValue(given.Value, last)
+			}
+		}
+		return max(1, min(last, maxCycles))
+	}
+
+	if size, ok := listSizes[key]; ok {
+		return size
+	}
+	return defaultListSize
+}
+
+func (c *complexityCounter) intValue(value ast.Value, fallback int) int {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		var n int
+		if _, err := fmt.Sscan(v.Value, &n); err == nil {
+			return n
+		}
+	case *ast.Variable:
+		switch n := c.variables[v.Name.Value].(type) {
+		case int:
+			return n
+		case float64:
+			return int(n)
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,66 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Executor struct {
+	schema        graphql.Schema
+	cycleService  *service.CycleService
+	usageService  *service.DailyUsageService
+	maxComplexity int
+}
+
+func SetupExecutor(userService *service.UserService, cycleService *service.CycleService, usageService *service.DailyUsageService, maxComplexity int) (*Executor, error) {
+	schema, err := newSchema(userService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+
+	return &Executor{
+		schema:        schema,
+		cycleService:  cycleService,
+		usageService:  usageService,
+		maxComplexity: maxComplexity,
+	}, nil
+}
+
+// Execute runs a query. It returns false when the request was rejected before
+// execution (syntax, validation or complexity errors), which the HTTP layer
+// reports as a client error rather than a partial result.
+func (e *Executor) Execute(ctx context.Context, req dto.GraphQLRequest) (*graphql.Result, bool) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}, false
+	}
+
+	if validation := graphql.ValidateDocument(&e.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, false
+	}
+
+	cost, err := complexity(&e.schema, doc, req.OperationName, req.Variables)
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(err.Error())}}, false
+	}
+	if cost > e.maxComplexity {
+		message := fmt.Sprintf("query complexity %d exceeds the limit of %d", cost, e.maxComplexity)
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}}, false
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(e.cycleService, e.usageService))
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	}), true
+}
@@ -0,0 +1,69 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// batchLoader collects the keys requested while a level of the query is
+// resolved and fetches them together the first time any of them is needed.
+// Resolvers return the thunk from Load; graphql-go runs thunks breadth-first,
+// after every sibling has registered its key, so each level costs one fetch.
+type batchLoader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:   fetch,
+		queued:  make(map[K]bool),
+		results: make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+// Load registers key for the next batch. Keys missing from the fetch result
+// resolve to the zero value.
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 && !l.done(key) {
+			keys := l.pending
+			l.pending = nil
+
+			results, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+					continue
+				}
+				l.results[k] = results[k]
+			}
+		}
+
+		return l.results[key], l.errs[key]
+	}
+}
+
+func (l *batchLoader[K, V]) done(key K) bool {
+	_, ok := l.results[key]
+	if !ok {
+		_, ok = l.errs[key]
+	}
+	return ok
+}
@@ -0,0 +1,247 @@
+package gql
+
+import (
+	"context"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultCycles = 6
+	maxCycles     = 24
+)
+
+// line is the source of the Line type. Lines are not stored on their own;
+// they are the MDNs that appear on cycles.
+type line struct {
+	MDN string
+}
+
+type loadersKey struct{}
+
+// loaders are created per request, so batches never mix requests and cached
+// results never outlive one.
+type loaders struct {
+	cyclesByMDN  *batchLoader[string, []*model.Cycle]
+	cyclesByUser *batchLoader[string, []*model.Cycle]
+	usage        *batchLoader[*model.Cycle, []*model.DailyUsageResponse]
+}
+
+func newLoaders(cycleService *service.CycleService, usageService *service.DailyUsageService) *loaders {
+	return &loaders{
+		cyclesByMDN: newBatchLoader(func(ctx context.Context, mdns []string) (map[string][]*model.Cycle, error) {
+			cycles, err := cycleService.GetCyclesByMDNs(ctx, mdns)
+			if err != nil {
+				return nil, err
+			}
+			return groupCycles(cycles, func(c *model.Cycle) string { return c.MDN }), nil
+		}),
+		cyclesByUser: newBatchLoader(func(ctx context.Context, userIDs []string) (map[string][]*model.Cycle, error) {
+			cycles, err := cycleService.GetCyclesByUserIDs(ctx, userIDs)
+			if err != nil {
+				return nil, err
+			}
+			return groupCycles(cycles, func(c *model.Cycle) string { return c.UserID }), nil
+		}),
+		usage: newBatchLoader(func(ctx context.Context, cycles []*model.Cycle) (map[*model.Cycle][]*model.DailyUsageResponse, error) {
+			byID, err := usageService.GetUsageForCycles(ctx, cycles)
+			if err != nil {
+				return nil, err
+			}
+			results := make(map[*model.Cycle][]*model.DailyUsageResponse, len(cycles))
+			for _, cycle := range cycles {
+				results[cycle] = byID[cycle.ID]
+			}
+			return results, nil
+		}),
+	}
+}
+
+func groupCycles(cycles []*model.Cycle, key func(*model.Cycle) string) map[string][]*model.Cycle {
+	grouped := make(map[string][]*model.Cycle)
+	for _, cycle := range cycles {
+		grouped[key(cycle)] = append(grouped[key(cycle)], cycle)
+	}
+	return grouped
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// listSizes estimates how many items list fields without a `last` argument
+// return, for the complexity limit.
+var listSizes = map[string]int{
+	"User.lines":  5,
+	"Cycle.usage": 31,
+}
+
+func newSchema(userService *service.UserService) (graphql.Schema, error) {
+	dailyUsageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DailyUsage",
+		Fields: graphql.Fields{
+			"date": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"usedMb": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*model.DailyUsageResponse).Usage, nil
+				},
+			},
+		},
+	})
+
+	cycleType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Cycle",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"mdn":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"userId":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"startDate": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"endDate":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"usage": &graphql.Field{
+				Description: "Daily usage of the cycle's owner, oldest day first",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(dailyUsageType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := loadersFrom(p.Context).usage.Load(p.Context, p.Source.(*model.Cycle))
+					return func() (interface{}, error) {
+						return load()
+					}, nil
+				},
+			},
+			"totalUsage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := loadersFrom(p.Context).usage.Load(p.Context, p.Source.(*model.Cycle))
+					return func() (interface{}, error) {
+						usages, err := load()
+						if err != nil {
+							return nil, err
+						}
+						total := 0.0
+						for _, usage := range usages {
+							total += usage.Usage
+						}
+						return total, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	lineType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Line",
+		Fields: graphql.Fields{
+			"mdn": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"cycles": &graphql.Field{
+				Description: "Billing cycles of the MDN across all of its owners, newest first",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(cycleType))),
+				Args: graphql.FieldConfigArgument{
+					"last": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultCycles},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					last, _ := p.Args["last"].(int)
+					last = max(1, min(last, maxCycles))
+					load := loadersFrom(p.Context).cyclesByMDN.Load(p.Context, p.Source.(*line).MDN)
+					return func() (interface{}, error) {
+						cycles, err := load()
+						if err != nil {
+							return nil, err
+						}
+						return cycles[:min(last, len(cycles))], nil
+					}, nil
+				},
+			},
+			"currentCycle": &graphql.Field{
+				Type: cycleType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := loadersFrom(p.Context).cyclesByMDN.Load(p.Context, p.Source.(*line).MDN)
+					return func() (interface{}, error) {
+						cycles, err := load()
+						if err != nil {
+							return nil, err
+						}
+						now := time.Now()
+						for _, cycle := range cycles {
+							if !now.Before(cycle.StartDate) && !now.After(cycle.EndDate) {
+								return cycle, nil
+							}
+						}
+						return nil, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"firstName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lastName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"lines": &graphql.Field{
+				Description: "Lines the user has held a billing cycle on, most recent first",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(lineType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := loadersFrom(p.Context).cyclesByUser.Load(p.Context, p.Source.(*model.UserResponse).ID)
+					return func() (interface{}, error) {
+						cycles, err := load()
+						if err != nil {
+							return nil, err
+						}
+						seen := make(map[string]bool)
+						lines := []*line{}
+						for _, cycle := range cycles {
+							if !seen[cycle.MDN] {
+								seen[cycle.MDN] = true
+								lines = append(lines, &line{MDN: cycle.MDN})
+							}
+						}
+						return lines, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return userService.GetUser(p.Context, p.Args["id"].(string))
+				},
+			},
+			"line": &graphql.Field{
+				Description: "A line by MDN, or null when it has no billing cycles",
+				Type:        lineType,
+				Args: graphql.FieldConfigArgument{
+					"mdn": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					mdn := p.Args["mdn"].(string)
+					load := loadersFrom(p.Context).cyclesByMDN.Load(p.Context, mdn)
+					return func() (interface{}, error) {
+						cycles, err := load()
+						if err != nil || len(cycles) == 0 {
+							return nil, err
+						}
+						return &line{MDN: mdn}, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func SetupAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey handles POST /api/v1/admin/api-keys
+// @Summary Create an API key
+// @Description Issue a key for a machine client. The key is only returned in this response; store it securely.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param key body dto.CreateAPIKeyRequest true "Key name and scopes"
+// @Success 201 {object} model.IssuedAPIKey
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	key, err := h.apiKeyService.CreateKey(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys handles GET /api/v1/admin/api-keys
+// @Summary List API keys
+// @Description List every key, including revoked ones, with its scopes and when it was last used
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {array} model.APIKey
+// @Router /api/v1/admin/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.ListKeys(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys": keys,
+	})
+}
+
+// RotateAPIKey handles POST /api/v1/admin/api-keys/:id/rotate
+// @Summary Rotate an API key
+// @Description Replace the key's secret while keeping its ID and scopes. The old secret stops working immediately.
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} model.IssuedAPIKey
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	key, err := h.apiKeyService.RotateKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/api-keys/:id
+// @Summary Revoke an API key
+// @Description Permanently disable a key. Revoked keys stay listed for auditing.
+// @Tags api-keys
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} model.APIKey
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	key, err := h.apiKeyService.RevokeKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
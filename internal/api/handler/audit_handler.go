@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func SetupAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditEntries handles GET /api/v1/admin/audit
+// @Summary Search the audit log
+// @Description List audit entries, newest first. Changes carry the fields they touched, with secrets redacted; reads by support agents and of admin routes are recorded as well.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param actor query string false "Actor, e.g. user:<id> or apikey:<id>"
+// @Param action query string false "Action, e.g. user.update or support.access"
+// @Param target query string false "Target, e.g. user:<id> or GET /api/v1/lines/<mdn>/usage"
+// @Param from query string false "Earliest time (RFC 3339)"
+// @Param to query string false "Latest time (RFC 3339)"
+// @Param limit query int false "Number of entries (default 100, max 500)"
+// @Success 200 {array} model.AuditEntry
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/audit [get]
+func (h *AuditHandler) ListAuditEntries(c *gin.Context) {
+	var req dto.ListAuditEntriesRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	entries, err := h.auditService.ListEntries(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
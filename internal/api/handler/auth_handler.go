@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	authService    *service.AuthService
+	accountService *service.AccountService
+}
+
+func SetupAuthHandler(authService *service.AuthService, accountService *service.AccountService) *AuthHandler {
+	return &AuthHandler{
+		authService:    authService,
+		accountService: accountService,
+	}
+}
+
+// Login handles POST /api/v1/auth/login
+// @Summary Sign in
+// @Description Check a user's email and password and open a session. Send the token as "Authorization: Bearer <token>". For users with MFA the response has mfaRequired set and a challenge to answer at /auth/mfa/verify instead of a token. Repeated failures for an email or from a client slow down further attempts and then lock them out for a while; throttled attempts get 429 with Retry-After.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body dto.LoginRequest true "Email and password"
+// @Success 200 {object} model.IssuedSession
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req dto.LoginRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.authService.Login(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// VerifyMFA handles POST /api/v1/auth/mfa/verify
+// @Summary Complete a login with a second factor
+// @Description Exchange the challenge from /auth/login and a code from the authenticator app, or a recovery code, for a session. Each challenge takes one attempt.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param code body dto.MFAVerifyRequest true "Challenge and code"
+// @Success 200 {object} model.IssuedSession
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 429 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req dto.MFAVerifyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.authService.VerifyMFA(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// Logout handles POST /api/v1/auth/logout
+// @Summary Sign out
+// @Description End the session whose token authenticates the request
+// @Tags auth
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	token := middleware.TokenFromHeader(c.Request.Header)
+	if err := h.authService.Logout(c.Request.Context(), token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail handles POST /api/v1/auth/verify
+// @Summary Verify an email address
+// @Description Consume the token mailed on sign-up and mark the address as verified. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.VerifyEmailRequest true "Token from the verification mail"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/verify [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.accountService.VerifyEmail(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ResendVerification handles POST /api/v1/auth/verify/resend
+// @Summary Resend the verification mail
+// @Description Mail a new verification link if the address belongs to an unverified user. The response does not say whether it does.
+// @Tags auth
+// @Accept json
+// @Param email body dto.EmailRequest true "Email address"
+// @Success 202
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/verify/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req dto.EmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.accountService.ResendVerification(c.Request.Context(), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ForgotPassword handles POST /api/v1/auth/forgot
+// @Summary Request a password reset
+// @Description Mail a password reset link if the address belongs to a user. The response does not say whether it does.
+// @Tags auth
+// @Accept json
+// @Param email body dto.EmailRequest true "Email address"
+// @Success 202
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req dto.EmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.accountService.ForgotPassword(c.Request.Context(), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /api/v1/auth/reset
+// @Summary Reset a password
+// @Description Set a new password with the token from the reset mail. Each token works once, and every session of the user is ended.
+// @Tags auth
+// @Accept json
+// @Param reset body dto.ResetPasswordRequest true "Token and new password"
+// @Success 204
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Request.Context(), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ConfirmEmailChange handles POST /api/v1/auth/email/confirm
+// @Summary Confirm a new email address
+// @Description Consume the token mailed to a pending address and make it the account's email. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.VerifyEmailRequest true "Token from the confirmation mail"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/email/confirm [post]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.accountService.ConfirmEmailChange(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/bowe99/phone-usage-service/internal/application/dtos"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,10 +22,13 @@ func SetupCycleHandler(cycleService *service.CycleService) *CycleHandler {
 
 // GetCycleHistory handles POST /api/v1/cycles/history
 // @Summary Get cycle history for an MDN
-// @Description Retrieve the complete billing cycle history for a given MDN (phone number)
+// @Description Retrieve the complete billing cycle history for a given MDN (phone number); customers only get the cycles they owned. CSV, NDJSON and XLSX exports are selected with ?format= or the Accept header.
 // @Tags cycles
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param format query string false "json (default), csv, ndjson or xlsx"
 // @Param request body dto.GetCycleHistoryRequest true "User ID and MDN"
 // @Success 200 {array} model.CycleResponse
 // @Failure 400 {object} middleware.ErrorResponse
@@ -40,6 +45,18 @@ func (h *CycleHandler) GetCycleHistory(c *gin.Context) {
 		return
 	}
 
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		stream := newExportStream(c, format, "cycles-"+req.MDN, []string{"cycleId", "startDate", "endDate"})
+		stream.Finish(h.cycleService.StreamCycleHistory(c.Request.Context(), req, func(cycle *model.CycleResponse) error {
+			return stream.Write(cycle.CycleID, cycle.StartDate, cycle.EndDate)
+		}))
+		return
+	}
+
 	cycles, err := h.cycleService.GetCycleHistory(c.Request.Context(), req)
 	if err != nil {
 		c.Error(err)
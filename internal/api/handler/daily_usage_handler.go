@@ -5,6 +5,8 @@ import (
 
 	"github.com/bowe99/phone-usage-service/internal/application/dtos"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,10 +22,13 @@ func SetupDailyUsageHandler(usageService *service.DailyUsageService) *DailyUsage
 
 // GetCurrentCycleUsage handles POST /api/v1/usage/current-cycle
 // @Summary Get current cycle daily usage
-// @Description Retrieve daily usage data for the current billing cycle of a customer
+// @Description Retrieve daily usage data for the current billing cycle of a customer. CSV, NDJSON and XLSX exports are selected with ?format= or the Accept header.
 // @Tags usage
 // @Accept json
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param format query string false "json (default), csv, ndjson or xlsx"
 // @Param request body dto.GetCurrentCycleUsageRequest true "User ID and MDN"
 // @Success 200 {array} model.DailyUsageResponse
 // @Failure 400 {object} middleware.ErrorResponse
@@ -40,6 +45,18 @@ func (h *DailyUsageHandler) GetCurrentCycleUsage(c *gin.Context) {
 		return
 	}
 
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+	if format != export.FormatJSON {
+		stream := newExportStream(c, format, "usage-"+req.MDN, []string{"date", "dailyUsage"})
+		stream.Finish(h.dailyUsageService.StreamCurrentCycleUsage(c.Request.Context(), req, func(usage *model.DailyUsageResponse) error {
+			return stream.Write(usage.Date, usage.Usage)
+		}))
+		return
+	}
+
 	usage, err := h.dailyUsageService.GetCurrentCycleUsage(c.Request.Context(), req)
 	if err != nil {
 		c.Error(err)
@@ -50,3 +67,188 @@ func (h *DailyUsageHandler) GetCurrentCycleUsage(c *gin.Context) {
 		"usage": usage,
 	})
 }
+
+// GetUsageTrends handles GET /api/v1/lines/:mdn/usage/trends
+// @Summary Get cycle-over-cycle usage trends for an MDN
+// @Description Total usage for the last N cycles with delta, percent change and average daily usage. A partial current cycle is compared against the same number of days of the previous cycle.
+// @Tags usage
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mdn path string true "MDN"
+// @Param cycles query int false "Number of cycles (default 6, max 24)"
+// @Success 200 {object} model.UsageTrendResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/lines/{mdn}/usage/trends [get]
+func (h *DailyUsageHandler) GetUsageTrends(c *gin.Context) {
+	var req dto.GetUsageTrendsRequest
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	trends, err := h.dailyUsageService.GetUsageTrends(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trends)
+}
+
+// RecordUsage handles POST /api/v1/usage
+// @Summary Record daily usage for an MDN
+// @Description Create or replace the usage of a single day and update the cycle summary. Fails with 409 if the day's cycle has been invoiced, and with 412 if the day keeps being changed concurrently
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param request body dto.RecordUsageRequest true "Usage for one day"
+// @Success 200 {object} model.DailyUsageResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Router /api/v1/usage [post]
+func (h *DailyUsageHandler) RecordUsage(c *gin.Context) {
+	var req dto.RecordUsageRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	usage, err := h.dailyUsageService.RecordUsage(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetCurrentCycleSummary handles POST /api/v1/usage/current-cycle/summary
+// @Summary Get current cycle usage summary
+// @Description Retrieve the materialized total, peak day and day count for the current billing cycle
+// @Tags usage
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param request body dto.GetCurrentCycleUsageRequest true "User ID and MDN"
+// @Success 200 {object} model.CycleSummaryResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/usage/current-cycle/summary [post]
+func (h *DailyUsageHandler) GetCurrentCycleSummary(c *gin.Context) {
+	var req dto.GetCurrentCycleUsageRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.dailyUsageService.GetCurrentCycleSummary(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetCycleSummary handles GET /api/v1/lines/:mdn/cycles/:cycleId/summary
+// @Summary Get a cycle usage summary
+// @Description Retrieve the materialized total, peak day and day count of any cycle of an MDN
+// @Tags usage
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mdn path string true "MDN"
+// @Param cycleId path string true "Cycle ID"
+// @Success 200 {object} model.CycleSummaryResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/lines/{mdn}/cycles/{cycleId}/summary [get]
+func (h *DailyUsageHandler) GetCycleSummary(c *gin.Context) {
+	var req dto.GetCycleSummaryRequest
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.dailyUsageService.GetCycleSummary(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ExportUsage handles GET /api/v1/lines/:mdn/usage
+// @Summary Export daily usage of an MDN over a date range
+// @Description Stream every daily usage record of an MDN between two dates (inclusive), across all owners of the line; customers only get their own. Records are streamed from the database, so large ranges export in constant memory.
+// @Tags usage
+// @Produce json,text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mdn path string true "MDN"
+// @Param from query string true "Start date (YYYY-MM-DD)"
+// @Param to query string true "End date (YYYY-MM-DD)"
+// @Param format query string false "json (default), csv, ndjson or xlsx"
+// @Success 200 {array} model.DailyUsage
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/lines/{mdn}/usage [get]
+func (h *DailyUsageHandler) ExportUsage(c *gin.Context) {
+	var req dto.LineRequest
+	var dates dto.UsageDateRange
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&dates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	format, ok := negotiateFormat(c)
+	if !ok {
+		return
+	}
+
+	stream := newExportStream(c, format, "usage-"+req.MDN, []string{"date", "mdn", "userId", "dailyUsage"})
+	stream.Finish(h.dailyUsageService.StreamUsage(c.Request.Context(), req.MDN, dates, func(usage *model.DailyUsage) error {
+		return stream.Write(usage.UsageDate, usage.MDN, usage.UserID, usage.UsedInMB)
+	}))
+}
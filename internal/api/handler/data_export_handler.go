@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	dataExportService *service.DataExportService
+}
+
+func SetupDataExportHandler(dataExportService *service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+	}
+}
+
+// RequestExport handles POST /api/v1/users/:id/data-export
+// @Summary Request a personal data export
+// @Description Start assembling a ZIP archive of everything stored about a user: their profile, the cycles and daily usage of every line they owned, their invoices and the audit entries by and about them, each as JSON and CSV. Poll the export at the Location returned until it is ready, then download it before it expires.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 202 {object} model.DataExport
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id}/data-export [post]
+func (h *DataExportHandler) RequestExport(c *gin.Context) {
+	dataExport, err := h.dataExportService.RequestExport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+dataExport.ID)
+	c.JSON(http.StatusAccepted, dataExport)
+}
+
+// GetExport handles GET /api/v1/users/:id/data-export/:exportId
+// @Summary Get a personal data export
+// @Description Get the status of a personal data export: running, ready or failed. Exports are removed once they expire.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param exportId path string true "Export ID"
+// @Success 200 {object} model.DataExport
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id}/data-export/{exportId} [get]
+func (h *DataExportHandler) GetExport(c *gin.Context) {
+	dataExport, err := h.dataExportService.GetExport(c.Request.Context(), c.Param("id"), c.Param("exportId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dataExport)
+}
+
+// DownloadExport handles GET /api/v1/users/:id/data-export/:exportId/download
+// @Summary Download a personal data export
+// @Description Download the ZIP archive of a ready personal data export.
+// @Tags users
+// @Produce application/zip
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param exportId path string true "Export ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id}/data-export/{exportId}/download [get]
+func (h *DataExportHandler) DownloadExport(c *gin.Context) {
+	dataExport, archive, err := h.dataExportService.DownloadExport(c.Request.Context(), c.Param("id"), c.Param("exportId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	filename := fmt.Sprintf("personal-data-%s-%s.zip", dataExport.UserID, dataExport.CreatedAt.UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, h.dataExportService.ContentType(), archive)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bowe99/phone-usage-service/internal/api/docs"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+type DocsHandler struct {
+	index       []byte
+	initializer []byte
+	assets      http.FileSystem
+}
+
+func SetupDocsHandler() *DocsHandler {
+	// Both files are embedded, so reading them cannot fail
+	index, _ := docs.UI.ReadFile("ui/index.html")
+	initializer, _ := docs.UI.ReadFile("ui/swagger-initializer.js")
+
+	return &DocsHandler{
+		index:       index,
+		initializer: initializer,
+		assets:      http.FS(swaggerFiles.FS),
+	}
+}
+
+// OpenAPI serves the generated OpenAPI document
+func (h *DocsHandler) OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", docs.OpenAPI)
+}
+
+// UI serves the bundled Swagger UI under /docs/, pointed at /openapi.json
+func (h *DocsHandler) UI(c *gin.Context) {
+	switch file := c.Param("filepath"); file {
+	case "/", "/index.html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", h.index)
+	case "/swagger-initializer.js":
+		c.Data(http.StatusOK, "text/javascript; charset=utf-8", h.initializer)
+	default:
+		c.FileFromFS(file, h.assets)
+	}
+}
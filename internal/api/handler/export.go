@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
+	"github.com/gin-gonic/gin"
+)
+
+// negotiateFormat reads the export format from ?format=, falling back to the
+// Accept header and finally to JSON. It responds with 400 and returns false
+// for an unknown ?format= value.
+func negotiateFormat(c *gin.Context) (export.Format, bool) {
+	if value := c.Query("format"); value != "" {
+		format, ok := export.ParseFormat(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request",
+				"details": fmt.Sprintf("unsupported format %q, expected json, csv, ndjson or xlsx", value),
+			})
+			return "", false
+		}
+		return format, true
+	}
+	return export.FromAccept(c.GetHeader("Accept")), true
+}
+
+// exportStream defers writing headers until the first row or until Finish, so
+// errors raised before any data is read still get a normal error response.
+type exportStream struct {
+	c        *gin.Context
+	format   export.Format
+	filename string
+	columns  []string
+	writer   export.RowWriter
+}
+
+func newExportStream(c *gin.Context, format export.Format, filename string, columns []string) *exportStream {
+	return &exportStream{
+		c:        c,
+		format:   format,
+		filename: filename,
+		columns:  columns,
+	}
+}
+
+func (s *exportStream) Write(row ...any) error {
+	if s.writer == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.writer.Write(row)
+}
+
+func (s *exportStream) start() error {
+	s.c.Header("Content-Type", s.format.ContentType())
+	if s.format != export.FormatJSON {
+		s.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, s.filename, time.Now().UTC().Format("20060102"), s.format.Extension()))
+	}
+	s.c.Status(http.StatusOK)
+	// Large exports can outlast the server's write timeout; the client
+	// disconnecting still cancels the request context and stops the cursor
+	_ = http.NewResponseController(s.c.Writer).SetWriteDeadline(time.Time{})
+
+	writer, err := export.NewRowWriter(s.format, s.c.Writer, s.columns)
+	if err != nil {
+		return err
+	}
+	s.writer = writer
+	return nil
+}
+
+// Finish completes the export. Once rows have been sent the status can no
+// longer change, so a failure part-way through aborts the response instead.
+func (s *exportStream) Finish(err error) {
+	if err != nil {
+		s.c.Error(err)
+		if s.writer != nil {
+			s.c.Abort()
+		}
+		return
+	}
+
+	if s.writer == nil {
+		if err := s.start(); err != nil {
+			s.c.Error(err)
+			return
+		}
+	}
+	if err := s.writer.Close(); err != nil {
+		s.c.Error(err)
+	}
+}
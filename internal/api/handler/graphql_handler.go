@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bowe99/phone-usage-service/internal/api/gql"
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLHandler struct {
+	executor *gql.Executor
+}
+
+func SetupGraphQLHandler(executor *gql.Executor) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+	}
+}
+
+// Query handles POST /graphql
+// @Summary Run a GraphQL query
+// @Description Query users, lines, cycles and daily usage in one round trip. Nested loads are batched per request. Queries whose estimated complexity exceeds the configured limit are rejected before they run.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param request body dto.GraphQLRequest true "Query, operation name and variables"
+// @Success 200 {object} object "GraphQL result; field errors are reported in errors"
+// @Failure 400 {object} object "Malformed, invalid or too complex query"
+// @Router /graphql [post]
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req dto.GraphQLRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, executed := h.executor.Execute(c.Request.Context(), req)
+	if !executed {
+		c.JSON(http.StatusBadRequest, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	db *database.MongoDB
+}
+
+func SetupHealthHandler(db *database.MongoDB) *HealthHandler {
+	return &HealthHandler{
+		db: db,
+	}
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Health handles GET /health
+// @Summary Health check
+// @Description Reports whether the service can reach MongoDB
+// @Tags health
+// @Produce json
+// @Success 200 {object} handler.HealthResponse
+// @Failure 500 {object} handler.HealthResponse
+// @Router /health [get]
+func (h *HealthHandler) Health(c *gin.Context) {
+	if err := h.db.HealthCheck(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, HealthResponse{Status: "unhealth", Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, HealthResponse{Status: "heatlhy"})
+}
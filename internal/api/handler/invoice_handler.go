@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandler struct {
+	invoiceService *service.InvoiceService
+}
+
+func SetupInvoiceHandler(invoiceService *service.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{
+		invoiceService: invoiceService,
+	}
+}
+
+// GetUserInvoices handles GET /api/v1/users/:id/invoices
+// @Summary List a user's invoices
+// @Description Retrieve every invoice issued to a user, newest billing period first
+// @Tags invoices
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} model.Invoice
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id}/invoices [get]
+func (h *InvoiceHandler) GetUserInvoices(c *gin.Context) {
+	userID := c.Param("id")
+
+	invoices, err := h.invoiceService.GetUserInvoices(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoices": invoices,
+	})
+}
+
+// GenerateInvoice handles POST /api/v1/admin/cycles/:cycleId/invoice
+// @Summary Invoice a closed cycle
+// @Description Rate a closed cycle against its plan and issue an invoice. Safe to repeat: an already invoiced cycle returns its existing invoice.
+// @Tags invoices
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param cycleId path string true "Cycle ID"
+// @Success 200 {object} model.Invoice
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/cycles/{cycleId}/invoice [post]
+func (h *InvoiceHandler) GenerateInvoice(c *gin.Context) {
+	cycleID := c.Param("cycleId")
+
+	invoice, err := h.invoiceService.GenerateInvoice(c.Request.Context(), cycleID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// CreatePlan handles POST /api/v1/admin/plans
+// @Summary Create a rate plan
+// @Description Create a plan that cycles are rated against. Amounts are in minor units of the plan currency.
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param plan body dto.CreatePlanRequest true "Plan"
+// @Success 201 {object} model.Plan
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/plans [post]
+func (h *InvoiceHandler) CreatePlan(c *gin.Context) {
+	var req dto.CreatePlanRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	plan, err := h.invoiceService.CreatePlan(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// CreateCredit handles POST /api/v1/admin/cycles/:cycleId/credits
+// @Summary Credit a cycle
+// @Description Record a credit that is deducted on the cycle's invoice
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param cycleId path string true "Cycle ID"
+// @Param credit body dto.CreateCreditRequest true "Credit"
+// @Success 201 {object} model.Credit
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/cycles/{cycleId}/credits [post]
+func (h *InvoiceHandler) CreateCredit(c *gin.Context) {
+	cycleID := c.Param("cycleId")
+
+	var req dto.CreateCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	credit, err := h.invoiceService.CreateCredit(c.Request.Context(), cycleID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, credit)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutHandler struct {
+	lockoutService *service.LockoutService
+}
+
+func SetupLockoutHandler(lockoutService *service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{
+		lockoutService: lockoutService,
+	}
+}
+
+// Unlock handles POST /api/v1/admin/users/:id/unlock
+// @Summary Lift a login lockout
+// @Description Unlock a user's account after repeated failed logins and forget its failures. Lockouts and unlocks are in the audit log as auth.lockout and auth.unlock.
+// @Tags users
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *LockoutHandler) Unlock(c *gin.Context) {
+	if err := h.lockoutService.Unlock(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
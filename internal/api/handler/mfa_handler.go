@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+func SetupMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// Enroll handles POST /api/v1/auth/mfa/enroll
+// @Summary Start MFA enrollment
+// @Description Create a TOTP secret for the signed-in user. Show the otpauth URI as a QR code, then confirm with /auth/mfa/activate. Starting again replaces a pending enrollment.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} model.MFAEnrollment
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	enrollment, err := h.mfaService.Enroll(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// Activate handles POST /api/v1/auth/mfa/activate
+// @Summary Enable MFA
+// @Description Confirm a pending enrollment with a code from the authenticator app. The response holds the recovery codes, which are not shown again. Sign in again for roles that require MFA.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body dto.MFACodeRequest true "Code from the authenticator app"
+// @Success 200 {object} model.RecoveryCodes
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/mfa/activate [post]
+func (h *MFAHandler) Activate(c *gin.Context) {
+	var req dto.MFACodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.mfaService.Activate(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// Disable handles POST /api/v1/auth/mfa/disable
+// @Summary Disable MFA
+// @Description Remove the signed-in user's second factor, given a current code or a recovery code
+// @Tags auth
+// @Accept json
+// @Security BearerAuth
+// @Param code body dto.MFACodeRequest true "Code from the authenticator app or a recovery code"
+// @Success 204
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Router /api/v1/auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req dto.MFACodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.mfaService.Disable(c.Request.Context(), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
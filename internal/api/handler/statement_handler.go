@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/infra/statement"
+	"github.com/gin-gonic/gin"
+)
+
+type StatementHandler struct {
+	statementService *service.StatementService
+	renderer         *statement.Renderer
+}
+
+func SetupStatementHandler(statementService *service.StatementService, renderer *statement.Renderer) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+		renderer:         renderer,
+	}
+}
+
+// GetStatement handles GET /api/v1/lines/:mdn/cycles/:cycleId/statement
+// @Summary Download a usage statement
+// @Description Render the statement of a cycle with user details, daily usage table and chart
+// @Tags statements
+// @Produce html,application/pdf
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mdn path string true "MDN"
+// @Param cycleId path string true "Cycle ID"
+// @Param format query string false "html (default) or pdf"
+// @Success 200 {string} string "HTML or PDF document"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/lines/{mdn}/cycles/{cycleId}/statement [get]
+func (h *StatementHandler) GetStatement(c *gin.Context) {
+	var req dto.GetStatementRequest
+	var query dto.StatementFormatQuery
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	stmt, err := h.statementService.GetStatement(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Render into a buffer first so a template error still produces a clean 500
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if query.Format == dto.StatementFormatPDF {
+		contentType = "application/pdf"
+		err = h.renderer.RenderPDF(&body, stmt)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s.pdf"`, stmt.MDN, stmt.Cycle.StartDate.Format("2006-01-02")))
+	} else {
+		err = h.renderer.RenderHTML(&body, stmt)
+	}
+	if err != nil {
+		c.Header("Content-Disposition", "")
+		c.Error(err)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type UsageAnalyticsHandler struct {
+	analyticsService *service.UsageAnalyticsService
+}
+
+func SetupUsageAnalyticsHandler(analyticsService *service.UsageAnalyticsService) *UsageAnalyticsHandler {
+	return &UsageAnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// GetTopConsumers handles GET /api/v1/admin/usage/top
+// @Summary Get the heaviest lines in a date range
+// @Description Rank MDNs by total usage between two dates (inclusive)
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param from query string true "Start date (YYYY-MM-DD)"
+// @Param to query string true "End date (YYYY-MM-DD)"
+// @Param limit query int false "Number of lines (default 10, max 1000)"
+// @Success 200 {array} model.LineUsageTotal
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/usage/top [get]
+func (h *UsageAnalyticsHandler) GetTopConsumers(c *gin.Context) {
+	var req dto.GetTopConsumersRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	totals, err := h.analyticsService.GetTopConsumers(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lines": totals,
+	})
+}
+
+// GetUsagePercentiles handles GET /api/v1/admin/usage/percentiles
+// @Summary Get usage percentiles across all lines
+// @Description p50, p90 and p99 (nearest rank) of the total usage of each cycle that ended between two dates (inclusive), counting all of the cycle's days
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param from query string true "Start date (YYYY-MM-DD)"
+// @Param to query string true "End date (YYYY-MM-DD)"
+// @Success 200 {object} model.UsagePercentiles
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/usage/percentiles [get]
+func (h *UsageAnalyticsHandler) GetUsagePercentiles(c *gin.Context) {
+	var req dto.GetUsagePercentilesRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	percentiles, err := h.analyticsService.GetUsagePercentiles(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, percentiles)
+}
+
+// GetUsageHistogram handles GET /api/v1/admin/usage/histogram
+// @Summary Get a histogram of cycle usage totals
+// @Description Distribution of the total usage of each cycle that ended between two dates (inclusive), counting all of the cycle's days, in evenly populated buckets
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param from query string true "Start date (YYYY-MM-DD)"
+// @Param to query string true "End date (YYYY-MM-DD)"
+// @Param buckets query int false "Number of buckets (default 10, max 100)"
+// @Success 200 {array} model.UsageHistogramBucket
+// @Failure 400 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/usage/histogram [get]
+func (h *UsageAnalyticsHandler) GetUsageHistogram(c *gin.Context) {
+	var req dto.GetUsageHistogramRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	histogram, err := h.analyticsService.GetUsageHistogram(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"buckets": histogram,
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// sseRetry tells EventSource clients how long to wait before reconnecting
+const sseRetry = 5 * time.Second
+
+type UsageStreamHandler struct {
+	streamService *service.UsageStreamService
+	heartbeat     time.Duration
+}
+
+func SetupUsageStreamHandler(streamService *service.UsageStreamService, heartbeat time.Duration) *UsageStreamHandler {
+	return &UsageStreamHandler{
+		streamService: streamService,
+		heartbeat:     heartbeat,
+	}
+}
+
+// StreamUsage handles GET /api/v1/lines/:mdn/usage/stream
+// @Summary Stream live usage of an MDN
+// @Description Server-Sent Events stream of the line's current cycle. A "usage" event is sent whenever a day's usage is recorded, carrying the daily and cycle totals, and a "threshold" event whenever the cycle total crosses a configured alert threshold. Comment heartbeats keep idle connections open. Reconnecting clients resume with the Last-Event-ID header or the lastEventId query parameter.
+// @Tags usage
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param mdn path string true "MDN"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param lastEventId query string false "ID of the last event received, for clients that cannot set headers"
+// @Success 200 {object} model.UsageEvent
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/lines/{mdn}/usage/stream [get]
+func (h *UsageStreamHandler) StreamUsage(c *gin.Context) {
+	var req dto.LineRequest
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.streamService.SubscribeUsage(ctx, req.MDN, lastEventID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Status(http.StatusOK)
+
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetry.Milliseconds()); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeUsageEvent(c.Writer, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeUsageEvent(w gin.ResponseWriter, event *model.UsageEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -2,23 +2,29 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	userService *service.UserService
+	userService      *service.UserService
+	retentionService *service.RetentionService
 }
 
-func SetupUserHandler(userService *service.UserService) *UserHandler {
+func SetupUserHandler(userService *service.UserService, retentionService *service.RetentionService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:      userService,
+		retentionService: retentionService,
 	}
 }
 
-// CreateUser handles POST /api/users
+// CreateUser handles POST /api/v1/users
 // @Summary Create a new user
 // @Description Create a new user account with provided information
 // @Tags users
@@ -28,7 +34,7 @@ func SetupUserHandler(userService *service.UserService) *UserHandler {
 // @Success 201 {object} model.UserResponse
 // @Failure 400 {object} middleware.ErrorResponse
 // @Failure 409 {object} middleware.ErrorResponse
-// @Router /api/users [post]
+// @Router /api/v1/users [post]
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req dto.CreateUserRequest
 
@@ -51,18 +57,72 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, user)
 }
 
-// UpdateUserProfile handles PUT /api/users/:id
+// GetUser handles GET /api/v1/users/:id
+// @Summary Get a user
+// @Description Get a user's profile. The ETag header holds its version, for If-Match on updates.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	user, err := h.userService.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// GetCurrentUser handles GET /api/v1/users/me
+// @Summary Get my profile
+// @Description Get the profile of the signed-in user. API keys have no profile.
+// @Tags users
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 401 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/users/me [get]
+func (h *UserHandler) GetCurrentUser(c *gin.Context) {
+	user, err := h.userService.GetCurrentUser(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserProfile handles PUT /api/v1/users/:id
 // @Summary Update user profile
-// @Description Update an existing user's profile information
+// @Description Update an existing user's profile information. A new email address takes effect once confirmed through the link mailed to it. Users changing their own email or password must send currentPassword. With If-Match, the update only applies to the version it names.
 // @Tags users
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the version the update is based on"
 // @Param user body dto.UpdateUserRequest true "Updated user information"
 // @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "Version of the user"
 // @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
 // @Failure 404 {object} middleware.ErrorResponse
-// @Router /api/users/{id} [put]
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id} [put]
 func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	userID := c.Param("id")
 
@@ -75,6 +135,13 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	req.IfMatch = version
+
 	// Update user
 	user, err := h.userService.UpdateUserProfile(c.Request.Context(), userID, req)
 	if err != nil {
@@ -82,5 +149,174 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// PatchUser handles PATCH /api/v1/users/:id
+// @Summary Patch user profile
+// @Description Apply a JSON Merge Patch (RFC 7396) to a user: members left out stay as they are and null clears one. Only pendingEmail can be cleared, which cancels a pending email change. Email and password changes work as with PUT. With If-Match, the patch only applies to the version it names.
+// @Tags users
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the version the patch is based on"
+// @Param patch body dto.PatchUserRequest true "Merge patch"
+// @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Failure 409 {object} middleware.ErrorResponse
+// @Failure 412 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req dto.PatchUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	req.IfMatch = version
+
+	user, err := h.userService.PatchUser(c.Request.Context(), userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// userETag is the entity tag of a user, which changes with every write.
+func userETag(user *model.UserResponse) string {
+	return `"` + strconv.FormatInt(user.Version, 10) + `"`
+}
+
+// ifMatch reads the version from an If-Match header holding a single entity
+// tag; nil when there is none or it is *. Users stored before versioning
+// have the tag "0", which is a version like any other. A tag that is not a
+// version never matches.
+func ifMatch(c *gin.Context) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !ok || err != nil || version < 0 {
+		return nil, repository.ErrConcurrentModification
+	}
+	return &version, nil
+}
+
+// DeleteUser handles DELETE /api/v1/users/:id
+// @Summary Delete a user
+// @Description Delete a user account and sign it out everywhere. The account's personal data is anonymized once the retention window has passed; its cycles, usage and invoices are kept under the user ID.
+// @Tags users
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	if err := h.retentionService.DeleteUser(c.Request.Context(), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListUsers handles GET /api/v1/admin/users
+// @Summary Search users
+// @Description List users, newest first unless sorted otherwise, a page at a time. Names and emails match as case-insensitive prefixes; names and emails are stored encrypted, so users can only be sorted by date. Pass the nextCursor of a page as cursor to get the next one.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param name query string false "Prefix of the first or last name, at least 3 characters"
+// @Param email query string false "Prefix of the email, at least 3 characters"
+// @Param createdFrom query string false "Earliest sign-up time (RFC 3339)"
+// @Param createdTo query string false "Latest sign-up time (RFC 3339)"
+// @Param sort query string false "createdAt or updatedAt, descending with a leading - (default -createdAt)"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Number of users (default 50, max 200)"
+// @Success 200 {object} model.UserPage
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users [get]
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	var req dto.ListUsersRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	page, err := h.userService.ListUsers(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// SetUserRoles handles PUT /api/v1/admin/users/:id/roles
+// @Summary Set a user's roles
+// @Description Replace the roles of a user. Customers see their own data, support agents read everyone's with each access audited, and admins can do anything. Only signed-in admins can assign roles; API keys cannot, whatever their scopes.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param roles body dto.SetRolesRequest true "New roles"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} middleware.ErrorResponse
+// @Failure 403 {object} middleware.ErrorResponse
+// @Failure 404 {object} middleware.ErrorResponse
+// @Router /api/v1/admin/users/{id}/roles [put]
+func (h *UserHandler) SetUserRoles(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req dto.SetRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.SetRoles(c.Request.Context(), userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	c.JSON(http.StatusOK, user)
 }
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ContextPrincipal holds the *model.Principal of an authenticated request
+	ContextPrincipal = "principal"
+	contextAuthError = "authError"
+)
+
+// Authenticate resolves the session token or API key presented in the
+// Authorization (Bearer) or X-API-Key header and attaches the caller to the
+// request context for the services. It never rejects a request itself: a
+// failure is kept for RequireScopes, which runs after rate limiting so that
+// guessing tokens is rate limited by client IP.
+func Authenticate(auth *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := TokenFromHeader(c.Request.Header)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		principal, err := auth.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			c.Set(contextAuthError, err)
+			c.Next()
+			return
+		}
+
+		c.Set(ContextPrincipal, principal)
+		if principal.APIKeyID != "" {
+			c.Set(ContextAPIKeyID, principal.APIKeyID)
+		} else {
+			c.Set(ContextUserID, principal.UserID)
+		}
+		c.Request = c.Request.WithContext(service.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireScopes rejects unauthenticated requests with 401. API keys must also
+// carry the scopes, or the request is rejected with 403. Signed-in users pass:
+// what they may do depends on whose data they ask for, which the services
+// check.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(ContextPrincipal)
+		if !ok {
+			err := service.ErrAuthenticationRequired
+			if authErr, ok := c.Get(contextAuthError); ok {
+				err = authErr.(error)
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="phone-usage-service"`)
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		principal := value.(*model.Principal)
+		if principal.APIKeyID != "" {
+			if err := service.RequireScopes(principal, scopes...); err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// AuditAccess records every request of a support agent, and of anyone on a
+// privileged route, before serving it. It refuses the request if the record
+// cannot be written.
+func AuditAccess(audit *service.AuditService, privileged bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target := c.Request.Method + " " + c.Request.URL.Path
+		if err := audit.RecordAccess(c.Request.Context(), target, privileged); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TokenFromHeader returns the token of an "Authorization: Bearer" or
+// "X-API-Key" header. The gRPC server reads the same names from metadata.
+func TokenFromHeader(header http.Header) string {
+	if authorization := header.Get("Authorization"); authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(header.Get("X-API-Key"))
+}
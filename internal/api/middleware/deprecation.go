@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation marks every response of a deprecated API version with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links to the same
+// path under the successor version. Routes in renamed link to the path they
+// were renamed to instead.
+func Deprecation(prefix string, deprecatedAt, sunset time.Time, successor string, renamed map[string]string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunsetDate)
+		}
+		if successor != "" {
+			path := strings.TrimPrefix(c.Request.URL.Path, prefix)
+			if renamedTo, ok := renamed[strings.TrimPrefix(c.FullPath(), prefix)]; ok {
+				path = renamedTo
+			}
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor+path))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+}
+
+// ErrorHandler turns the last error attached with c.Error into a JSON response.
+// Handlers only call c.Error(err) and return; the status code is derived here.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(throttled.RetryAfter))))
+		}
+		c.JSON(StatusFor(err), ErrorResponse{Error: err.Error()})
+	}
+}
+
+// StatusFor maps an application error to its HTTP status. The gRPC server
+// derives its status codes from the same mapping.
+func StatusFor(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound),
+		errors.Is(err, repository.ErrCycleNotFound),
+		errors.Is(err, repository.ErrNoCycleActive),
+		errors.Is(err, domainrepo.ErrCycleSummaryNotFound),
+		errors.Is(err, domainrepo.ErrInvoiceNotFound),
+		errors.Is(err, domainrepo.ErrPlanNotFound),
+		errors.Is(err, domainrepo.ErrAPIKeyNotFound),
+		errors.Is(err, domainrepo.ErrDataExportNotFound),
+		errors.Is(err, service.ErrNoCyclesFound),
+		errors.Is(err, service.ErrCycleNotOnLine):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidDateRange),
+		errors.Is(err, domainrepo.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidToken),
+		errors.Is(err, service.ErrCurrencyMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrUserAlreadyExists),
+		errors.Is(err, service.ErrEmailAlreadyExists),
+		errors.Is(err, domainrepo.ErrPlanAlreadyExists),
+		errors.Is(err, service.ErrCycleNotClosed),
+		errors.Is(err, service.ErrCycleInvoiced),
+		errors.Is(err, service.ErrAPIKeyRevoked),
+		errors.Is(err, service.ErrMFAAlreadyEnabled),
+		errors.Is(err, service.ErrMFANotEnrolled),
+		errors.Is(err, service.ErrDataExportNotReady):
+		return http.StatusConflict
+	case errors.Is(err, domainrepo.ErrConcurrentModification):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrLoginThrottled):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrNoPlanForCycle):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidAPIKey),
+		errors.Is(err, service.ErrInvalidSession),
+		errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrAuthenticationRequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInsufficientScope),
+		errors.Is(err, service.ErrPermissionDenied),
+		errors.Is(err, service.ErrCurrentPasswordMismatch):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by authentication middleware. The rate limiter keys
+// buckets by the first one present, falling back to the client IP.
+const (
+	ContextAPIKeyID = "apiKeyID"
+	ContextUserID   = "userID"
+)
+
+// RateLimit takes a token from the caller's bucket for the route group and
+// rejects the request with 429 when it is empty. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers; rejected ones also carry Retry-After.
+func RateLimit(store repository.RateLimitStore, group string, limit model.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := store.Take(c.Request.Context(), group+":"+rateLimitIdentity(c), limit)
+		if err != nil {
+			// An unavailable store must not take the API down with it
+			c.Next()
+			return
+		}
+
+		for name, value := range RateLimitHeaders(result, limit) {
+			c.Header(name, value)
+		}
+		if !result.Allowed {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Error: "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitHeaders are the headers that report result, including
+// Retry-After when the request was rejected.
+func RateLimitHeaders(result *model.RateLimitResult, limit model.RateLimit) map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(ceilSeconds(result.Reset)),
+		"RateLimit-Policy":    strconv.Itoa(limit.Burst) + ";w=" + strconv.Itoa(ceilSeconds(limit.Window())),
+	}
+	if !result.Allowed {
+		headers["Retry-After"] = strconv.Itoa(max(1, ceilSeconds(result.RetryAfter)))
+	}
+	return headers
+}
+
+// RateLimitIdentity is who a request is rate limited as: its API key or
+// user, or else the client address.
+func RateLimitIdentity(principal *model.Principal, clientIP string) string {
+	switch {
+	case principal != nil && principal.APIKeyID != "":
+		return "key:" + principal.APIKeyID
+	case principal != nil && principal.UserID != "":
+		return "user:" + principal.UserID
+	}
+	return "ip:" + clientIP
+}
+
+func rateLimitIdentity(c *gin.Context) string {
+	principal := &model.Principal{APIKeyID: c.GetString(ContextAPIKeyID), UserID: c.GetString(ContextUserID)}
+	return RateLimitIdentity(principal, c.ClientIP())
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
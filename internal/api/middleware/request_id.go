@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID carries the ID that ties a request to its audit entries.
+const HeaderRequestID = "X-Request-ID"
+
+const maxRequestIDLength = 64
+
+// RequestID echoes the caller's X-Request-ID, or a generated one, and attaches
+// it to the request context together with the client IP.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := ResolveRequestID(c.GetHeader(HeaderRequestID))
+		c.Header(HeaderRequestID, requestID)
+		c.Request = c.Request.WithContext(service.WithRequestInfo(c.Request.Context(), requestID, c.ClientIP()))
+		c.Next()
+	}
+}
+
+// ResolveRequestID keeps a presented ID if it is short and printable ASCII,
+// so it cannot be used to inject into logs, and otherwise generates one.
+func ResolveRequestID(presented string) string {
+	if presented != "" && len(presented) <= maxRequestIDLength && printable(presented) {
+		return presented
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimits configures a token bucket per route group. A route's group is
+// the first segment of its path within the version (users, usage, lines,
+// admin, ...), so /api/users and /api/v1/users share their buckets.
+type RateLimits struct {
+	// Store is nil when rate limiting is disabled
+	Store   repository.RateLimitStore
+	Default model.RateLimit
+	Groups  map[string]model.RateLimit
+}
+
+// middleware returns nil when rate limiting is disabled.
+func (r RateLimits) middleware(group string) gin.HandlerFunc {
+	if r.Store == nil {
+		return nil
+	}
+	return middleware.RateLimit(r.Store, group, r.Limit(group))
+}
+
+// Limit is the limit of the group's buckets.
+func (r RateLimits) Limit(group string) model.RateLimit {
+	if limit, ok := r.Groups[group]; ok {
+		return limit
+	}
+	return r.Default
+}
+
+// Group is the rate limit group of the route.
+func (r Route) Group() string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+	return group
+}
@@ -1,42 +1,101 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
 	"github.com/bowe99/phone-usage-service/internal/infra/database"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(db *database.MongoDB, ginMode string, userHandler *handler.UserHandler, cycleHandler *handler.CycleHandler, dailyUsageHandler *handler.DailyUsageHandler) *gin.Engine {
-	gin.SetMode(ginMode)
+type Handlers struct {
+	User        *handler.UserHandler
+	Cycle       *handler.CycleHandler
+	DailyUsage  *handler.DailyUsageHandler
+	Analytics   *handler.UsageAnalyticsHandler
+	Invoice     *handler.InvoiceHandler
+	Statement   *handler.StatementHandler
+	UsageStream *handler.UsageStreamHandler
+	GraphQL     *handler.GraphQLHandler
+	APIKey      *handler.APIKeyHandler
+	Auth        *handler.AuthHandler
+	Audit       *handler.AuditHandler
+	MFA         *handler.MFAHandler
+	Lockout     *handler.LockoutHandler
+	DataExport  *handler.DataExportHandler
+}
+
+type Config struct {
+	GinMode string
+	// Auth authenticates callers of routes that are not public
+	Auth *service.AuthService
+	// Audit records the requests of support agents and reads of admin routes
+	Audit      *service.AuditService
+	RateLimits RateLimits
+}
+
+func SetupRouter(db *database.MongoDB, cfg Config, h Handlers) *gin.Engine {
+	gin.SetMode(cfg.GinMode)
 	router := gin.New()
 
 	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.ErrorHandler())
 
-	router.GET("/health", func(c *gin.Context) {
-		if err := db.HealthCheck(c.Request.Context()); err != nil {
-			c.JSON(500, gin.H{"status": "unhealth", "error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"status": "heatlhy"})
-	})
+	router.GET("/health", handler.SetupHealthHandler(db).Health)
 
-	users := router.Group("/api/users")
-	{
-		users.POST("", userHandler.CreateUser)
-		users.PUT("/:id", userHandler.UpdateUserProfile)
-	}
+	docsHandler := handler.SetupDocsHandler()
+	router.GET("/openapi.json", docsHandler.OpenAPI)
+	router.GET("/docs/*filepath", docsHandler.UI)
 
-	cycle := router.Group("/api/cycle")
-	{
-		cycle.POST("/history", cycleHandler.GetCycleHistory)
-	}
+	authenticate := middleware.Authenticate(cfg.Auth)
 
-	usage := router.Group("/api/usage")
-	{
-		usage.POST("/current-cycle", dailyUsageHandler.GetCurrentCycleUsage)
+	for _, version := range Versions(h) {
+		group := router.Group(version.Prefix)
+		if version.Deprecated() {
+			group.Use(middleware.Deprecation(version.Prefix, version.DeprecatedAt, version.Sunset, version.Successor, version.Renamed))
+		}
+		group.Use(authenticate)
+		for _, route := range version.Routes {
+			handlers := chain(cfg, route)
+			group.Handle(route.Method, route.Path, handlers...)
+			for former, path := range version.Renamed {
+				if path == route.Path {
+					group.Handle(route.Method, former, handlers...)
+				}
+			}
+		}
 	}
 
-
+	// GraphQL resolves through the same application services as the REST
+	// routes, so middleware added here must mirror what guards them
+	graphql := Route{http.MethodPost, "/graphql", h.GraphQL.Query, []string{model.ScopeUsageRead}}
+	router.POST(graphql.Path, append([]gin.HandlerFunc{authenticate}, chain(cfg, graphql)...)...)
 
 	return router
-}
\ No newline at end of file
+}
+
+// chain runs after Authenticate, which only resolves the caller, so buckets
+// are kept per key or user. Rate limiting comes before RequireScopes rejects
+// anything, so requests with bad or missing tokens are limited too, by
+// client IP. Routes with nil scopes are public; the others require a caller,
+// whose access is audited if they are a support agent or read an admin
+// route. Admin changes are audited by the services, with the fields they
+// changed.
+func chain(cfg Config, route Route) []gin.HandlerFunc {
+	var handlers []gin.HandlerFunc
+	if rateLimit := cfg.RateLimits.middleware(route.Group()); rateLimit != nil {
+		handlers = append(handlers, rateLimit)
+	}
+	if route.Scopes != nil {
+		handlers = append(handlers, middleware.RequireScopes(route.Scopes...))
+		if cfg.Audit != nil {
+			privileged := route.Method == http.MethodGet && route.Group() == "admin"
+			handlers = append(handlers, middleware.AuditAccess(cfg.Audit, privileged))
+		}
+	}
+	return append(handlers, route.Handler)
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Compatibility policy
+//
+// A version's contract only changes additively: new routes, new optional
+// request fields and new response fields. Anything else (removing or renaming
+// fields, changing types, status codes or semantics) ships in a new version,
+// registered alongside the old one in Versions. When a successor is released
+// the old version is marked deprecated, which adds Deprecation, Sunset and
+// successor Link headers to all of its responses, and it is removed no
+// earlier than its sunset date, at least six months later.
+
+// Route is a single endpoint of a version, relative to the version prefix.
+type Route struct {
+	Method  string
+	Path    string
+	Handler func(*gin.Context)
+	// Scopes an API key needs to call the route. Nil leaves the route public
+	// and an empty list only requires the caller to be authenticated.
+	Scopes []string
+}
+
+type Routes []Route
+
+// With returns a copy of the routes in which the given routes replace those
+// with the same method and path and are added otherwise. It lets a version be
+// declared as its predecessor plus the handlers that changed.
+func (r Routes) With(changed ...Route) Routes {
+	routes := append(Routes(nil), r...)
+	for _, route := range changed {
+		replaced := false
+		for i := range routes {
+			if routes[i].Method == route.Method && routes[i].Path == route.Path {
+				routes[i] = route
+				replaced = true
+			}
+		}
+		if !replaced {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// Without returns a copy of the routes without the given endpoint.
+func (r Routes) Without(method, path string) Routes {
+	routes := make(Routes, 0, len(r))
+	for _, route := range r {
+		if route.Method != method || route.Path != path {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// Version is a REST contract mounted under its own prefix.
+type Version struct {
+	Prefix string
+	Routes Routes
+	// DeprecatedAt is zero for supported versions
+	DeprecatedAt time.Time
+	Sunset       time.Time
+	// Successor is the prefix of the version that replaces this one
+	Successor string
+	// Renamed maps former paths that the version still serves to the routes
+	// of the version that replaced them. A former path is handled, limited
+	// and linked to its successor as the route it was renamed to.
+	Renamed map[string]string
+}
+
+func (v Version) Deprecated() bool {
+	return !v.DeprecatedAt.IsZero()
+}
+
+var (
+	// The unversioned /api routes predate /api/v1 and alias it
+	legacyDeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Versions lists every mounted version, current first. To introduce v2,
+// declare it from v1's routes, add it here and mark v1 deprecated:
+//
+//	v2 := v1.With(Route{http.MethodGet, "/lines/:mdn/usage", h.DailyUsageV2.ExportUsage, usageRead})
+func Versions(h Handlers) []Version {
+	v1 := v1Routes(h)
+
+	return []Version{
+		{Prefix: "/api/v1", Routes: v1},
+		{Prefix: "/api", Routes: v1, DeprecatedAt: legacyDeprecatedAt, Sunset: legacySunset, Successor: "/api/v1",
+			// v1 made the documented plural path the only one
+			Renamed: map[string]string{"/cycle/history": "/cycles/history"}},
+	}
+}
+
+func v1Routes(h Handlers) Routes {
+	var (
+		usageRead     = []string{model.ScopeUsageRead}
+		usageWrite    = []string{model.ScopeUsageWrite}
+		cyclesAdmin   = []string{model.ScopeCyclesAdmin}
+		usersAdmin    = []string{model.ScopeUsersAdmin}
+		keysAdmin     = []string{model.ScopeKeysAdmin}
+		auditRead     = []string{model.ScopeAuditRead}
+		analyticsRead = []string{model.ScopeAnalyticsRead}
+		anyCaller     = []string{}
+	)
+
+	return Routes{
+		// Sign-up, login and recovery stay open to anyone
+		{http.MethodPost, "/users", h.User.CreateUser, nil},
+		{http.MethodPost, "/auth/login", h.Auth.Login, nil},
+		{http.MethodPost, "/auth/logout", h.Auth.Logout, anyCaller},
+		{http.MethodPost, "/auth/verify", h.Auth.VerifyEmail, nil},
+		{http.MethodPost, "/auth/verify/resend", h.Auth.ResendVerification, nil},
+		{http.MethodPost, "/auth/forgot", h.Auth.ForgotPassword, nil},
+		{http.MethodPost, "/auth/reset", h.Auth.ResetPassword, nil},
+		{http.MethodPost, "/auth/email/confirm", h.Auth.ConfirmEmailChange, nil},
+		{http.MethodPost, "/auth/mfa/verify", h.Auth.VerifyMFA, nil},
+		{http.MethodPost, "/auth/mfa/enroll", h.MFA.Enroll, anyCaller},
+		{http.MethodPost, "/auth/mfa/activate", h.MFA.Activate, anyCaller},
+		{http.MethodPost, "/auth/mfa/disable", h.MFA.Disable, anyCaller},
+
+		{http.MethodGet, "/users/me", h.User.GetCurrentUser, anyCaller},
+		{http.MethodGet, "/users/:id", h.User.GetUser, usersAdmin},
+		{http.MethodPut, "/users/:id", h.User.UpdateUserProfile, usersAdmin},
+		{http.MethodPatch, "/users/:id", h.User.PatchUser, usersAdmin},
+		{http.MethodDelete, "/users/:id", h.User.DeleteUser, usersAdmin},
+		{http.MethodGet, "/users/:id/invoices", h.Invoice.GetUserInvoices, usageRead},
+		{http.MethodPost, "/users/:id/data-export", h.DataExport.RequestExport, usersAdmin},
+		{http.MethodGet, "/users/:id/data-export/:exportId", h.DataExport.GetExport, usersAdmin},
+		{http.MethodGet, "/users/:id/data-export/:exportId/download", h.DataExport.DownloadExport, usersAdmin},
+
+		{http.MethodPost, "/cycles/history", h.Cycle.GetCycleHistory, usageRead},
+
+		{http.MethodPost, "/usage", h.DailyUsage.RecordUsage, usageWrite},
+		{http.MethodPost, "/usage/current-cycle", h.DailyUsage.GetCurrentCycleUsage, usageRead},
+		{http.MethodPost, "/usage/current-cycle/summary", h.DailyUsage.GetCurrentCycleSummary, usageRead},
+
+		{http.MethodGet, "/lines/:mdn/usage", h.DailyUsage.ExportUsage, usageRead},
+		{http.MethodGet, "/lines/:mdn/usage/stream", h.UsageStream.StreamUsage, usageRead},
+		{http.MethodGet, "/lines/:mdn/usage/trends", h.DailyUsage.GetUsageTrends, usageRead},
+		{http.MethodGet, "/lines/:mdn/cycles/:cycleId/summary", h.DailyUsage.GetCycleSummary, usageRead},
+		{http.MethodGet, "/lines/:mdn/cycles/:cycleId/statement", h.Statement.GetStatement, usageRead},
+
+		{http.MethodGet, "/admin/usage/top", h.Analytics.GetTopConsumers, analyticsRead},
+		{http.MethodGet, "/admin/usage/percentiles", h.Analytics.GetUsagePercentiles, analyticsRead},
+		{http.MethodGet, "/admin/usage/histogram", h.Analytics.GetUsageHistogram, analyticsRead},
+		{http.MethodPost, "/admin/plans", h.Invoice.CreatePlan, cyclesAdmin},
+		{http.MethodPost, "/admin/cycles/:cycleId/credits", h.Invoice.CreateCredit, cyclesAdmin},
+		{http.MethodPost, "/admin/cycles/:cycleId/invoice", h.Invoice.GenerateInvoice, cyclesAdmin},
+
+		{http.MethodGet, "/admin/users", h.User.ListUsers, usersAdmin},
+		{http.MethodPut, "/admin/users/:id/roles", h.User.SetUserRoles, usersAdmin},
+		{http.MethodPost, "/admin/users/:id/unlock", h.Lockout.Unlock, usersAdmin},
+		{http.MethodGet, "/admin/audit", h.Audit.ListAuditEntries, auditRead},
+
+		{http.MethodPost, "/admin/api-keys", h.APIKey.CreateAPIKey, keysAdmin},
+		{http.MethodGet, "/admin/api-keys", h.APIKey.ListAPIKeys, keysAdmin},
+		{http.MethodPost, "/admin/api-keys/:id/rotate", h.APIKey.RotateAPIKey, keysAdmin},
+		{http.MethodDelete, "/admin/api-keys/:id", h.APIKey.RevokeAPIKey, keysAdmin},
+	}
+}
@@ -0,0 +1,213 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodScopes mirrors the scopes of the matching REST routes. A nil entry
+// leaves the method public; methods of this API missing from the map are
+// rejected, while health checking and reflection stay open.
+var methodScopes = map[string][]string{
+	pb.UserService_CreateUser_FullMethodName: nil,
+	pb.UserService_UpdateUser_FullMethodName: {model.ScopeUsersAdmin},
+
+	pb.CycleService_GetCycleHistory_FullMethodName:    {model.ScopeUsageRead},
+	pb.CycleService_ExportCycleHistory_FullMethodName: {model.ScopeUsageRead},
+
+	pb.DailyUsageService_RecordUsage_FullMethodName:             {model.ScopeUsageWrite},
+	pb.DailyUsageService_GetCurrentCycleUsage_FullMethodName:    {model.ScopeUsageRead},
+	pb.DailyUsageService_GetCurrentCycleSummary_FullMethodName:  {model.ScopeUsageRead},
+	pb.DailyUsageService_GetCycleSummary_FullMethodName:         {model.ScopeUsageRead},
+	pb.DailyUsageService_GetUsageTrends_FullMethodName:          {model.ScopeUsageRead},
+	pb.DailyUsageService_ExportCurrentCycleUsage_FullMethodName: {model.ScopeUsageRead},
+	pb.DailyUsageService_ExportUsage_FullMethodName:             {model.ScopeUsageRead},
+}
+
+// methodGroups puts each method in the rate limit group of the matching REST
+// route, so a caller's buckets are shared across both APIs.
+var methodGroups = map[string]string{
+	pb.UserService_CreateUser_FullMethodName: "users",
+	pb.UserService_UpdateUser_FullMethodName: "users",
+
+	pb.CycleService_GetCycleHistory_FullMethodName:    "cycles",
+	pb.CycleService_ExportCycleHistory_FullMethodName: "cycles",
+
+	pb.DailyUsageService_RecordUsage_FullMethodName:             "usage",
+	pb.DailyUsageService_GetCurrentCycleUsage_FullMethodName:    "usage",
+	pb.DailyUsageService_GetCurrentCycleSummary_FullMethodName:  "usage",
+	pb.DailyUsageService_ExportCurrentCycleUsage_FullMethodName: "usage",
+	pb.DailyUsageService_GetCycleSummary_FullMethodName:         "lines",
+	pb.DailyUsageService_GetUsageTrends_FullMethodName:          "lines",
+	pb.DailyUsageService_ExportUsage_FullMethodName:             "lines",
+}
+
+// MethodScopes reports the scopes a method requires and whether it is
+// declared at all.
+func MethodScopes(method string) ([]string, bool) {
+	scopes, ok := methodScopes[method]
+	return scopes, ok
+}
+
+// MethodGroup reports the rate limit group of a method.
+func MethodGroup(method string) (string, bool) {
+	group, ok := methodGroups[method]
+	return group, ok
+}
+
+// authorizer plays the part of middleware.RequestID, middleware.Authenticate,
+// middleware.RateLimit, middleware.RequireScopes and middleware.AuditAccess,
+// in that order, reading the same headers from metadata.
+type authorizer struct {
+	auth       *service.AuthService
+	audit      *service.AuditService
+	rateLimits router.RateLimits
+}
+
+func (a *authorizer) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, requestID := withRequestInfo(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authorizer) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, requestID := withRequestInfo(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(requestIDKey, requestID))
+
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize returns ctx carrying the caller's principal for the services.
+// As on the REST routes, a failed authentication is only reported once the
+// request has been rate limited, so guessing tokens is limited by address.
+func (a *authorizer) authorize(ctx context.Context, method string) (context.Context, error) {
+	scopes, ok := methodScopes[method]
+	if !ok {
+		if strings.HasPrefix(method, "/phoneusage.") {
+			return nil, status.Error(codes.PermissionDenied, "method has no declared scopes")
+		}
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := http.Header{
+		"Authorization": md.Get("authorization"),
+		"X-Api-Key":     md.Get("x-api-key"),
+	}
+	var principal *model.Principal
+	authErr := service.ErrAuthenticationRequired
+	if token := middleware.TokenFromHeader(header); token != "" {
+		principal, authErr = a.auth.Authenticate(ctx, token)
+	}
+	if authErr == nil {
+		ctx = service.WithPrincipal(ctx, principal)
+	}
+
+	if err := a.rateLimit(ctx, method, principal); err != nil {
+		return nil, err
+	}
+
+	if scopes == nil {
+		return ctx, nil
+	}
+	if authErr != nil {
+		return nil, authErr
+	}
+	if principal.APIKeyID != "" {
+		if err := service.RequireScopes(principal, scopes...); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.audit != nil {
+		if err := a.audit.RecordAccess(ctx, method, false); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// rateLimit takes a token from the caller's bucket for the method's group,
+// and reports the bucket in the response header metadata.
+func (a *authorizer) rateLimit(ctx context.Context, method string, principal *model.Principal) error {
+	group, ok := methodGroups[method]
+	if a.rateLimits.Store == nil || !ok {
+		return nil
+	}
+	limit := a.rateLimits.Limit(group)
+
+	result, err := a.rateLimits.Store.Take(ctx, group+":"+middleware.RateLimitIdentity(principal, peerIP(ctx)), limit)
+	if err != nil {
+		// An unavailable store must not take the API down with it
+		return nil
+	}
+
+	md := metadata.MD{}
+	for name, value := range middleware.RateLimitHeaders(result, limit) {
+		md.Set(name, value)
+	}
+	_ = grpc.SetHeader(ctx, md)
+	if !result.Allowed {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return nil
+}
+
+// requestIDKey is the metadata form of middleware.HeaderRequestID.
+const requestIDKey = "x-request-id"
+
+func withRequestInfo(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var presented string
+	if values := md.Get(requestIDKey); len(values) > 0 {
+		presented = values[0]
+	}
+	requestID := middleware.ResolveRequestID(presented)
+
+	return service.WithRequestInfo(ctx, requestID, peerIP(ctx)), requestID
+}
+
+// peerIP is the address of the client, without its port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// principalStream hands the authorized context to stream handlers.
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"context"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type cycleServer struct {
+	pb.UnimplementedCycleServiceServer
+	cycleService *service.CycleService
+}
+
+func (s *cycleServer) GetCycleHistory(ctx context.Context, req *pb.GetCycleHistoryRequest) (*pb.GetCycleHistoryResponse, error) {
+	historyReq := dto.GetCycleHistoryRequest{UserID: req.GetUserId(), MDN: req.GetMdn()}
+	if err := validate(historyReq); err != nil {
+		return nil, err
+	}
+
+	cycles, err := s.cycleService.GetCycleHistory(ctx, historyReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetCycleHistoryResponse{Cycles: make([]*pb.Cycle, 0, len(cycles))}
+	for _, cycle := range cycles {
+		resp.Cycles = append(resp.Cycles, toCycle(cycle))
+	}
+	return resp, nil
+}
+
+func (s *cycleServer) ExportCycleHistory(req *pb.GetCycleHistoryRequest, stream grpc.ServerStreamingServer[pb.Cycle]) error {
+	historyReq := dto.GetCycleHistoryRequest{UserID: req.GetUserId(), MDN: req.GetMdn()}
+	if err := validate(historyReq); err != nil {
+		return err
+	}
+
+	return s.cycleService.StreamCycleHistory(stream.Context(), historyReq, func(cycle *model.CycleResponse) error {
+		return stream.Send(toCycle(cycle))
+	})
+}
+
+func toCycle(cycle *model.CycleResponse) *pb.Cycle {
+	return &pb.Cycle{
+		CycleId:   cycle.CycleID,
+		StartDate: timestamppb.New(cycle.StartDate),
+		EndDate:   timestamppb.New(cycle.EndDate),
+	}
+}
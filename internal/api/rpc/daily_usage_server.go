@@ -0,0 +1,173 @@
+package rpc
+
+import (
+	"context"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type dailyUsageServer struct {
+	pb.UnimplementedDailyUsageServiceServer
+	usageService *service.DailyUsageService
+}
+
+func (s *dailyUsageServer) RecordUsage(ctx context.Context, req *pb.RecordUsageRequest) (*pb.DailyUsage, error) {
+	usageDate, err := requireTimestamp(req.GetUsageDate(), "usage_date")
+	if err != nil {
+		return nil, err
+	}
+	usedMB := req.GetUsedMb()
+	recordReq := dto.RecordUsageRequest{
+		UserID:    req.GetUserId(),
+		MDN:       req.GetMdn(),
+		UsageDate: usageDate,
+		UsedInMB:  &usedMB,
+	}
+	if err := validate(recordReq); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.usageService.RecordUsage(ctx, recordReq)
+	if err != nil {
+		return nil, err
+	}
+	return toDailyUsage(usage), nil
+}
+
+func (s *dailyUsageServer) GetCurrentCycleUsage(ctx context.Context, req *pb.LineOwnerRequest) (*pb.GetCurrentCycleUsageResponse, error) {
+	usageReq := dto.GetCurrentCycleUsageRequest{UserID: req.GetUserId(), MDN: req.GetMdn()}
+	if err := validate(usageReq); err != nil {
+		return nil, err
+	}
+
+	usages, err := s.usageService.GetCurrentCycleUsage(ctx, usageReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetCurrentCycleUsageResponse{Usage: make([]*pb.DailyUsage, 0, len(usages))}
+	for _, usage := range usages {
+		resp.Usage = append(resp.Usage, toDailyUsage(usage))
+	}
+	return resp, nil
+}
+
+func (s *dailyUsageServer) GetCurrentCycleSummary(ctx context.Context, req *pb.LineOwnerRequest) (*pb.CycleSummary, error) {
+	summaryReq := dto.GetCurrentCycleUsageRequest{UserID: req.GetUserId(), MDN: req.GetMdn()}
+	if err := validate(summaryReq); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.usageService.GetCurrentCycleSummary(ctx, summaryReq)
+	if err != nil {
+		return nil, err
+	}
+	return toCycleSummary(summary), nil
+}
+
+func (s *dailyUsageServer) GetCycleSummary(ctx context.Context, req *pb.GetCycleSummaryRequest) (*pb.CycleSummary, error) {
+	summaryReq := dto.GetCycleSummaryRequest{MDN: req.GetMdn(), CycleID: req.GetCycleId()}
+	if err := validate(summaryReq); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.usageService.GetCycleSummary(ctx, summaryReq)
+	if err != nil {
+		return nil, err
+	}
+	return toCycleSummary(summary), nil
+}
+
+func (s *dailyUsageServer) GetUsageTrends(ctx context.Context, req *pb.GetUsageTrendsRequest) (*pb.UsageTrends, error) {
+	trendsReq := dto.GetUsageTrendsRequest{MDN: req.GetMdn(), Cycles: int(req.GetCycles())}
+	if err := validate(trendsReq); err != nil {
+		return nil, err
+	}
+
+	trends, err := s.usageService.GetUsageTrends(ctx, trendsReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.UsageTrends{
+		Mdn:         trends.MDN,
+		AlignedDays: int32(trends.AlignedDays),
+		Cycles:      make([]*pb.CycleTrend, 0, len(trends.Cycles)),
+	}
+	for _, trend := range trends.Cycles {
+		resp.Cycles = append(resp.Cycles, &pb.CycleTrend{
+			CycleId:        trend.CycleID,
+			StartDate:      timestamppb.New(trend.StartDate),
+			EndDate:        timestamppb.New(trend.EndDate),
+			Partial:        trend.Partial,
+			DaysElapsed:    int32(trend.DaysElapsed),
+			TotalMb:        trend.TotalUsage,
+			AlignedMb:      trend.AlignedUsage,
+			AverageDailyMb: trend.AverageDailyUsage,
+			DeltaMb:        trend.Delta,
+			PercentChange:  trend.PercentChange,
+		})
+	}
+	return resp, nil
+}
+
+func (s *dailyUsageServer) ExportCurrentCycleUsage(req *pb.LineOwnerRequest, stream grpc.ServerStreamingServer[pb.DailyUsage]) error {
+	usageReq := dto.GetCurrentCycleUsageRequest{UserID: req.GetUserId(), MDN: req.GetMdn()}
+	if err := validate(usageReq); err != nil {
+		return err
+	}
+
+	return s.usageService.StreamCurrentCycleUsage(stream.Context(), usageReq, func(usage *model.DailyUsageResponse) error {
+		return stream.Send(toDailyUsage(usage))
+	})
+}
+
+func (s *dailyUsageServer) ExportUsage(req *pb.ExportUsageRequest, stream grpc.ServerStreamingServer[pb.DailyUsage]) error {
+	from, err := requireTimestamp(req.GetFrom(), "from")
+	if err != nil {
+		return err
+	}
+	to, err := requireTimestamp(req.GetTo(), "to")
+	if err != nil {
+		return err
+	}
+	lineReq := dto.LineRequest{MDN: req.GetMdn()}
+	if err := validate(lineReq); err != nil {
+		return err
+	}
+
+	dates := dto.UsageDateRange{From: from, To: to}
+	return s.usageService.StreamUsage(stream.Context(), lineReq.MDN, dates, func(usage *model.DailyUsage) error {
+		return stream.Send(&pb.DailyUsage{
+			Date:   timestamppb.New(usage.UsageDate),
+			UsedMb: usage.UsedInMB,
+			UserId: usage.UserID,
+			Mdn:    usage.MDN,
+		})
+	})
+}
+
+func toDailyUsage(usage *model.DailyUsageResponse) *pb.DailyUsage {
+	return &pb.DailyUsage{
+		Date:   timestamppb.New(usage.Date),
+		UsedMb: usage.Usage,
+	}
+}
+
+func toCycleSummary(summary *model.CycleSummaryResponse) *pb.CycleSummary {
+	return &pb.CycleSummary{
+		CycleId:     summary.CycleID,
+		StartDate:   timestamppb.New(summary.StartDate),
+		EndDate:     timestamppb.New(summary.EndDate),
+		TotalMb:     summary.TotalUsage,
+		PeakDate:    optionalTimestamp(summary.PeakDate),
+		PeakMb:      summary.PeakUsage,
+		DayCount:    int32(summary.DayCount),
+		LastUpdated: timestamppb.New(summary.LastUpdated),
+	}
+}
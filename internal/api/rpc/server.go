@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SetupServer registers the gRPC services on top of the same application
+// services the gin handlers use, together with health checking and reflection.
+func SetupServer(authService *service.AuthService, auditService *service.AuditService, rateLimits router.RateLimits, userService *service.UserService, cycleService *service.CycleService, usageService *service.DailyUsageService) (*grpc.Server, *health.Server) {
+	auth := &authorizer{auth: authService, audit: auditService, rateLimits: rateLimits}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryErrorInterceptor, auth.unary),
+		grpc.ChainStreamInterceptor(streamErrorInterceptor, auth.stream),
+	)
+
+	pb.RegisterUserServiceServer(server, &userServer{userService: userService})
+	pb.RegisterCycleServiceServer(server, &cycleServer{cycleService: cycleService})
+	pb.RegisterDailyUsageServiceServer(server, &dailyUsageServer{usageService: usageService})
+
+	healthServer := health.NewServer()
+	for _, name := range []string{"", pb.UserService_ServiceDesc.ServiceName, pb.CycleService_ServiceDesc.ServiceName, pb.DailyUsageService_ServiceDesc.ServiceName} {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	return server, healthServer
+}
+
+// The interceptors play the part of middleware.ErrorHandler: servers return
+// application errors as they are and the status code is derived here.
+func unaryErrorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(err)
+}
+
+func streamErrorInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(handler(srv, ss))
+}
+
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var code codes.Code
+	switch middleware.StatusFor(err) {
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	case http.StatusPreconditionFailed:
+		code = codes.Aborted
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// validate applies the binding rules of the REST DTOs, so both APIs accept
+// exactly the same requests.
+func validate(req any) error {
+	return binding.Validator.ValidateStruct(req)
+}
+
+func requireTimestamp(ts *timestamppb.Timestamp, field string) (time.Time, error) {
+	if ts == nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, fmt.Sprintf("%s is required", field))
+	}
+	if err := ts.CheckValid(); err != nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, fmt.Sprintf("%s: %v", field, err))
+	}
+	return ts.AsTime(), nil
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	userService *service.UserService
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	createReq := dto.CreateUserRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+		Password:  req.GetPassword(),
+	}
+	if err := validate(createReq); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.CreateUser(ctx, createReq)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	updateReq := dto.UpdateUserRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+		Password:  req.GetPassword(),
+
+		CurrentPassword: req.GetCurrentPassword(),
+	}
+	if err := validate(updateReq); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.UpdateUserProfile(ctx, req.GetId(), updateReq)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func toUser(user *model.UserResponse) *pb.User {
+	return &pb.User{
+		Id:            user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+		EmailVerified: user.EmailVerified,
+		PendingEmail:  user.PendingEmail,
+	}
+}
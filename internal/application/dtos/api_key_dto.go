@@ -0,0 +1,6 @@
+package dto
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=usage:write usage:read cycles:admin users:admin keys:admin audit:read analytics:read"`
+}
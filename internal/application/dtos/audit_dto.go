@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// Times are RFC 3339, e.g. ?from=2024-11-01T00:00:00Z
+type ListAuditEntriesRequest struct {
+	Actor  string    `form:"actor"`
+	Action string    `form:"action"`
+	Target string    `form:"target"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int       `form:"limit" binding:"omitempty,min=1,max=500"`
+}
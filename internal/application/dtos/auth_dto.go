@@ -0,0 +1,33 @@
+package dto
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest asks for a link to be mailed to an address. The response is
+// the same whether or not the address belongs to a user.
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// MFACodeRequest carries a code from the authenticator app, or a recovery
+// code where one is accepted.
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest is the second login step for users with MFA.
+type MFAVerifyRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+}
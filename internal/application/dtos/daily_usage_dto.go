@@ -1,6 +1,24 @@
 package dto
 
+import "time"
+
 type GetCurrentCycleUsageRequest struct {
 	UserID string `json:"userId" binding:"required"`
 	MDN    string `json:"mdn" binding:"required,len=10"`
 }
+
+type RecordUsageRequest struct {
+	UserID    string    `json:"userId" binding:"required"`
+	MDN       string    `json:"mdn" binding:"required,len=10"`
+	UsageDate time.Time `json:"usageDate" binding:"required"`
+	UsedInMB  *float64  `json:"usedInMb" binding:"required,gte=0"`
+}
+
+type GetCycleSummaryRequest struct {
+	MDN     string `uri:"mdn" binding:"required,len=10"`
+	CycleID string `uri:"cycleId" binding:"required"`
+}
+
+type LineRequest struct {
+	MDN string `uri:"mdn" binding:"required,len=10"`
+}
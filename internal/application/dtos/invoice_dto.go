@@ -0,0 +1,18 @@
+package dto
+
+type CreatePlanRequest struct {
+	ID                 string  `json:"id" binding:"required,max=64"`
+	Name               string  `json:"name" binding:"required,max=100"`
+	Currency           string  `json:"currency" binding:"required,len=3,uppercase"`
+	BaseFee            int64   `json:"baseFee" binding:"gte=0"`
+	IncludedMB         float64 `json:"includedMb" binding:"gte=0"`
+	OverageUnitMB      float64 `json:"overageUnitMb" binding:"required,gt=0"`
+	OverageRate        int64   `json:"overageRate" binding:"gte=0"`
+	TaxRateBasisPoints int64   `json:"taxRateBasisPoints" binding:"gte=0,lte=10000"`
+}
+
+type CreateCreditRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,len=3,uppercase"`
+	Reason   string `json:"reason" binding:"required,max=200"`
+}
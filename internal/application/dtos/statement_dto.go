@@ -0,0 +1,15 @@
+package dto
+
+const (
+	StatementFormatHTML = "html"
+	StatementFormatPDF  = "pdf"
+)
+
+type GetStatementRequest struct {
+	MDN     string `uri:"mdn" binding:"required,len=10"`
+	CycleID string `uri:"cycleId" binding:"required"`
+}
+
+type StatementFormatQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=html pdf"`
+}
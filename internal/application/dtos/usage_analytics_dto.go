@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// Dates are inclusive calendar days in UTC, e.g. ?from=2024-11-01&to=2024-11-30
+type UsageDateRange struct {
+	From time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To   time.Time `form:"to" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+}
+
+type GetTopConsumersRequest struct {
+	UsageDateRange
+	Limit int `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+type GetUsagePercentilesRequest struct {
+	UsageDateRange
+}
+
+type GetUsageHistogramRequest struct {
+	UsageDateRange
+	Buckets int `form:"buckets" binding:"omitempty,min=1,max=100"`
+}
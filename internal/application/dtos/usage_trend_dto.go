@@ -0,0 +1,6 @@
+package dto
+
+type GetUsageTrendsRequest struct {
+	MDN    string `uri:"mdn" binding:"required,len=10"`
+	Cycles int    `form:"cycles" binding:"omitempty,min=1,max=24"`
+}
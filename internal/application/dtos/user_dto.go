@@ -1,5 +1,12 @@
 package dto
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type CreateUserRequest struct {
 	FirstName string `json:"firstName" binding:"required,min=2,max=50"`
 	LastName  string `json:"lastName" binding:"required,min=2,max=50"`
@@ -12,4 +19,65 @@ type UpdateUserRequest struct {
 	LastName  string `json:"lastName" binding:"omitempty,min=2,max=50"`
 	Email     string `json:"email" binding:"omitempty,email"`
 	Password  string `json:"password" binding:"omitempty,min=8"`
-}
\ No newline at end of file
+	// CurrentPassword is required to change your own email or password
+	CurrentPassword string `json:"currentPassword"`
+	// IfMatch is the version the update is based on, from the If-Match
+	// header. Nil updates whatever version is stored; users stored before
+	// versioning are at version 0
+	IfMatch *int64 `json:"-"`
+}
+
+// PatchUserRequest is a JSON Merge Patch (RFC 7396) of a user: members left
+// out stay as they are and null clears a member. Only pendingEmail can be
+// cleared, which cancels a pending email change.
+type PatchUserRequest struct {
+	FirstName *string `json:"firstName" binding:"omitempty,min=2,max=50"`
+	LastName  *string `json:"lastName" binding:"omitempty,min=2,max=50"`
+	Email     *string `json:"email" binding:"omitempty,email"`
+	Password  *string `json:"password" binding:"omitempty,min=8"`
+	// CurrentPassword is required to change your own email or password
+	CurrentPassword string `json:"currentPassword"`
+
+	ClearPendingEmail bool   `json:"-"`
+	IfMatch           *int64 `json:"-"`
+}
+
+func (r *PatchUserRequest) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	for name, value := range members {
+		null := bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+		switch {
+		case name == "pendingEmail" && null:
+			r.ClearPendingEmail = true
+		case name == "pendingEmail":
+			return fmt.Errorf("pendingEmail can only be cleared; set email to change the address")
+		case null:
+			return fmt.Errorf("%s cannot be cleared", name)
+		}
+	}
+
+	// plain decodes the members without coming back here
+	type plain PatchUserRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,dive,oneof=customer support admin"`
+}
+
+// Times are RFC 3339, e.g. ?createdFrom=2024-11-01T00:00:00Z
+type ListUsersRequest struct {
+	// Name and Email are matched as case-insensitive prefixes of at least
+	// three characters
+	Name        string    `form:"name" binding:"omitempty,min=3,max=32"`
+	Email       string    `form:"email" binding:"omitempty,min=3,max=32"`
+	CreatedFrom time.Time `form:"createdFrom" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"createdTo" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Sort is createdAt or updatedAt, descending with a leading -
+	Sort   string `form:"sort" binding:"omitempty,oneof=createdAt -createdAt updatedAt -updatedAt"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+}
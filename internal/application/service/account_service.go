@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+const (
+	verifyEmailMarker   = "pev_"
+	resetPasswordMarker = "ppr_"
+	changeEmailMarker   = "pce_"
+)
+
+type AccountOptions struct {
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	// LinkBaseURL is where the pages that take the mailed tokens are served;
+	// links point to /verify-email, /reset-password and /confirm-email below it
+	LinkBaseURL string
+}
+
+// AccountService proves control of a user's email address with single-use
+// tokens sent by mail, to verify the address, to reset a forgotten password or
+// to move the account to a new address.
+type AccountService struct {
+	userRepo    repository.UserRepository
+	tokenRepo   repository.UserTokenRepository
+	sessionRepo repository.SessionRepository
+	mailer      repository.MailSender
+	opts        AccountOptions
+	audit       *AuditService
+	sending     sync.WaitGroup
+}
+
+func SetupAccountService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, sessionRepo repository.SessionRepository, mailer repository.MailSender, opts AccountOptions, audit *AuditService) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		mailer:      mailer,
+		opts:        opts,
+		audit:       audit,
+	}
+}
+
+// SendVerification mails the user a link to verify their address. A nil
+// service sends nothing.
+func (s *AccountService) SendVerification(ctx context.Context, user *model.User) error {
+	if s == nil {
+		return nil
+	}
+
+	token, err := s.issue(ctx, user, user.Email, model.UserTokenVerifyEmail, verifyEmailMarker, s.opts.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &model.Mail{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within %s:\n\n%s\n\nIf you did not create an account, you can ignore this message.\n",
+			user.FirstName, s.opts.VerificationTTL, s.link("/verify-email", token)),
+	})
+}
+
+// ResendVerification sends a new verification link. Unknown and already
+// verified addresses are ignored, so the caller cannot tell them apart.
+func (s *AccountService) ResendVerification(ctx context.Context, req dto.EmailRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	s.sendInBackground(ctx, func(ctx context.Context) error {
+		return s.SendVerification(ctx, user)
+	})
+	return nil
+}
+
+// VerifyEmail marks the address the token was sent to as verified. Tokens
+// sent to an address the user has since changed are rejected.
+func (s *AccountService) VerifyEmail(ctx context.Context, req dto.VerifyEmailRequest) (*model.UserResponse, error) {
+	token, user, err := s.consume(ctx, model.UserTokenVerifyEmail, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, token.Email) {
+		return nil, ErrInvalidToken
+	}
+	if user.EmailVerified {
+		return user.ToResponse(), nil
+	}
+
+	before, err := s.updateUser(ctx, user, func(user *model.User) error {
+		if !strings.EqualFold(user.Email, token.Email) {
+			return ErrInvalidToken
+		}
+		user.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithPrincipal(ctx, model.UserPrincipal(user))
+	if err := s.audit.Record(ctx, model.AuditActionEmailVerified, "user:"+token.UserID, before, user); err != nil {
+		return nil, err
+	}
+
+	return user.ToResponse(), nil
+}
+
+// ForgotPassword mails a password reset link. Unknown addresses are ignored,
+// so the caller cannot tell whether an account exists.
+func (s *AccountService) ForgotPassword(ctx context.Context, req dto.EmailRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	s.sendInBackground(ctx, func(ctx context.Context) error {
+		token, err := s.issue(ctx, user, user.Email, model.UserTokenResetPassword, resetPasswordMarker, s.opts.ResetTTL)
+		if err != nil {
+			return err
+		}
+
+		return s.mailer.Send(ctx, &model.Mail{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nOpen the link below within %s to choose a new password:\n\n%s\n\nIf you did not ask for this, you can ignore this message; your password is unchanged.\n",
+				user.FirstName, s.opts.ResetTTL, s.link("/reset-password", token)),
+		})
+	})
+	return nil
+}
+
+// sendInBackground issues and mails a token after the request has returned.
+// Requests for unknown addresses return as soon as the lookup does, so
+// waiting for the token and the mail would tell how long known ones take.
+// The caller cannot be told about failures either way; they are logged.
+func (s *AccountService) sendInBackground(ctx context.Context, send func(ctx context.Context) error) {
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		if err := send(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Failed to send account mail: %v", err)
+		}
+	}()
+}
+
+// Wait blocks until the mails sent in the background by this instance have
+// gone out.
+func (s *AccountService) Wait() {
+	s.sending.Wait()
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. The
+// token was delivered to the user's address, which also verifies it.
+func (s *AccountService) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	token, user, err := s.consume(ctx, model.UserTokenResetPassword, req.Token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	before, err := s.updateUser(ctx, user, func(user *model.User) error {
+		if !strings.EqualFold(user.Email, token.Email) {
+			return ErrInvalidToken
+		}
+		user.Password = string(hashedPassword)
+		user.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	ctx = WithPrincipal(ctx, model.UserPrincipal(user))
+	return s.audit.Record(ctx, model.AuditActionPasswordReset, "user:"+token.UserID, before, user)
+}
+
+// RequestEmailChange mails a confirmation link to the user's pending address
+// and tells the current address that a change was asked for. A nil service
+// sends nothing.
+func (s *AccountService) RequestEmailChange(ctx context.Context, user *model.User) error {
+	if s == nil {
+		return nil
+	}
+
+	token, err := s.issue(ctx, user, user.PendingEmail, model.UserTokenChangeEmail, changeEmailMarker, s.opts.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, &model.Mail{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below within %s to start signing in with this address:\n\n%s\n\nIf you did not ask for this, you can ignore this message.\n",
+			user.FirstName, s.opts.VerificationTTL, s.link("/confirm-email", token)),
+	}); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &model.Mail{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your account to %s. The change takes effect once the new address is confirmed.\n\nIf this was not you, reset your password now to keep your account.\n",
+			user.FirstName, user.PendingEmail),
+	})
+}
+
+// ConfirmEmailChange moves the account to the pending address the token was
+// sent to. Tokens for an address the user has since replaced are rejected.
+func (s *AccountService) ConfirmEmailChange(ctx context.Context, req dto.VerifyEmailRequest) (*model.UserResponse, error) {
+	token, user, err := s.consume(ctx, model.UserTokenChangeEmail, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The address may have been taken since the change was asked for; the
+	// repository reports that as a conflict
+	before, err := s.updateUser(ctx, user, func(user *model.User) error {
+		if user.PendingEmail == "" || !strings.EqualFold(user.PendingEmail, token.Email) {
+			return ErrInvalidToken
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+		user.EmailVerified = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithPrincipal(ctx, model.UserPrincipal(user))
+	if err := s.audit.Record(ctx, model.AuditActionEmailChange, "user:"+token.UserID, before, user); err != nil {
+		return nil, err
+	}
+
+	return user.ToResponse(), nil
+}
+
+// issue stores a token for the given purpose, bound to the address it is
+// mailed to, and returns its secret.
+func (s *AccountService) issue(ctx context.Context, user *model.User, email, purpose, marker string, ttl time.Duration) (string, error) {
+	secret, err := generateToken(marker)
+	if err != nil {
+		return "", err
+	}
+
+	token := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     email,
+		Hash:      hashToken(secret),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// consume uses up the token and returns the user it was issued to. Callers
+// check that the address the token was mailed to is still the one they act on.
+func (s *AccountService) consume(ctx context.Context, purpose, secret string) (*model.UserToken, *model.User, error) {
+	token, err := s.tokenRepo.Consume(ctx, purpose, hashToken(secret), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	return token, user, nil
+}
+
+// updateUser applies change to the user and stores it, returning the user
+// as it was before. The token is used up by then, so when the user changed
+// since it was read the change is applied again to a fresh read rather than
+// given up.
+func (s *AccountService) updateUser(ctx context.Context, user *model.User, change func(*model.User) error) (*model.User, error) {
+	var before model.User
+	reread := false
+	err := retryOnConflict(ctx, conflictAttempts, func() error {
+		if reread {
+			fresh, err := s.userRepo.GetByID(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			*user = *fresh
+		}
+		reread = true
+
+		before = *user
+		if err := change(user); err != nil {
+			return err
+		}
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &before, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return strings.TrimSuffix(s.opts.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const defaultAuditLimit = 100
+
+// systemActor is recorded for changes made without a principal, such as the
+// scheduled invoice run.
+const systemActor = "system"
+
+// secretFields are matched against lower-cased field names; their values
+// never reach the audit log.
+var secretFields = []string{"password", "secret", "hash", "token"}
+
+// personalFields hold personal data, which is only stored encrypted. The
+// audit log is not encrypted, so their values are redacted like secrets.
+var personalFields = map[string]bool{"firstName": true, "lastName": true, "email": true, "pendingEmail": true}
+
+type AuditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func SetupAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	id       string
+	sourceIP string
+}
+
+// WithRequestInfo attaches the request ID and client address to ctx so that
+// audit entries can be traced back to the request that caused them.
+func WithRequestInfo(ctx context.Context, requestID, sourceIP string) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, requestInfo{id: requestID, sourceIP: sourceIP})
+}
+
+// sourceIP returns the client address attached with WithRequestInfo.
+func sourceIP(ctx context.Context) string {
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+	return info.sourceIP
+}
+
+// Record appends an entry for a change to target. before and after are the
+// stored documents around the change, either of which may be nil; only the
+// fields that differ are kept. Services call it once the change is stored and
+// return its error, so a change that could not be audited is not reported as
+// a success. A nil service records nothing.
+func (s *AuditService) Record(ctx context.Context, action, target string, before, after any) error {
+	if s == nil {
+		return nil
+	}
+
+	changedBefore, changedAfter, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("failed to diff audit entry: %w", err)
+	}
+
+	entry := s.entry(ctx, action, target)
+	entry.Before = changedBefore
+	entry.After = changedAfter
+	if err := s.auditRepo.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record %s: %w", action, err)
+	}
+
+	return nil
+}
+
+// ForgetSubject redacts the personal fields of every entry about target.
+// Entries written before those fields were redacted may still hold them, and
+// an anonymized user must not be identifiable from the audit log. A nil
+// service has nothing to forget.
+func (s *AuditService) ForgetSubject(ctx context.Context, target string) error {
+	if s == nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(personalFields))
+	for field := range personalFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if err := s.auditRepo.RedactFields(ctx, target, fields); err != nil {
+		return fmt.Errorf("failed to forget %s: %w", target, err)
+	}
+
+	return nil
+}
+
+// RecordAccess logs a request made by a support agent, and by anyone when
+// privileged is set. It runs before the request is served, and an error means
+// the request must be refused, so that no such access goes unrecorded.
+func (s *AuditService) RecordAccess(ctx context.Context, target string, privileged bool) error {
+	principal := PrincipalFrom(ctx)
+	if principal == nil {
+		return nil
+	}
+
+	var action string
+	switch {
+	case principal.HasRole(model.RoleSupport):
+		action = model.AuditActionSupportAccess
+	case privileged:
+		action = model.AuditActionAdminRead
+	default:
+		return nil
+	}
+
+	if err := s.auditRepo.Append(ctx, s.entry(ctx, action, target)); err != nil {
+		return fmt.Errorf("failed to record %s: %w", action, err)
+	}
+
+	return nil
+}
+
+// ListEntries returns the newest entries matching req.
+func (s *AuditService) ListEntries(ctx context.Context, req dto.ListAuditEntriesRequest) ([]*model.AuditEntry, error) {
+	if err := authorize(ctx, model.PermissionAuditRead, ""); err != nil {
+		return nil, err
+	}
+	if !req.From.IsZero() && !req.To.IsZero() && req.To.Before(req.From) {
+		return nil, ErrInvalidDateRange
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+
+	return s.auditRepo.Find(ctx, model.AuditFilter{
+		Actor:  req.Actor,
+		Action: req.Action,
+		Target: req.Target,
+		From:   req.From,
+		To:     req.To,
+		Limit:  limit,
+	})
+}
+
+func (s *AuditService) entry(ctx context.Context, action, target string) *model.AuditEntry {
+	actor := systemActor
+	if principal := PrincipalFrom(ctx); principal != nil {
+		actor = principal.Actor()
+	}
+	info, _ := ctx.Value(requestInfoKey{}).(requestInfo)
+
+	return &model.AuditEntry{
+		At:        time.Now(),
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		RequestID: info.id,
+		SourceIP:  info.sourceIP,
+	}
+}
+
+// auditDiff returns the fields of the BSON documents before and after that
+// differ. Secrets and personal data are redacted on both sides but still
+// show up as changed.
+func auditDiff(before, after any) (map[string]any, map[string]any, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changedBefore, changedAfter := map[string]any{}, map[string]any{}
+	collect := func(fields bson.M, changed map[string]any, other bson.M) {
+		for name, value := range fields {
+			if name == "_id" || reflect.DeepEqual(value, other[name]) {
+				continue
+			}
+			if isSecretField(name) || personalFields[name] {
+				value = model.RedactedValue
+			}
+			changed[name] = value
+		}
+	}
+	collect(beforeFields, changedBefore, afterFields)
+	collect(afterFields, changedAfter, beforeFields)
+
+	if len(changedBefore) == 0 {
+		changedBefore = nil
+	}
+	if len(changedAfter) == 0 {
+		changedAfter = nil
+	}
+	return changedBefore, changedAfter, nil
+}
+
+func auditFields(document any) (bson.M, error) {
+	if document == nil {
+		return nil, nil
+	}
+	if value := reflect.ValueOf(document); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil, nil
+	}
+
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, secret := range secretFields {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+	return false
+}
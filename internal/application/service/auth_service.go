@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// sessionMarker starts every session token, which is how Authenticate tells
+// them apart from API keys.
+const sessionMarker = "pss_"
+
+// dummyPasswordHash is compared against when no account has the email, so
+// that a login takes as long whether or not the account exists.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("no account has this password"), bcrypt.DefaultCost)
+	return hash
+})
+
+type AuthService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	apiKeys     *APIKeyService
+	mfa         *MFAService
+	lockout     *LockoutService
+	sessionTTL  time.Duration
+}
+
+func SetupAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, apiKeys *APIKeyService, mfa *MFAService, lockout *LockoutService, sessionTTL time.Duration) *AuthService {
+	return &AuthService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		apiKeys:     apiKeys,
+		mfa:         mfa,
+		lockout:     lockout,
+		sessionTTL:  sessionTTL,
+	}
+}
+
+// Login checks a user's password and opens a session. Users with MFA get a
+// challenge instead, to answer with VerifyMFA. Unknown emails and wrong
+// passwords fail alike and take as long, and both count towards a lockout.
+func (s *AuthService) Login(ctx context.Context, req dto.LoginRequest) (*model.IssuedSession, error) {
+	if err := s.lockout.check(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	hash := dummyPasswordHash()
+	if user != nil {
+		hash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || user == nil {
+		if err := s.lockout.fail(ctx, req.Email, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Failures are only forgotten once the whole login succeeds, so that a
+	// correct password does not reset the count of wrong codes
+	if s.mfa.Enabled(user) {
+		challenge, expiresAt, err := s.mfa.challenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &model.IssuedSession{MFARequired: true, Challenge: challenge, ExpiresAt: expiresAt}, nil
+	}
+
+	if err := s.lockout.succeed(ctx, req.Email); err != nil {
+		return nil, err
+	}
+	return s.open(ctx, user, false)
+}
+
+// VerifyMFA completes a login that was answered with a challenge. Accounts
+// locked since the challenge was issued are refused even a valid code.
+func (s *AuthService) VerifyMFA(ctx context.Context, req dto.MFAVerifyRequest) (*model.IssuedSession, error) {
+	if s.mfa == nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.mfa.answer(ctx, req.Challenge, req.Code, func(user *model.User) error {
+		return s.lockout.check(ctx, user.Email)
+	})
+	if err != nil {
+		// Wrong codes count like wrong passwords, so holding the password
+		// does not allow guessing codes without limit
+		if user != nil && errors.Is(err, ErrInvalidMFACode) {
+			if err := s.lockout.fail(ctx, user.Email, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.lockout.succeed(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	return s.open(ctx, user, true)
+}
+
+func (s *AuthService) open(ctx context.Context, user *model.User, mfa bool) (*model.IssuedSession, error) {
+	token, err := generateToken(sessionMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &model.Session{
+		UserID:    user.ID,
+		Hash:      hashToken(token),
+		MFA:       mfa,
+		ExpiresAt: time.Now().Add(s.sessionTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &model.IssuedSession{Token: token, ExpiresAt: session.ExpiresAt, User: user.ToResponse()}, nil
+}
+
+// Logout ends the session of token. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	session, err := s.sessionRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sessionRepo.Delete(ctx, session.ID)
+}
+
+// Authenticate resolves a session token or an API key to its principal. A
+// user's roles are read on every request, so role changes apply at once.
+// Roles that require MFA only apply to sessions opened with a second factor.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*model.Principal, error) {
+	if !strings.HasPrefix(token, sessionMarker) {
+		key, err := s.apiKeys.Authenticate(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return model.APIKeyPrincipal(key), nil
+	}
+
+	session, err := s.sessionRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	return model.UserPrincipal(s.mfa.Restrict(user, session.MFA)), nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+type principalKey struct{}
+
+// WithPrincipal attaches the caller to ctx. The HTTP and gRPC layers do this
+// for every authenticated request before calling a service.
+func WithPrincipal(ctx context.Context, principal *model.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the caller attached to ctx, or nil.
+func PrincipalFrom(ctx context.Context) *model.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*model.Principal)
+	return principal
+}
+
+// authorize checks that the caller holds the permission over data owned by
+// ownerID. Calls without a principal come from inside the process, such as
+// scheduled jobs, and are trusted: every API entry point attaches one.
+func authorize(ctx context.Context, permission, ownerID string) error {
+	principal := PrincipalFrom(ctx)
+	if principal == nil || principal.Can(permission, ownerID) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrPermissionDenied, permission)
+}
+
+// lineScope tells whether the caller holds the permission over every owner
+// of a line, and if not, the user whose own data a line-scoped read is
+// narrowed to. Numbers are recycled, so customers only see their own time on
+// a line, as with statements and cycle summaries.
+func lineScope(ctx context.Context, permission string) (userID string, all bool) {
+	principal := PrincipalFrom(ctx)
+	if principal == nil || principal.Can(permission, "") {
+		return "", true
+	}
+	return principal.UserID, false
+}
+
+// visibleCycles keeps the cycles whose owner the caller holds the permission
+// over.
+func visibleCycles(ctx context.Context, permission string, cycles []*model.Cycle) []*model.Cycle {
+	if _, all := lineScope(ctx, permission); all {
+		return cycles
+	}
+	visible := make([]*model.Cycle, 0, len(cycles))
+	for _, cycle := range cycles {
+		if authorize(ctx, permission, cycle.UserID) == nil {
+			visible = append(visible, cycle)
+		}
+	}
+	return visible
+}
+
+// lineCycles returns the cycles of the MDN the caller can see, newest first.
+// Callers who never held the line are denied.
+func lineCycles(ctx context.Context, cycleRepo repository.CycleRepository, permission, mdn string) ([]*model.Cycle, error) {
+	cycles, err := cycleRepo.GetByMDN(ctx, mdn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycles: %w", err)
+	}
+	if _, all := lineScope(ctx, permission); all {
+		return cycles, nil
+	}
+
+	cycles = visibleCycles(ctx, permission, cycles)
+	if len(cycles) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, permission)
+	}
+	return cycles, nil
+}
+
+// authorizeLine denies callers who never held the line. Only callers
+// narrowed to their own data need the line's cycles read for it.
+func authorizeLine(ctx context.Context, cycleRepo repository.CycleRepository, permission, mdn string) error {
+	if _, all := lineScope(ctx, permission); all {
+		return nil
+	}
+	_, err := lineCycles(ctx, cycleRepo, permission, mdn)
+	return err
+}
@@ -0,0 +1,16 @@
+package service
+
+import "time"
+
+// Clock tells services the time. Tests substitute a fake one to exercise
+// time-based codes without waiting.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+// conflictAttempts bounds how often a read-modify-write is started over
+// after another writer got in first.
+const conflictAttempts = 3
+
+// retryOnConflict runs attempt until it stores its write without a
+// concurrent modification, at most attempts times. Each attempt must read
+// what it modifies afresh. The last conflict is returned once the attempts
+// run out, which callers see as 412.
+func retryOnConflict(ctx context.Context, attempts int, attempt func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = attempt(); !errors.Is(err, repository.ErrConcurrentModification) {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
@@ -21,7 +21,8 @@ func SetupCycleService(cycleRepo repository.CycleRepository) *CycleService {
 }
 
 // Note: Query by MDN, not just userId, because MDNs can be transferred between users
-// This ensures we return the full history of the phone number, regardless of ownership changes
+// This ensures we return the full history of the phone number, regardless of ownership changes.
+// Customers only get the cycles they owned themselves.
 func (s *CycleService) GetCycleHistory(ctx context.Context, req dto.GetCycleHistoryRequest) ([]*model.CycleResponse, error) {
 	if req.UserID == "" {
 		return nil, fmt.Errorf("userId is required")
@@ -30,9 +31,9 @@ func (s *CycleService) GetCycleHistory(ctx context.Context, req dto.GetCycleHist
 		return nil, fmt.Errorf("mdn is required")
 	}
 
-	cycles, err := s.cycleRepo.GetByMDN(ctx, req.MDN)
+	cycles, err := lineCycles(ctx, s.cycleRepo, model.PermissionUsageRead, req.MDN)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cycle history: %w", err)
+		return nil, err
 	}
 
 	responses := make([]*model.CycleResponse, len(cycles))
@@ -43,11 +44,71 @@ func (s *CycleService) GetCycleHistory(ctx context.Context, req dto.GetCycleHist
 	return responses, nil
 }
 
+// StreamCycleHistory is GetCycleHistory for exports: cycles are handed to fn
+// as they are read instead of being collected first.
+func (s *CycleService) StreamCycleHistory(ctx context.Context, req dto.GetCycleHistoryRequest, fn func(*model.CycleResponse) error) error {
+	if req.UserID == "" {
+		return fmt.Errorf("userId is required")
+	}
+	if req.MDN == "" {
+		return fmt.Errorf("mdn is required")
+	}
+
+	// Customers only hold a few cycles of a line, which are loaded and
+	// filtered rather than streamed
+	if _, all := lineScope(ctx, model.PermissionUsageRead); !all {
+		cycles, err := lineCycles(ctx, s.cycleRepo, model.PermissionUsageRead, req.MDN)
+		if err != nil {
+			return err
+		}
+		for _, cycle := range cycles {
+			if err := fn(cycle.ToResponse()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return s.cycleRepo.StreamByMDN(ctx, req.MDN, func(cycle *model.Cycle) error {
+		return fn(cycle.ToResponse())
+	})
+}
+
 func (s *CycleService) GetCurrentCycle(ctx context.Context, userID, mdn string) (*model.Cycle, error) {
+	if err := authorize(ctx, model.PermissionUsageRead, userID); err != nil {
+		return nil, err
+	}
+
 	cycle, err := s.cycleRepo.GetCurrentCycle(ctx, userID, mdn, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
 	return cycle, nil
-}
\ No newline at end of file
+}
+
+// GetCyclesByMDNs returns the full history of several MDNs, newest first,
+// in a single repository call. Cycles the caller cannot see are left out
+// instead of failing the batch.
+func (s *CycleService) GetCyclesByMDNs(ctx context.Context, mdns []string) ([]*model.Cycle, error) {
+	cycles, err := s.cycleRepo.GetByMDNs(ctx, mdns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycles: %w", err)
+	}
+	return visibleCycles(ctx, model.PermissionUsageRead, cycles), nil
+}
+
+// GetCyclesByUserIDs returns every cycle owned by any of the users, newest first.
+func (s *CycleService) GetCyclesByUserIDs(ctx context.Context, userIDs []string) ([]*model.Cycle, error) {
+	for _, userID := range userIDs {
+		if err := authorize(ctx, model.PermissionUsageRead, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	cycles, err := s.cycleRepo.GetByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycles: %w", err)
+	}
+	return cycles, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,15 +11,31 @@ import (
 	"github.com/bowe99/phone-usage-service/internal/domain/repository"
 )
 
+const defaultTrendCycles = 6
+
+var (
+	ErrNoCyclesFound  = errors.New("no billing cycles found")
+	ErrCycleNotOnLine = errors.New("cycle does not belong to this line")
+	ErrCycleInvoiced  = errors.New("usage of an invoiced cycle cannot be changed")
+)
+
 type DailyUsageService struct {
-	usageRepo repository.DailyUsageRepository
-	cycleRepo repository.CycleRepository
+	usageRepo   repository.DailyUsageRepository
+	cycleRepo   repository.CycleRepository
+	summaryRepo repository.CycleSummaryRepository
+	invoiceRepo repository.InvoiceRepository
+	broker      repository.UsageEventBroker
+	audit       *AuditService
 }
 
-func SetupDailyUsageService(usageRepo repository.DailyUsageRepository, cycleRepo repository.CycleRepository) *DailyUsageService {
+func SetupDailyUsageService(usageRepo repository.DailyUsageRepository, cycleRepo repository.CycleRepository, summaryRepo repository.CycleSummaryRepository, invoiceRepo repository.InvoiceRepository, broker repository.UsageEventBroker, audit *AuditService) *DailyUsageService {
 	return &DailyUsageService{
-		usageRepo: usageRepo,
-		cycleRepo: cycleRepo,
+		usageRepo:   usageRepo,
+		cycleRepo:   cycleRepo,
+		summaryRepo: summaryRepo,
+		invoiceRepo: invoiceRepo,
+		broker:      broker,
+		audit:       audit,
 	}
 }
 
@@ -33,6 +50,9 @@ func (s *DailyUsageService) GetCurrentCycleUsage(ctx context.Context, req dto.Ge
 	if req.MDN == "" {
 		return nil, fmt.Errorf("mdn is required")
 	}
+	if err := authorize(ctx, model.PermissionUsageRead, req.UserID); err != nil {
+		return nil, err
+	}
 
 	currentCycle, err := s.cycleRepo.GetCurrentCycle(ctx, req.UserID, req.MDN, time.Now())
 	if err != nil {
@@ -57,3 +77,340 @@ func (s *DailyUsageService) GetCurrentCycleUsage(ctx context.Context, req dto.Ge
 
 	return responses, nil
 }
+
+// StreamCurrentCycleUsage is GetCurrentCycleUsage for exports. The cycle is
+// resolved before fn is first called, so a missing cycle is reported as an
+// error before any output is produced.
+func (s *DailyUsageService) StreamCurrentCycleUsage(ctx context.Context, req dto.GetCurrentCycleUsageRequest, fn func(*model.DailyUsageResponse) error) error {
+	if req.UserID == "" {
+		return fmt.Errorf("userId is required")
+	}
+	if req.MDN == "" {
+		return fmt.Errorf("mdn is required")
+	}
+	if err := authorize(ctx, model.PermissionUsageRead, req.UserID); err != nil {
+		return err
+	}
+
+	currentCycle, err := s.cycleRepo.GetCurrentCycle(ctx, req.UserID, req.MDN, time.Now())
+	if err != nil {
+		return fmt.Errorf("no active billing cycle found for user %s and MDN %s: %w", req.UserID, req.MDN, err)
+	}
+
+	return s.usageRepo.StreamByDateRange(ctx, req.UserID, req.MDN, currentCycle.StartDate, currentCycle.EndDate, func(usage *model.DailyUsage) error {
+		return fn(usage.ToResponse())
+	})
+}
+
+// StreamUsage exports the usage of an MDN across every owner it had in the
+// range. Customers only get the usage of their own cycles.
+func (s *DailyUsageService) StreamUsage(ctx context.Context, mdn string, dates dto.UsageDateRange, fn func(*model.DailyUsage) error) error {
+	if mdn == "" {
+		return fmt.Errorf("mdn is required")
+	}
+	if err := authorizeLine(ctx, s.cycleRepo, model.PermissionUsageRead, mdn); err != nil {
+		return err
+	}
+
+	startDate, endDate, err := dayRange(dates)
+	if err != nil {
+		return err
+	}
+
+	if userID, all := lineScope(ctx, model.PermissionUsageRead); !all {
+		return s.usageRepo.StreamByDateRange(ctx, userID, mdn, startDate, endDate, fn)
+	}
+	return s.usageRepo.StreamByMDN(ctx, mdn, startDate, endDate, fn)
+}
+
+// Algorithm:
+//  1. Load the most recent N cycles of the MDN (any owner, since MDNs can be transferred;
+//     customers only their own)
+//  2. Sum usage per cycle with one aggregation, counting the first D days separately
+//     where D is the number of elapsed days of the current cycle if it is still open
+//  3. Compare each cycle with the one before it; a partial cycle is compared against
+//     the first D days of the previous cycle so the numbers are like-for-like
+func (s *DailyUsageService) GetUsageTrends(ctx context.Context, req dto.GetUsageTrendsRequest) (*model.UsageTrendResponse, error) {
+	if req.MDN == "" {
+		return nil, fmt.Errorf("mdn is required")
+	}
+	limit := req.Cycles
+	if limit <= 0 {
+		limit = defaultTrendCycles
+	}
+
+	cycles, err := lineCycles(ctx, s.cycleRepo, model.PermissionUsageRead, req.MDN)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// Cycles that have not started yet carry no usage and would skew the comparison
+	started := make([]*model.Cycle, 0, len(cycles))
+	for _, cycle := range cycles {
+		if !cycle.StartDate.After(now) {
+			started = append(started, cycle)
+		}
+	}
+	if len(started) == 0 {
+		return nil, ErrNoCyclesFound
+	}
+	if len(started) > limit {
+		started = started[:limit]
+	}
+
+	alignDays := 0
+	if newest := started[0]; now.Before(newest.EndDate) {
+		alignDays = newest.DaysElapsed(now)
+	}
+
+	totals, err := s.usageRepo.GetCycleTotals(ctx, req.MDN, started, alignDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycle totals: %w", err)
+	}
+
+	return buildUsageTrends(req.MDN, started, totals, alignDays, now), nil
+}
+
+// buildUsageTrends combines cycles (newest first) with their aggregated totals.
+// Cycles without any usage documents are reported with a zero total.
+func buildUsageTrends(mdn string, cycles []*model.Cycle, totals []*model.CycleUsageTotal, alignDays int, now time.Time) *model.UsageTrendResponse {
+	byCycle := make(map[string]*model.CycleUsageTotal, len(totals))
+	for _, total := range totals {
+		byCycle[total.CycleID] = total
+	}
+
+	trends := make([]*model.CycleTrend, len(cycles))
+	for i, cycle := range cycles {
+		trend := &model.CycleTrend{
+			CycleID:     cycle.ID,
+			StartDate:   cycle.StartDate,
+			EndDate:     cycle.EndDate,
+			Partial:     now.Before(cycle.EndDate),
+			DaysElapsed: cycle.DaysElapsed(now),
+		}
+		if total, ok := byCycle[cycle.ID]; ok {
+			trend.TotalUsage = total.TotalMB
+			if alignDays > 0 {
+				aligned := total.AlignedMB
+				trend.AlignedUsage = &aligned
+			}
+		} else if alignDays > 0 {
+			zero := 0.0
+			trend.AlignedUsage = &zero
+		}
+		if trend.DaysElapsed > 0 {
+			trend.AverageDailyUsage = trend.TotalUsage / float64(trend.DaysElapsed)
+		}
+		trends[i] = trend
+	}
+
+	for i := 0; i < len(trends)-1; i++ {
+		current, previous := trends[i], trends[i+1]
+		base := previous.TotalUsage
+		if current.Partial && previous.AlignedUsage != nil {
+			base = *previous.AlignedUsage
+		}
+		delta := current.TotalUsage - base
+		current.Delta = &delta
+		if base != 0 {
+			percent := delta / base * 100
+			current.PercentChange = &percent
+		}
+	}
+
+	return &model.UsageTrendResponse{
+		MDN:         mdn,
+		AlignedDays: alignDays,
+		Cycles:      trends,
+	}
+}
+
+// RecordUsage stores the usage of a single day, replacing any value already
+// recorded for that day, and folds the change into the cycle summary. Replaying
+// a request that failed part way through repairs the summary. Invoices are
+// never changed once issued, so the usage they billed cannot be either.
+func (s *DailyUsageService) RecordUsage(ctx context.Context, req dto.RecordUsageRequest) (*model.DailyUsageResponse, error) {
+	if req.UserID == "" {
+		return nil, fmt.Errorf("userId is required")
+	}
+	if req.MDN == "" {
+		return nil, fmt.Errorf("mdn is required")
+	}
+	if req.UsedInMB == nil || *req.UsedInMB < 0 {
+		return nil, fmt.Errorf("usedInMb must be zero or positive")
+	}
+	if err := authorize(ctx, model.PermissionUsageWrite, req.UserID); err != nil {
+		return nil, err
+	}
+
+	usageDate := req.UsageDate.UTC().Truncate(24 * time.Hour)
+	cycle, err := s.cycleRepo.GetCurrentCycle(ctx, req.UserID, req.MDN, usageDate)
+	if err != nil {
+		return nil, fmt.Errorf("no billing cycle covers %s for user %s and MDN %s: %w", usageDate.Format(time.DateOnly), req.UserID, req.MDN, err)
+	}
+	// Only cycles that have ended are invoiced
+	if !time.Now().Before(cycle.EndDate) {
+		_, err := s.invoiceRepo.GetByCycleID(ctx, cycle.ID)
+		if err == nil {
+			return nil, ErrCycleInvoiced
+		}
+		if !errors.Is(err, repository.ErrInvoiceNotFound) {
+			return nil, fmt.Errorf("failed to check for an invoice: %w", err)
+		}
+	}
+
+	// The summary is adjusted by the difference to the value this write
+	// replaced, so a record changed or created since it was read is read
+	// again
+	var usage *model.DailyUsage
+	var before *model.DailyUsage
+	err = retryOnConflict(ctx, conflictAttempts, func() error {
+		existing, err := s.usageRepo.GetByDateRange(ctx, req.UserID, req.MDN, usageDate, usageDate.Add(24*time.Hour-time.Nanosecond))
+		if err != nil {
+			return fmt.Errorf("failed to get usage records: %w", err)
+		}
+
+		if len(existing) == 0 {
+			before = nil
+			usage = &model.DailyUsage{
+				MDN:       req.MDN,
+				UserID:    req.UserID,
+				UsageDate: usageDate,
+				UsedInMB:  *req.UsedInMB,
+			}
+			if err := s.usageRepo.Create(ctx, usage); err != nil {
+				return fmt.Errorf("failed to create usage: %w", err)
+			}
+			return nil
+		}
+
+		usage = existing[0]
+		previous := *usage
+		before = &previous
+		usage.UsedInMB = *req.UsedInMB
+		if err := s.usageRepo.Update(ctx, usage); err != nil {
+			return fmt.Errorf("failed to update usage: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	previousMB := 0.0
+	if before != nil {
+		previousMB = before.UsedInMB
+		// Only corrections are audited; first recordings are the raw feed
+		if usage.UsedInMB != previousMB {
+			if err := s.audit.Record(ctx, model.AuditActionUsageCorrection, "usage:"+usage.ID, before, usage); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The usage and the summary are separate writes. A write that changes
+	// nothing may be the replay of one whose summary update never happened,
+	// and a failed update leaves the summary unknown, so both rebuild it from
+	// the stored usage; that makes replaying a failed request safe.
+	rebuild := before != nil && usage.UsedInMB == previousMB
+	if !rebuild {
+		summary, err := s.summaryRepo.ApplyUsage(ctx, cycle, usageDate, usage.UsedInMB, previousMB, before == nil)
+		// Lowering the peak day means another day may now be the peak, which
+		// the incremental update cannot know about
+		rebuild = err != nil || (usage.UsedInMB < previousMB && summary.PeakDate.Equal(usageDate))
+	}
+	if rebuild {
+		if err := s.summaryRepo.Rebuild(ctx, cycle.ID); err != nil {
+			return nil, fmt.Errorf("failed to rebuild cycle summary: %w", err)
+		}
+	}
+
+	// The usage is already stored, so live subscribers are best effort
+	_ = s.broker.Publish(ctx, &model.UsageEvent{
+		Type:       model.UsageEventDailyUsage,
+		MDN:        usage.MDN,
+		UserID:     usage.UserID,
+		UsageDate:  usage.UsageDate,
+		DailyUsage: usage.UsedInMB,
+	})
+
+	return usage.ToResponse(), nil
+}
+
+func (s *DailyUsageService) GetCurrentCycleSummary(ctx context.Context, req dto.GetCurrentCycleUsageRequest) (*model.CycleSummaryResponse, error) {
+	if req.UserID == "" {
+		return nil, fmt.Errorf("userId is required")
+	}
+	if req.MDN == "" {
+		return nil, fmt.Errorf("mdn is required")
+	}
+	if err := authorize(ctx, model.PermissionUsageRead, req.UserID); err != nil {
+		return nil, err
+	}
+
+	currentCycle, err := s.cycleRepo.GetCurrentCycle(ctx, req.UserID, req.MDN, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("no active billing cycle found for user %s and MDN %s: %w", req.UserID, req.MDN, err)
+	}
+
+	return s.summaryFor(ctx, currentCycle)
+}
+
+func (s *DailyUsageService) GetCycleSummary(ctx context.Context, req dto.GetCycleSummaryRequest) (*model.CycleSummaryResponse, error) {
+	cycle, err := s.cycleRepo.GetByID(ctx, req.CycleID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle.MDN != req.MDN {
+		return nil, ErrCycleNotOnLine
+	}
+	if err := authorize(ctx, model.PermissionUsageRead, cycle.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.summaryFor(ctx, cycle)
+}
+
+func (s *DailyUsageService) summaryFor(ctx context.Context, cycle *model.Cycle) (*model.CycleSummaryResponse, error) {
+	summary, err := s.summaryRepo.GetByCycleID(ctx, cycle.ID)
+	if errors.Is(err, repository.ErrCycleSummaryNotFound) {
+		return model.EmptyCycleSummary(cycle).ToResponse(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycle summary: %w", err)
+	}
+
+	return summary.ToResponse(), nil
+}
+
+// GetUsageForCycles loads the daily usage of several cycles in one repository
+// call and returns it keyed by cycle ID, oldest day first. Like the current
+// cycle endpoints, a cycle only includes the usage of its own owner. Cycles
+// the caller cannot see are left out instead of failing the batch.
+func (s *DailyUsageService) GetUsageForCycles(ctx context.Context, cycles []*model.Cycle) (map[string][]*model.DailyUsageResponse, error) {
+	cycles = visibleCycles(ctx, model.PermissionUsageRead, cycles)
+	if len(cycles) == 0 {
+		return map[string][]*model.DailyUsageResponse{}, nil
+	}
+
+	usages, err := s.usageRepo.GetByCycles(ctx, cycles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage records: %w", err)
+	}
+
+	byCycle := make(map[string][]*model.DailyUsageResponse, len(cycles))
+	for _, cycle := range cycles {
+		byCycle[cycle.ID] = []*model.DailyUsageResponse{}
+	}
+	for _, usage := range usages {
+		for _, cycle := range cycles {
+			if usage.UserID == cycle.UserID && usage.MDN == cycle.MDN &&
+				!usage.UsageDate.Before(cycle.StartDate) && !usage.UsageDate.After(cycle.EndDate) {
+				byCycle[cycle.ID] = append(byCycle[cycle.ID], usage.ToResponse())
+			}
+		}
+	}
+
+	return byCycle, nil
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+// ErrDataExportNotReady is returned for the archive of an export that is
+// still running or has failed.
+var ErrDataExportNotReady = errors.New("data export is not ready")
+
+const defaultDataExportTimeout = 10 * time.Minute
+
+type DataExportOptions struct {
+	// TTL is how long a finished archive can be downloaded
+	TTL time.Duration
+	// Timeout bounds assembling one archive. Exports still running after it
+	// are reported failed, as the instance running them may have stopped.
+	Timeout time.Duration
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// DataExportService assembles everything stored about a user, for subject
+// access requests, into an archive that is built in the background and
+// downloaded once ready.
+type DataExportService struct {
+	exportRepo  repository.DataExportRepository
+	userRepo    repository.UserRepository
+	cycleRepo   repository.CycleRepository
+	usageRepo   repository.DailyUsageRepository
+	invoiceRepo repository.InvoiceRepository
+	auditRepo   repository.AuditRepository
+	archiver    repository.DataArchiver
+	opts        DataExportOptions
+	audit       *AuditService
+	running     sync.WaitGroup
+}
+
+func SetupDataExportService(exportRepo repository.DataExportRepository, userRepo repository.UserRepository, cycleRepo repository.CycleRepository, usageRepo repository.DailyUsageRepository, invoiceRepo repository.InvoiceRepository, auditRepo repository.AuditRepository, archiver repository.DataArchiver, opts DataExportOptions, audit *AuditService) *DataExportService {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultDataExportTimeout
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &DataExportService{
+		exportRepo:  exportRepo,
+		userRepo:    userRepo,
+		cycleRepo:   cycleRepo,
+		usageRepo:   usageRepo,
+		invoiceRepo: invoiceRepo,
+		auditRepo:   auditRepo,
+		archiver:    archiver,
+		opts:        opts,
+		audit:       audit,
+	}
+}
+
+// RequestExport starts assembling the archive of a user and returns the
+// running export to poll.
+func (s *DataExportService) RequestExport(ctx context.Context, userID string) (*model.DataExport, error) {
+	if err := authorize(ctx, model.PermissionUsersRead, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.opts.Clock.Now()
+	export := &model.DataExport{
+		UserID:    user.ID,
+		Status:    model.DataExportRunning,
+		CreatedAt: now,
+		// Completing the export moves this to TTL after it finished
+		ExpiresAt: now.Add(s.opts.Timeout + s.opts.TTL),
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionDataExport, "user:"+user.ID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	// The export outlives the request; it keeps the request's values so that
+	// what it reads is traced back to it
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.run(context.WithoutCancel(ctx), export.ID, user)
+	}()
+
+	return export, nil
+}
+
+// GetExport returns the status of an export of the user's data.
+func (s *DataExportService) GetExport(ctx context.Context, userID, exportID string) (*model.DataExport, error) {
+	if err := authorize(ctx, model.PermissionUsersRead, userID); err != nil {
+		return nil, err
+	}
+
+	export, err := s.exportRepo.GetByID(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export.UserID != userID {
+		return nil, repository.ErrDataExportNotFound
+	}
+
+	if export.Status == model.DataExportRunning && s.opts.Clock.Now().After(export.CreatedAt.Add(s.opts.Timeout)) {
+		export.Status = model.DataExportFailed
+		export.Error = "export did not finish in time"
+	}
+
+	return export, nil
+}
+
+// DownloadExport returns a ready export together with its archive.
+func (s *DataExportService) DownloadExport(ctx context.Context, userID, exportID string) (*model.DataExport, []byte, error) {
+	export, err := s.GetExport(ctx, userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != model.DataExportReady {
+		return nil, nil, ErrDataExportNotReady
+	}
+
+	archive, err := s.exportRepo.GetArchive(ctx, export.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionDataExportDownload, "user:"+userID, nil, nil); err != nil {
+		return nil, nil, err
+	}
+
+	return export, archive, nil
+}
+
+// ContentType is the media type of the archives.
+func (s *DataExportService) ContentType() string {
+	return s.archiver.ContentType()
+}
+
+// Wait blocks until the exports started by this instance have finished.
+func (s *DataExportService) Wait() {
+	s.running.Wait()
+}
+
+func (s *DataExportService) run(ctx context.Context, exportID string, user *model.User) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	var archive bytes.Buffer
+	err := s.assemble(ctx, user, &archive)
+	now := s.opts.Clock.Now()
+	if err == nil {
+		err = s.exportRepo.Complete(ctx, exportID, archive.Bytes(), now, now.Add(s.opts.TTL))
+	}
+	if err != nil {
+		// Nobody waits for the result; the export reports the failure
+		_ = s.exportRepo.Fail(context.WithoutCancel(ctx), exportID, err.Error(), now)
+	}
+}
+
+// assemble writes the user's profile, the cycles and usage of every line
+// they owned, their invoices and the audit entries by and about them. Usage
+// is only included for the cycles the user owned, so a line's earlier or
+// later owners are left out.
+func (s *DataExportService) assemble(ctx context.Context, user *model.User, archive *bytes.Buffer) error {
+	cycles, err := s.cycleRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get cycles: %w", err)
+	}
+
+	usage, err := s.usageRepo.GetByCycles(ctx, cycles)
+	if err != nil {
+		return fmt.Errorf("failed to get usage records: %w", err)
+	}
+
+	invoices, err := s.invoiceRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	entries, err := s.auditEntries(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	tables := []*model.ExportTable{
+		profileTable(user),
+		cyclesTable(cycles),
+		usageTable(usage),
+		invoicesTable(invoices),
+		invoiceLineItemsTable(invoices),
+	}
+	audit, err := auditTable(entries)
+	if err != nil {
+		return err
+	}
+	tables = append(tables, audit)
+
+	return s.archiver.Write(archive, tables)
+}
+
+// auditEntries returns the entries about the user and those of their own
+// actions, newest first.
+func (s *DataExportService) auditEntries(ctx context.Context, userID string) ([]*model.AuditEntry, error) {
+	about, err := s.auditRepo.Find(ctx, model.AuditFilter{Target: "user:" + userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+	by, err := s.auditRepo.Find(ctx, model.AuditFilter{Actor: "user:" + userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+
+	seen := make(map[string]bool, len(about))
+	entries := make([]*model.AuditEntry, 0, len(about)+len(by))
+	for _, entry := range append(about, by...) {
+		if seen[entry.ID] {
+			continue
+		}
+		seen[entry.ID] = true
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.After(entries[j].At) })
+
+	return entries, nil
+}
+
+func profileTable(user *model.User) *model.ExportTable {
+	profile := user.ToResponse()
+	return &model.ExportTable{
+		Name:    "profile",
+		Columns: []string{"id", "firstName", "lastName", "email", "emailVerified", "pendingEmail", "roles", "mfaEnabled", "createdAt", "updatedAt"},
+		Rows: [][]any{{
+			profile.ID, profile.FirstName, profile.LastName, profile.Email, profile.EmailVerified,
+			profile.PendingEmail, strings.Join(profile.Roles, ","), profile.MFAEnabled, profile.CreatedAt, profile.UpdatedAt,
+		}},
+	}
+}
+
+func cyclesTable(cycles []*model.Cycle) *model.ExportTable {
+	table := &model.ExportTable{
+		Name:    "cycles",
+		Columns: []string{"id", "mdn", "startDate", "endDate", "planId", "createdAt"},
+	}
+	for _, cycle := range cycles {
+		table.Rows = append(table.Rows, []any{cycle.ID, cycle.MDN, cycle.StartDate, cycle.EndDate, cycle.PlanID, cycle.CreatedAt})
+	}
+	return table
+}
+
+func usageTable(usage []*model.DailyUsage) *model.ExportTable {
+	table := &model.ExportTable{
+		Name:    "daily_usage",
+		Columns: []string{"date", "mdn", "usedInMb", "createdAt", "updatedAt"},
+	}
+	for _, record := range usage {
+		table.Rows = append(table.Rows, []any{record.UsageDate, record.MDN, record.UsedInMB, record.CreatedAt, record.UpdatedAt})
+	}
+	return table
+}
+
+// invoicesTable holds amounts in minor units of the invoice's currency.
+func invoicesTable(invoices []*model.Invoice) *model.ExportTable {
+	table := &model.ExportTable{
+		Name: "invoices",
+		Columns: []string{
+			"id", "cycleId", "mdn", "planId", "periodStart", "periodEnd", "usageMb",
+			"currency", "subtotal", "credits", "tax", "total", "issuedAt",
+		},
+	}
+	for _, invoice := range invoices {
+		table.Rows = append(table.Rows, []any{
+			invoice.ID, invoice.CycleID, invoice.MDN, invoice.PlanID, invoice.PeriodStart, invoice.PeriodEnd, invoice.UsageMB,
+			invoice.Currency, invoice.Subtotal.Amount, invoice.Credits.Amount, invoice.Tax.Amount, invoice.Total.Amount, invoice.IssuedAt,
+		})
+	}
+	return table
+}
+
+func invoiceLineItemsTable(invoices []*model.Invoice) *model.ExportTable {
+	table := &model.ExportTable{
+		Name:    "invoice_line_items",
+		Columns: []string{"invoiceId", "type", "description", "quantity", "unitPrice", "amount", "currency"},
+	}
+	for _, invoice := range invoices {
+		for _, item := range invoice.LineItems {
+			table.Rows = append(table.Rows, []any{
+				invoice.ID, item.Type, item.Description, item.Quantity, item.UnitPrice.Amount, item.Amount.Amount, item.Amount.Currency,
+			})
+		}
+	}
+	return table
+}
+
+// auditTable holds the changed fields of each entry as JSON objects.
+func auditTable(entries []*model.AuditEntry) (*model.ExportTable, error) {
+	table := &model.ExportTable{
+		Name:    "audit_log",
+		Columns: []string{"id", "at", "actor", "action", "target", "before", "after", "requestId", "sourceIp"},
+	}
+	for _, entry := range entries {
+		before, err := jsonCell(entry.Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := jsonCell(entry.After)
+		if err != nil {
+			return nil, err
+		}
+		table.Rows = append(table.Rows, []any{
+			entry.ID, entry.At, entry.Actor, entry.Action, entry.Target, before, after, entry.RequestID, entry.SourceIP,
+		})
+	}
+	return table, nil
+}
+
+func jsonCell(fields map[string]any) (any, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit fields: %w", err)
+	}
+	return string(data), nil
+}
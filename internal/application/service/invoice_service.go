@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+var (
+	ErrCycleNotClosed   = errors.New("cycle has not ended yet")
+	ErrNoPlanForCycle   = errors.New("cycle has no plan and no default plan is configured")
+	ErrCurrencyMismatch = model.ErrCurrencyMismatch
+)
+
+type InvoiceService struct {
+	invoiceRepo   repository.InvoiceRepository
+	cycleRepo     repository.CycleRepository
+	usageRepo     repository.DailyUsageRepository
+	planRepo      repository.PlanRepository
+	creditRepo    repository.CreditRepository
+	defaultPlanID string
+	audit         *AuditService
+}
+
+func SetupInvoiceService(invoiceRepo repository.InvoiceRepository, cycleRepo repository.CycleRepository, usageRepo repository.DailyUsageRepository, planRepo repository.PlanRepository, creditRepo repository.CreditRepository, defaultPlanID string, audit *AuditService) *InvoiceService {
+	return &InvoiceService{
+		invoiceRepo:   invoiceRepo,
+		cycleRepo:     cycleRepo,
+		usageRepo:     usageRepo,
+		planRepo:      planRepo,
+		creditRepo:    creditRepo,
+		defaultPlanID: defaultPlanID,
+		audit:         audit,
+	}
+}
+
+// Algorithm:
+//  1. Return the existing invoice if the cycle was already invoiced
+//  2. Total the cycle's usage from the raw daily documents
+//  3. Rate it against the plan: base fee, overage blocks, credits, then tax on the net amount
+//  4. Insert the invoice; if a concurrent run won the race, return its invoice instead
+func (s *InvoiceService) GenerateInvoice(ctx context.Context, cycleID string) (*model.Invoice, error) {
+	if err := authorize(ctx, model.PermissionCyclesAdmin, ""); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.invoiceRepo.GetByCycleID(ctx, cycleID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, repository.ErrInvoiceNotFound) {
+		return nil, fmt.Errorf("failed to check for an existing invoice: %w", err)
+	}
+
+	cycle, err := s.cycleRepo.GetByID(ctx, cycleID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(cycle.EndDate) {
+		return nil, ErrCycleNotClosed
+	}
+
+	plan, err := s.planFor(ctx, cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.usageRepo.GetCycleTotals(ctx, cycle.MDN, []*model.Cycle{cycle}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total cycle usage: %w", err)
+	}
+	usageMB := 0.0
+	if len(totals) > 0 {
+		usageMB = totals[0].TotalMB
+	}
+
+	credits, err := s.creditRepo.GetByCycleID(ctx, cycle.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credits: %w", err)
+	}
+
+	invoice, err := rateCycle(cycle, plan, usageMB, credits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.invoiceRepo.Create(ctx, invoice); err != nil {
+		if errors.Is(err, repository.ErrInvoiceAlreadyExists) {
+			return s.invoiceRepo.GetByCycleID(ctx, cycleID)
+		}
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionCycleInvoice, "cycle:"+cycle.ID, nil, invoice); err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// GenerateInvoicesForEndedCycles invoices every cycle that ended in [from, to).
+// Cycles that fail are reported in the returned map and do not stop the run.
+func (s *InvoiceService) GenerateInvoicesForEndedCycles(ctx context.Context, from, to time.Time) (int, map[string]error, error) {
+	cycles, err := s.cycleRepo.GetEndedBetween(ctx, from, to)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get ended cycles: %w", err)
+	}
+
+	invoiced := 0
+	failures := make(map[string]error)
+	for _, cycle := range cycles {
+		if _, err := s.GenerateInvoice(ctx, cycle.ID); err != nil {
+			failures[cycle.ID] = err
+			continue
+		}
+		invoiced++
+	}
+
+	return invoiced, failures, nil
+}
+
+func (s *InvoiceService) GetUserInvoices(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("userId is required")
+	}
+	if err := authorize(ctx, model.PermissionUsageRead, userID); err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.invoiceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+
+	return invoices, nil
+}
+
+func (s *InvoiceService) CreatePlan(ctx context.Context, req dto.CreatePlanRequest) (*model.Plan, error) {
+	if err := authorize(ctx, model.PermissionCyclesAdmin, ""); err != nil {
+		return nil, err
+	}
+
+	plan := &model.Plan{
+		ID:                 req.ID,
+		Name:               req.Name,
+		Currency:           req.Currency,
+		BaseFee:            req.BaseFee,
+		IncludedMB:         req.IncludedMB,
+		OverageUnitMB:      req.OverageUnitMB,
+		OverageRate:        req.OverageRate,
+		TaxRateBasisPoints: req.TaxRateBasisPoints,
+	}
+
+	if err := s.planRepo.Create(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionPlanCreate, "plan:"+plan.ID, nil, plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// CreateCredit records a credit against a cycle. Credits added after the cycle
+// has been invoiced are not reflected on that invoice.
+func (s *InvoiceService) CreateCredit(ctx context.Context, cycleID string, req dto.CreateCreditRequest) (*model.Credit, error) {
+	if err := authorize(ctx, model.PermissionCyclesAdmin, ""); err != nil {
+		return nil, err
+	}
+
+	cycle, err := s.cycleRepo.GetByID(ctx, cycleID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.planFor(ctx, cycle)
+	if err != nil {
+		return nil, err
+	}
+	if req.Currency != plan.Currency {
+		return nil, fmt.Errorf("%w: the plan bills in %s", ErrCurrencyMismatch, plan.Currency)
+	}
+
+	credit := &model.Credit{
+		CycleID: cycle.ID,
+		UserID:  cycle.UserID,
+		Amount:  model.NewMoney(req.Amount, req.Currency),
+		Reason:  req.Reason,
+	}
+	if err := s.creditRepo.Create(ctx, credit); err != nil {
+		return nil, fmt.Errorf("failed to create credit: %w", err)
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionCycleCredit, "cycle:"+cycle.ID, nil, credit); err != nil {
+		return nil, err
+	}
+
+	return credit, nil
+}
+
+func (s *InvoiceService) planFor(ctx context.Context, cycle *model.Cycle) (*model.Plan, error) {
+	planID := cycle.PlanID
+	if planID == "" {
+		planID = s.defaultPlanID
+	}
+	if planID == "" {
+		return nil, ErrNoPlanForCycle
+	}
+
+	return s.planRepo.GetByID(ctx, planID)
+}
+
+func rateCycle(cycle *model.Cycle, plan *model.Plan, usageMB float64, credits []*model.Credit) (*model.Invoice, error) {
+	currency := plan.Currency
+	baseFee := model.NewMoney(plan.BaseFee, currency)
+	items := []model.InvoiceLineItem{{
+		Type:        model.LineItemBaseFee,
+		Description: plan.Name,
+		Quantity:    1,
+		UnitPrice:   baseFee,
+		Amount:      baseFee,
+	}}
+	subtotal := baseFee
+
+	if overMB := usageMB - plan.IncludedMB; overMB > 0 && plan.OverageUnitMB > 0 {
+		units := int64(math.Ceil(overMB / plan.OverageUnitMB))
+		rate := model.NewMoney(plan.OverageRate, currency)
+		amount := model.NewMoney(units*plan.OverageRate, currency)
+		items = append(items, model.InvoiceLineItem{
+			Type:        model.LineItemOverage,
+			Description: fmt.Sprintf("Overage: %.2f MB over %.2f MB included, billed per %.0f MB", overMB, plan.IncludedMB, plan.OverageUnitMB),
+			Quantity:    float64(units),
+			UnitPrice:   rate,
+			Amount:      amount,
+		})
+		var err error
+		if subtotal, err = subtotal.Add(amount); err != nil {
+			return nil, err
+		}
+	}
+
+	totalCredits := model.NewMoney(0, currency)
+	for _, credit := range credits {
+		if credit.Amount.Currency != currency {
+			return nil, fmt.Errorf("%w: credit %s is in %s", ErrCurrencyMismatch, credit.ID, credit.Amount.Currency)
+		}
+		// Credits never push the invoice below zero
+		applied := credit.Amount
+		remaining, err := subtotal.Sub(totalCredits)
+		if err != nil {
+			return nil, err
+		}
+		if applied.Amount > remaining.Amount {
+			applied = remaining
+		}
+		if applied.Amount <= 0 {
+			continue
+		}
+		negated := model.NewMoney(-applied.Amount, currency)
+		items = append(items, model.InvoiceLineItem{
+			Type:        model.LineItemCredit,
+			Description: credit.Reason,
+			Quantity:    1,
+			UnitPrice:   negated,
+			Amount:      negated,
+		})
+		if totalCredits, err = totalCredits.Add(applied); err != nil {
+			return nil, err
+		}
+	}
+
+	taxable, err := subtotal.Sub(totalCredits)
+	if err != nil {
+		return nil, err
+	}
+	tax := taxable.MulBasisPoints(plan.TaxRateBasisPoints)
+	if tax.Amount > 0 {
+		items = append(items, model.InvoiceLineItem{
+			Type:        model.LineItemTax,
+			Description: fmt.Sprintf("Tax at %.2f%%", float64(plan.TaxRateBasisPoints)/100),
+			Quantity:    1,
+			UnitPrice:   tax,
+			Amount:      tax,
+		})
+	}
+
+	total, err := taxable.Add(tax)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Invoice{
+		CycleID:     cycle.ID,
+		UserID:      cycle.UserID,
+		MDN:         cycle.MDN,
+		PlanID:      plan.ID,
+		PeriodStart: cycle.StartDate,
+		PeriodEnd:   cycle.EndDate,
+		UsageMB:     usageMB,
+		Currency:    currency,
+		LineItems:   items,
+		Subtotal:    subtotal,
+		Credits:     totalCredits,
+		Tax:         tax,
+		Total:       total,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+var ErrLoginThrottled = errors.New("too many failed logins, try again later")
+
+// LoginThrottledError is ErrLoginThrottled with the time until the next
+// attempt is accepted.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string { return ErrLoginThrottled.Error() }
+
+func (e *LoginThrottledError) Unwrap() error { return ErrLoginThrottled }
+
+type LockoutOptions struct {
+	// MaxAccountFailures locks an account after this many failed logins
+	MaxAccountFailures int
+	// MaxIPFailures locks out a client address after this many failed logins,
+	// across all the accounts it tried
+	MaxIPFailures int
+	// Window is how long failures are remembered after the last one
+	Window time.Duration
+	// LockDuration is how long a lock lasts unless an admin lifts it
+	LockDuration time.Duration
+	// BaseDelay is the wait after the first failure of an account, doubling
+	// with every further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// LockoutService slows down and then locks out repeated failed logins, per
+// account and per client address. Accounts are keyed by the email presented,
+// so an address without an account is treated exactly like one with.
+type LockoutService struct {
+	attemptRepo repository.LoginAttemptRepository
+	userRepo    repository.UserRepository
+	opts        LockoutOptions
+	audit       *AuditService
+}
+
+func SetupLockoutService(attemptRepo repository.LoginAttemptRepository, userRepo repository.UserRepository, opts LockoutOptions, audit *AuditService) *LockoutService {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &LockoutService{
+		attemptRepo: attemptRepo,
+		userRepo:    userRepo,
+		opts:        opts,
+		audit:       audit,
+	}
+}
+
+// Unlock lifts the lock of a user's account and forgets its failures.
+func (s *LockoutService) Unlock(ctx context.Context, userID string) error {
+	if err := authorize(ctx, model.PermissionUsersWrite, ""); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.attemptRepo.Reset(ctx, accountKey(user.Email)); err != nil {
+		return err
+	}
+
+	return s.audit.Record(ctx, model.AuditActionLoginUnlock, "user:"+user.ID, nil, nil)
+}
+
+// check refuses a login while the account or address is locked, or while
+// the delay after the account's last failure has not passed. A nil service
+// accepts every attempt.
+func (s *LockoutService) check(ctx context.Context, email string) error {
+	if s == nil {
+		return nil
+	}
+	now := s.opts.Clock.Now()
+
+	if ip := sourceIP(ctx); ip != "" {
+		attempts, err := s.attemptRepo.Get(ctx, ipKey(ip))
+		if err != nil {
+			return err
+		}
+		if attempts.Locked(now) {
+			return &LoginThrottledError{RetryAfter: attempts.LockedUntil.Sub(now)}
+		}
+	}
+
+	attempts, err := s.attemptRepo.Get(ctx, accountKey(email))
+	if err != nil {
+		return err
+	}
+	if attempts.Locked(now) {
+		return &LoginThrottledError{RetryAfter: attempts.LockedUntil.Sub(now)}
+	}
+	if attempts.Failures > 0 {
+		if next := attempts.LastFailureAt.Add(s.delay(attempts.Failures)); now.Before(next) {
+			return &LoginThrottledError{RetryAfter: next.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// fail counts a failed login and locks the account or address once it
+// crosses its limit. user is nil when no account has the email.
+func (s *LockoutService) fail(ctx context.Context, email string, user *model.User) error {
+	if s == nil {
+		return nil
+	}
+	now := s.opts.Clock.Now()
+
+	target := "email:" + normalizeEmail(email)
+	if user != nil {
+		target = "user:" + user.ID
+	}
+	if err := s.count(ctx, accountKey(email), target, s.opts.MaxAccountFailures, now); err != nil {
+		return err
+	}
+
+	if ip := sourceIP(ctx); ip != "" {
+		return s.count(ctx, ipKey(ip), "ip:"+ip, s.opts.MaxIPFailures, now)
+	}
+	return nil
+}
+
+// succeed forgets the account's failures. The address keeps its count, so a
+// client trying many accounts is not let off by the one password it has.
+func (s *LockoutService) succeed(ctx context.Context, email string) error {
+	if s == nil {
+		return nil
+	}
+	return s.attemptRepo.Reset(ctx, accountKey(email))
+}
+
+// count records a failure for key and locks it at the limit. Once a lock has
+// expired the next failure locks again, until the failures are forgotten.
+func (s *LockoutService) count(ctx context.Context, key, target string, limit int, now time.Time) error {
+	attempts, err := s.attemptRepo.RecordFailure(ctx, key, now, s.opts.Window)
+	if err != nil {
+		return err
+	}
+	if limit <= 0 || attempts.Failures < limit || attempts.Locked(now) {
+		return nil
+	}
+
+	until := now.Add(s.opts.LockDuration)
+	if err := s.attemptRepo.Lock(ctx, key, until); err != nil {
+		return err
+	}
+
+	return s.audit.Record(ctx, model.AuditActionLoginLockout, target, nil, map[string]any{
+		"failures":    attempts.Failures,
+		"lockedUntil": until,
+	})
+}
+
+// delay is how long to wait after the given number of failures.
+func (s *LockoutService) delay(failures int) time.Duration {
+	delay := s.opts.BaseDelay
+	for i := 1; i < failures && delay < s.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.opts.MaxDelay)
+}
+
+func accountKey(email string) string {
+	return "account:" + normalizeEmail(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
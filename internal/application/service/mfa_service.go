@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+var (
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+	ErrMFANotEnrolled    = errors.New("mfa is not enrolled")
+)
+
+const (
+	mfaChallengeMarker = "pmc_"
+	recoveryCodeCount  = 10
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAOptions struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// RequiredRoles are only granted to sessions that presented a second factor
+	RequiredRoles []string
+	// ChallengeTTL is how long the second login step may take
+	ChallengeTTL time.Duration
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// MFAService manages TOTP second factors: enrollment, the login challenge and
+// the per-role policy that makes them mandatory.
+type MFAService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.UserTokenRepository
+	cipher    repository.SecretCipher
+	opts      MFAOptions
+	audit     *AuditService
+}
+
+func SetupMFAService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, cipher repository.SecretCipher, opts MFAOptions, audit *AuditService) *MFAService {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &MFAService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		cipher:    cipher,
+		opts:      opts,
+		audit:     audit,
+	}
+}
+
+// Enroll starts an enrollment for the calling user with a new secret. It only
+// takes effect once Activate sees a code generated from it, so an abandoned
+// enrollment can simply be started again.
+func (s *MFAService) Enroll(ctx context.Context) (*model.MFAEnrollment, error) {
+	user, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFA != nil && user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	if err := s.userRepo.SetMFA(ctx, user.ID, &model.MFA{Secret: encrypted}); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollment{
+		Secret: totpEncoding.EncodeToString(secret),
+		URI:    totpURI(s.opts.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Activate enables a pending enrollment given a code from the authenticator
+// and returns the recovery codes, which are not shown again. The session it
+// is called from keeps its roles; the next login asks for a code.
+func (s *MFAService) Activate(ctx context.Context, req dto.MFACodeRequest) (*model.RecoveryCodes, error) {
+	user, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFA == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.cipher.Decrypt(user.MFA.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	step, ok := matchTOTP(secret, req.Code, s.opts.Clock.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enabledAt := s.opts.Clock.Now()
+	mfa := &model.MFA{
+		Secret:        user.MFA.Secret,
+		Enabled:       true,
+		EnabledAt:     &enabledAt,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+	}
+	if err := s.userRepo.SetMFA(ctx, user.ID, mfa); err != nil {
+		return nil, err
+	}
+
+	if err := s.audit.Record(ctx, model.AuditActionMFAEnable, "user:"+user.ID, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return &model.RecoveryCodes{Codes: codes}, nil
+}
+
+// Disable removes the calling user's second factor given a current code or
+// a recovery code. Roles that require MFA stop applying to their sessions.
+func (s *MFAService) Disable(ctx context.Context, req dto.MFACodeRequest) error {
+	user, err := s.caller(ctx)
+	if err != nil {
+		return err
+	}
+	if user.MFA == nil || !user.MFA.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	if err := s.check(ctx, user, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetMFA(ctx, user.ID, nil); err != nil {
+		return err
+	}
+
+	return s.audit.Record(ctx, model.AuditActionMFADisable, "user:"+user.ID, nil, nil)
+}
+
+// Enabled reports whether logging in as user takes a second step. A nil
+// service never asks for one.
+func (s *MFAService) Enabled(user *model.User) bool {
+	return s != nil && user.MFA != nil && user.MFA.Enabled
+}
+
+// Restrict returns the user as a session should see them: without the roles
+// that require MFA unless the session presented a second factor. Users
+// holding such a role without having enrolled sign in with the rest, which
+// is enough to enroll.
+func (s *MFAService) Restrict(user *model.User, verified bool) *model.User {
+	if s == nil || verified {
+		return user
+	}
+
+	restricted := *user
+	restricted.Roles = slices.DeleteFunc(slices.Clone(user.Roles), func(role string) bool {
+		return slices.Contains(s.opts.RequiredRoles, role)
+	})
+	return &restricted
+}
+
+// challenge issues the single-use token that stands for a correct password
+// until the second factor is presented.
+func (s *MFAService) challenge(ctx context.Context, user *model.User) (string, time.Time, error) {
+	secret, err := generateToken(mfaChallengeMarker)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	token := &model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.UserTokenMFAChallenge,
+		Email:     user.Email,
+		Hash:      hashToken(secret),
+		ExpiresAt: s.opts.Clock.Now().Add(s.opts.ChallengeTTL),
+	}
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return secret, token.ExpiresAt, nil
+}
+
+// answer consumes the challenge and checks the code, returning the user
+// that passed both steps. A challenge takes a single attempt: after a wrong
+// code the password has to be entered again. The user the challenge was for
+// is returned along with ErrInvalidMFACode, and passed to allow before the
+// code is checked; an error from allow is returned as is.
+func (s *MFAService) answer(ctx context.Context, challenge, code string, allow func(*model.User) error) (*model.User, error) {
+	token, err := s.tokenRepo.Consume(ctx, model.UserTokenMFAChallenge, hashToken(challenge), s.opts.Clock.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if err := allow(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.check(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return user, err
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// check accepts a TOTP code or a recovery code, either of them once.
+func (s *MFAService) check(ctx context.Context, user *model.User, code string) error {
+	if user.MFA == nil || !user.MFA.Enabled {
+		return ErrInvalidMFACode
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		secret, err := s.cipher.Decrypt(user.MFA.Secret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt totp secret: %w", err)
+		}
+		step, ok := matchTOTP(secret, code, s.opts.Clock.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		if err := s.userRepo.UseTOTPStep(ctx, user.ID, step); err != nil {
+			if errors.Is(err, repository.ErrMFAFactorUsed) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := s.userRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, repository.ErrMFAFactorUsed) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+
+	ctx = WithPrincipal(ctx, model.UserPrincipal(user))
+	return s.audit.Record(ctx, model.AuditActionMFARecovery, "user:"+user.ID, nil, nil)
+}
+
+// caller loads the signed-in user; API keys have no second factor.
+func (s *MFAService) caller(ctx context.Context) (*model.User, error) {
+	principal := PrincipalFrom(ctx)
+	if principal == nil || principal.UserID == "" {
+		return nil, ErrPermissionDenied
+	}
+	return s.userRepo.GetByID(ctx, principal.UserID)
+}
+
+// generateRecoveryCodes returns codes like "abcd-efgh-ijkl-mnop" and their
+// hashes. They carry 80 random bits each, so like tokens they are hashed
+// with a plain SHA-256.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 10)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(buf))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and separators, which users retype freely.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
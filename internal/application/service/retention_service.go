@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+type RetentionOptions struct {
+	// Window is how long the personal data of a deleted user is kept before
+	// it is anonymized
+	Window time.Duration
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// RetentionService deletes users in two steps. Deleting an account signs it
+// out and hides it at once, but keeps its personal data, and its email
+// address taken, for the retention window; a scheduled run then anonymizes
+// it. Cycles, usage and invoices are never touched: they stay linked to the
+// user ID, which identifies no one once the user record is anonymized.
+type RetentionService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	opts        RetentionOptions
+	audit       *AuditService
+}
+
+func SetupRetentionService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, opts RetentionOptions, audit *AuditService) *RetentionService {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &RetentionService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		opts:        opts,
+		audit:       audit,
+	}
+}
+
+// DeleteUser soft-deletes a user and signs them out everywhere.
+func (s *RetentionService) DeleteUser(ctx context.Context, userID string) error {
+	if err := authorize(ctx, model.PermissionUsersWrite, userID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SoftDelete(ctx, user.ID, s.opts.Clock.Now()); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to sign out deleted user: %w", err)
+	}
+
+	return s.audit.Record(ctx, model.AuditActionUserDelete, "user:"+user.ID, nil, nil)
+}
+
+// AnonymizeDeletedUsers anonymizes every user deleted longer than the
+// retention window ago. Users that fail are reported in the returned map and
+// picked up again by the next run.
+func (s *RetentionService) AnonymizeDeletedUsers(ctx context.Context) (int, map[string]error, error) {
+	now := s.opts.Clock.Now()
+	users, err := s.userRepo.GetDeletedBefore(ctx, now.Add(-s.opts.Window))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get deleted users: %w", err)
+	}
+
+	anonymized := 0
+	failures := make(map[string]error)
+	for _, user := range users {
+		if err := s.anonymize(ctx, user, now); err != nil {
+			failures[user.ID] = err
+			continue
+		}
+		anonymized++
+	}
+
+	return anonymized, failures, nil
+}
+
+// anonymize records no diff and scrubs the user's earlier audit entries: the
+// audit log must not keep the data it removes.
+func (s *RetentionService) anonymize(ctx context.Context, user *model.User, at time.Time) error {
+	if err := s.userRepo.Anonymize(ctx, user.ID, at); err != nil {
+		return err
+	}
+	target := "user:" + user.ID
+	if err := s.audit.ForgetSubject(ctx, target); err != nil {
+		return err
+	}
+	return s.audit.Record(ctx, model.AuditActionUserAnonymize, target, nil, nil)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+type StatementService struct {
+	userRepo  repository.UserRepository
+	cycleRepo repository.CycleRepository
+	usageRepo repository.DailyUsageRepository
+}
+
+func SetupStatementService(userRepo repository.UserRepository, cycleRepo repository.CycleRepository, usageRepo repository.DailyUsageRepository) *StatementService {
+	return &StatementService{
+		userRepo:  userRepo,
+		cycleRepo: cycleRepo,
+		usageRepo: usageRepo,
+	}
+}
+
+// GetStatement assembles the statement of a cycle. The statement is addressed
+// to the user who owned the line during that cycle, which may not be the
+// current owner if the MDN has since been transferred.
+func (s *StatementService) GetStatement(ctx context.Context, req dto.GetStatementRequest) (*model.Statement, error) {
+	cycle, err := s.cycleRepo.GetByID(ctx, req.CycleID)
+	if err != nil {
+		return nil, err
+	}
+	if cycle.MDN != req.MDN {
+		return nil, ErrCycleNotOnLine
+	}
+	if err := authorize(ctx, model.PermissionUsageRead, cycle.UserID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, cycle.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	usageRecords, err := s.usageRepo.GetByDateRange(ctx, cycle.UserID, cycle.MDN, cycle.StartDate, cycle.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage records: %w", err)
+	}
+
+	statement := &model.Statement{
+		User:        user.ToResponse(),
+		MDN:         cycle.MDN,
+		Cycle:       cycle.ToResponse(),
+		Usage:       make([]*model.DailyUsageResponse, len(usageRecords)),
+		GeneratedAt: time.Now(),
+	}
+	for i, record := range usageRecords {
+		statement.Usage[i] = record.ToResponse()
+		statement.TotalUsage += record.UsedInMB
+		if statement.PeakDate == nil || record.UsedInMB > statement.PeakUsage {
+			peakDate := record.UsageDate
+			statement.PeakDate = &peakDate
+			statement.PeakUsage = record.UsedInMB
+		}
+	}
+
+	return statement, nil
+}
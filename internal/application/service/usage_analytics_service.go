@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+const (
+	defaultTopConsumers     = 10
+	defaultHistogramBuckets = 10
+	maxAnalyticsRange       = 366 * 24 * time.Hour
+)
+
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+type UsageAnalyticsService struct {
+	analyticsRepo repository.UsageAnalyticsRepository
+}
+
+func SetupUsageAnalyticsService(analyticsRepo repository.UsageAnalyticsRepository) *UsageAnalyticsService {
+	return &UsageAnalyticsService{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+func (s *UsageAnalyticsService) GetTopConsumers(ctx context.Context, req dto.GetTopConsumersRequest) ([]*model.LineUsageTotal, error) {
+	if err := authorize(ctx, model.PermissionAnalyticsRead, ""); err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := analyticsRange(req.UsageDateRange)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultTopConsumers
+	}
+
+	totals, err := s.analyticsRepo.GetTopConsumers(ctx, startDate, endDate, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top consumers: %w", err)
+	}
+
+	return totals, nil
+}
+
+func (s *UsageAnalyticsService) GetUsagePercentiles(ctx context.Context, req dto.GetUsagePercentilesRequest) (*model.UsagePercentiles, error) {
+	if err := authorize(ctx, model.PermissionAnalyticsRead, ""); err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := analyticsRange(req.UsageDateRange)
+	if err != nil {
+		return nil, err
+	}
+
+	percentiles, err := s.analyticsRepo.GetUsagePercentiles(ctx, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage percentiles: %w", err)
+	}
+
+	return percentiles, nil
+}
+
+func (s *UsageAnalyticsService) GetUsageHistogram(ctx context.Context, req dto.GetUsageHistogramRequest) ([]*model.UsageHistogramBucket, error) {
+	if err := authorize(ctx, model.PermissionAnalyticsRead, ""); err != nil {
+		return nil, err
+	}
+
+	startDate, endDate, err := analyticsRange(req.UsageDateRange)
+	if err != nil {
+		return nil, err
+	}
+	buckets := req.Buckets
+	if buckets <= 0 {
+		buckets = defaultHistogramBuckets
+	}
+
+	histogram, err := s.analyticsRepo.GetUsageHistogram(ctx, startDate, endDate, buckets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage histogram: %w", err)
+	}
+
+	return histogram, nil
+}
+
+// analyticsRange is dayRange capped at a year to bound scan cost.
+func analyticsRange(dates dto.UsageDateRange) (time.Time, time.Time, error) {
+	startDate, endDate, err := dayRange(dates)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if endDate.Sub(startDate) > maxAnalyticsRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range must not exceed a year", ErrInvalidDateRange)
+	}
+
+	return startDate, endDate, nil
+}
+
+// dayRange turns inclusive calendar days into the [start, end] instants
+// queried on usageDate.
+func dayRange(dates dto.UsageDateRange) (time.Time, time.Time, error) {
+	startDate := dates.From.UTC().Truncate(24 * time.Hour)
+	endDate := dates.To.UTC().Truncate(24 * time.Hour).Add(24*time.Hour - time.Nanosecond)
+
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must not be after to", ErrInvalidDateRange)
+	}
+
+	return startDate, endDate, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+// thresholdIDSeparator joins the ID of a usage event and the number of one
+// of its threshold events. Broker IDs are decimal or base64url and never
+// contain it.
+const thresholdIDSeparator = ':'
+
+type UsageStreamService struct {
+	usageRepo    repository.DailyUsageRepository
+	cycleRepo    repository.CycleRepository
+	broker       repository.UsageEventBroker
+	thresholdsMB []float64
+}
+
+func SetupUsageStreamService(usageRepo repository.DailyUsageRepository, cycleRepo repository.CycleRepository, broker repository.UsageEventBroker, thresholdsMB []float64) *UsageStreamService {
+	thresholds := append([]float64(nil), thresholdsMB...)
+	sort.Float64s(thresholds)
+
+	return &UsageStreamService{
+		usageRepo:    usageRepo,
+		cycleRepo:    cycleRepo,
+		broker:       broker,
+		thresholdsMB: thresholds,
+	}
+}
+
+// cycleUsageState tracks the per-day usage of the line's current cycle so
+// that a daily total can be turned into a cycle total without the previous
+// value of the day, which change streams do not carry.
+type cycleUsageState struct {
+	cycle *model.Cycle
+	days  map[time.Time]float64
+	total float64
+}
+
+// SubscribeUsage streams usage events for the line's current cycle.
+//
+// Algorithm:
+//  1. Subscribe to the broker first, so nothing recorded while the state loads is lost
+//  2. Load the current cycle and its daily usage as the starting state
+//  3. For every event, replace the day's value and add the cycle total
+//  4. Emit a threshold event for every configured threshold the total climbed past
+//
+// Events replayed after a Last-Event-ID resume are already part of the loaded
+// state, so they are delivered again but do not repeat threshold events.
+func (s *UsageStreamService) SubscribeUsage(ctx context.Context, mdn, lastEventID string) (<-chan *model.UsageEvent, error) {
+	if mdn == "" {
+		return nil, fmt.Errorf("mdn is required")
+	}
+	if err := authorizeLine(ctx, s.cycleRepo, model.PermissionUsageRead, mdn); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	raw, err := s.broker.Subscribe(ctx, mdn, brokerEventID(lastEventID))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to subscribe to usage events: %w", err)
+	}
+
+	state, err := s.loadState(ctx, mdn, time.Now().UTC())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan *model.UsageEvent)
+	go func() {
+		defer cancel()
+		defer close(events)
+
+		for event := range raw {
+			// Customers only follow their own usage of the line
+			if authorize(ctx, model.PermissionUsageRead, event.UserID) != nil {
+				continue
+			}
+			if event.UsageDate.After(state.cycle.EndDate) {
+				if next, err := s.loadState(ctx, mdn, event.UsageDate); err == nil {
+					state = next
+				}
+			}
+
+			for _, out := range s.apply(state, event) {
+				select {
+				case events <- out:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func (s *UsageStreamService) loadState(ctx context.Context, mdn string, at time.Time) (*cycleUsageState, error) {
+	cycles, err := lineCycles(ctx, s.cycleRepo, model.PermissionUsageRead, mdn)
+	if err != nil {
+		return nil, err
+	}
+
+	var current *model.Cycle
+	for _, cycle := range cycles {
+		if !at.Before(cycle.StartDate) && !at.After(cycle.EndDate) {
+			current = cycle
+			break
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("no billing cycle covers %s for MDN %s: %w", at.Format(time.DateOnly), mdn, ErrNoCyclesFound)
+	}
+
+	usages, err := s.usageRepo.GetByDateRange(ctx, current.UserID, mdn, current.StartDate, current.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage records: %w", err)
+	}
+
+	state := &cycleUsageState{cycle: current, days: make(map[time.Time]float64, len(usages))}
+	for _, usage := range usages {
+		day := usage.UsageDate.UTC().Truncate(24 * time.Hour)
+		state.days[day] = usage.UsedInMB
+		state.total += usage.UsedInMB
+	}
+
+	return state, nil
+}
+
+// apply folds the event into the state and returns it together with any
+// threshold events it caused. Events outside the tracked cycle, such as late
+// corrections to a closed cycle, are passed through without a cycle total.
+func (s *UsageStreamService) apply(state *cycleUsageState, event *model.UsageEvent) []*model.UsageEvent {
+	cycle := state.cycle
+	if event.UserID != cycle.UserID || event.UsageDate.Before(cycle.StartDate) || event.UsageDate.After(cycle.EndDate) {
+		return []*model.UsageEvent{event}
+	}
+
+	day := event.UsageDate.UTC().Truncate(24 * time.Hour)
+	previousTotal := state.total
+	state.total += event.DailyUsage - state.days[day]
+	state.days[day] = event.DailyUsage
+
+	// Brokers hand the same event to every subscriber of the line, each with
+	// its own cycle state, so it is annotated on a copy
+	annotated := *event
+	annotated.CycleID = cycle.ID
+	annotated.CycleUsage = state.total
+
+	out := []*model.UsageEvent{&annotated}
+	for _, threshold := range s.thresholdsMB {
+		if previousTotal < threshold && state.total >= threshold {
+			crossed := annotated
+			crossed.ID = fmt.Sprintf("%s%c%d", event.ID, thresholdIDSeparator, len(out))
+			crossed.Type = model.UsageEventThreshold
+			crossed.ThresholdMB = threshold
+			out = append(out, &crossed)
+		}
+	}
+
+	return out
+}
+
+// brokerEventID strips the suffix that tells the threshold events of a usage
+// event apart, so clients resuming after one resume after its usage event.
+func brokerEventID(lastEventID string) string {
+	if i := strings.LastIndexByte(lastEventID, thresholdIDSeparator); i >= 0 {
+		return lastEventID[:i]
+	}
+	return lastEventID
+}
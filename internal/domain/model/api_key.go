@@ -0,0 +1,50 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes grant an API key access to groups of routes.
+const (
+	ScopeUsageWrite    = "usage:write"
+	ScopeUsageRead     = "usage:read"
+	ScopeCyclesAdmin   = "cycles:admin"
+	ScopeUsersAdmin    = "users:admin"
+	ScopeKeysAdmin     = "keys:admin"
+	ScopeAuditRead     = "audit:read"
+	ScopeAnalyticsRead = "analytics:read"
+)
+
+// Scopes lists every scope a key can be granted.
+var Scopes = []string{ScopeUsageWrite, ScopeUsageRead, ScopeCyclesAdmin, ScopeUsersAdmin, ScopeKeysAdmin, ScopeAuditRead, ScopeAnalyticsRead}
+
+// APIKey authenticates a machine client such as a mediation or billing
+// system. Only a SHA-256 hash of the key is stored; the key itself is shown
+// once, when it is created or rotated.
+type APIKey struct {
+	ID         string     `bson:"_id,omitempty" json:"id"`
+	Name       string     `bson:"name" json:"name"`
+	Prefix     string     `bson:"prefix" json:"prefix"`
+	Hash       string     `bson:"hash" json:"-"`
+	Scopes     []string   `bson:"scopes" json:"scopes"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	RotatedAt  *time.Time `bson:"rotatedAt,omitempty" json:"rotatedAt,omitempty"`
+	LastUsedAt *time.Time `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IssuedAPIKey is the response to creating or rotating a key, the only time
+// the key is returned.
+type IssuedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
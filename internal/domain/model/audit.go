@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+const (
+	// AuditActionSupportAccess records a request made by a support agent
+	AuditActionSupportAccess = "support.access"
+	// AuditActionAdminRead records a read of an admin endpoint
+	AuditActionAdminRead = "admin.read"
+	// AuditActionLoginLockout records an account or client address locked out
+	// after repeated failed logins, and AuditActionLoginUnlock an admin
+	// lifting such a lock
+	AuditActionLoginLockout = "auth.lockout"
+	AuditActionLoginUnlock  = "auth.unlock"
+	// AuditActionDataExport records a request for a user's personal data and
+	// AuditActionDataExportDownload each download of the archive
+	AuditActionDataExport         = "user.data_export"
+	AuditActionDataExportDownload = "user.data_export_download"
+
+	AuditActionUserUpdate      = "user.update"
+	AuditActionUserRoles       = "user.roles"
+	AuditActionEmailVerified   = "user.email_verified"
+	AuditActionEmailChange     = "user.email_change"
+	AuditActionMFAEnable       = "user.mfa_enable"
+	AuditActionMFADisable      = "user.mfa_disable"
+	AuditActionMFARecovery     = "user.mfa_recovery"
+	AuditActionPasswordReset   = "user.password_reset"
+	AuditActionUserDelete      = "user.delete"
+	AuditActionUserAnonymize   = "user.anonymize"
+	AuditActionUsageCorrection = "usage.correct"
+	AuditActionPlanCreate      = "plan.create"
+	AuditActionCycleCredit     = "cycle.credit"
+	AuditActionCycleInvoice    = "cycle.invoice"
+	AuditActionAPIKeyCreate    = "apikey.create"
+	AuditActionAPIKeyRotate    = "apikey.rotate"
+	AuditActionAPIKeyRevoke    = "apikey.revoke"
+)
+
+// AuditEntry is an append-only record of who did what. Before and After hold
+// only the fields that changed, with secrets and personal data replaced by
+// RedactedValue.
+type AuditEntry struct {
+	ID        string         `bson:"_id,omitempty" json:"id"`
+	At        time.Time      `bson:"at" json:"at"`
+	Actor     string         `bson:"actor" json:"actor"`
+	Action    string         `bson:"action" json:"action"`
+	Target    string         `bson:"target" json:"target"`
+	Before    map[string]any `bson:"before,omitempty" json:"before,omitempty"`
+	After     map[string]any `bson:"after,omitempty" json:"after,omitempty"`
+	RequestID string         `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	SourceIP  string         `bson:"sourceIp,omitempty" json:"sourceIp,omitempty"`
+}
+
+// RedactedValue stands in for secrets and personal data in audit diffs, so
+// an entry shows that a password or an address changed without revealing it.
+const RedactedValue = "[redacted]"
+
+// AuditFilter selects audit entries, newest first. Empty fields match all.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Credit is a one-off amount owed back to a customer on a cycle's invoice.
+type Credit struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	CycleID   string    `bson:"cycleId" json:"cycleId"`
+	UserID    string    `bson:"userId" json:"userId"`
+	Amount    Money     `bson:"amount" json:"amount"`
+	Reason    string    `bson:"reason" json:"reason"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -8,7 +8,11 @@ type Cycle struct {
 	StartDate time.Time `bson:"startDate" json:"startDate"`
 	EndDate   time.Time `bson:"endDate" json:"endDate"`
 	UserID    string    `bson:"userId" json:"userId"`
+	PlanID    string    `bson:"planId,omitempty" json:"planId,omitempty"`
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	// Version counts the writes to the cycle. Cycles are not changed once
+	// created, but any update must only apply to the version it was based on
+	Version int64 `bson:"version" json:"version"`
 }
 
 type CycleResponse struct {
@@ -23,4 +27,23 @@ func (c *Cycle) ToResponse() *CycleResponse {
 		StartDate: c.StartDate,
 		EndDate:   c.EndDate,
 	}
-}
\ No newline at end of file
+}
+
+// DaysInCycle returns the number of calendar days covered by the cycle,
+// counting both the start and the end day.
+func (c *Cycle) DaysInCycle() int {
+	return int(c.EndDate.Sub(c.StartDate).Hours()/24) + 1
+}
+
+// DaysElapsed returns how many days of the cycle have started as of now,
+// capped at the length of the cycle.
+func (c *Cycle) DaysElapsed(now time.Time) int {
+	if now.Before(c.StartDate) {
+		return 0
+	}
+	elapsed := int(now.Sub(c.StartDate).Hours()/24) + 1
+	if total := c.DaysInCycle(); elapsed > total {
+		return total
+	}
+	return elapsed
+}
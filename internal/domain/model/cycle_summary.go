@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// CycleSummary is the materialized rollup of a cycle's daily usage. It shares
+// its ID with the cycle it summarizes.
+type CycleSummary struct {
+	CycleID     string    `bson:"_id" json:"cycleId"`
+	MDN         string    `bson:"mdn" json:"mdn"`
+	UserID      string    `bson:"userId" json:"userId"`
+	StartDate   time.Time `bson:"startDate" json:"startDate"`
+	EndDate     time.Time `bson:"endDate" json:"endDate"`
+	TotalMB     float64   `bson:"totalMb" json:"totalMb"`
+	PeakDate    time.Time `bson:"peakDate,omitempty" json:"peakDate,omitempty"`
+	PeakMB      float64   `bson:"peakMb" json:"peakMb"`
+	DayCount    int       `bson:"dayCount" json:"dayCount"`
+	LastUpdated time.Time `bson:"lastUpdated" json:"lastUpdated"`
+}
+
+type CycleSummaryResponse struct {
+	CycleID     string     `json:"cycleId"`
+	StartDate   time.Time  `json:"startDate"`
+	EndDate     time.Time  `json:"endDate"`
+	TotalUsage  float64    `json:"totalUsage"`
+	PeakDate    *time.Time `json:"peakDate,omitempty"`
+	PeakUsage   float64    `json:"peakUsage"`
+	DayCount    int        `json:"dayCount"`
+	LastUpdated time.Time  `json:"lastUpdated"`
+}
+
+// EmptyCycleSummary is the summary of a cycle that has no usage recorded yet.
+func EmptyCycleSummary(cycle *Cycle) *CycleSummary {
+	return &CycleSummary{
+		CycleID:   cycle.ID,
+		MDN:       cycle.MDN,
+		UserID:    cycle.UserID,
+		StartDate: cycle.StartDate,
+		EndDate:   cycle.EndDate,
+	}
+}
+
+func (s *CycleSummary) ToResponse() *CycleSummaryResponse {
+	response := &CycleSummaryResponse{
+		CycleID:     s.CycleID,
+		StartDate:   s.StartDate,
+		EndDate:     s.EndDate,
+		TotalUsage:  s.TotalMB,
+		PeakUsage:   s.PeakMB,
+		DayCount:    s.DayCount,
+		LastUpdated: s.LastUpdated,
+	}
+	if !s.PeakDate.IsZero() {
+		peakDate := s.PeakDate
+		response.PeakDate = &peakDate
+	}
+	return response
+}
@@ -10,6 +10,9 @@ type DailyUsage struct {
 	UsedInMB  float64   `bson:"usedInMb" json:"usedInMb"`
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	// Version counts the writes to the record; updates only apply to the
+	// version they were based on
+	Version int64 `bson:"version" json:"version"`
 }
 
 type DailyUsageResponse struct {
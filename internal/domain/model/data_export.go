@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+const (
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a request for everything stored about a user. The archive is
+// assembled in the background and can be downloaded until ExpiresAt, after
+// which the export is removed.
+type DataExport struct {
+	ID          string     `bson:"_id,omitempty" json:"id"`
+	UserID      string     `bson:"userId" json:"userId"`
+	Status      string     `bson:"status" json:"status"`
+	Error       string     `bson:"error,omitempty" json:"error,omitempty"`
+	Size        int        `bson:"size,omitempty" json:"size,omitempty"`
+	CreatedAt   time.Time  `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `bson:"expiresAt" json:"expiresAt"`
+}
+
+// ExportTable is one kind of record in a data export, with a row of cells
+// per record. Cells hold strings, numbers, bools or time.Time.
+type ExportTable struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
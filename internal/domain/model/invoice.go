@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+const (
+	LineItemBaseFee = "base_fee"
+	LineItemOverage = "overage"
+	LineItemCredit  = "credit"
+	LineItemTax     = "tax"
+)
+
+type InvoiceLineItem struct {
+	Type        string  `bson:"type" json:"type"`
+	Description string  `bson:"description" json:"description"`
+	Quantity    float64 `bson:"quantity" json:"quantity"`
+	UnitPrice   Money   `bson:"unitPrice" json:"unitPrice"`
+	Amount      Money   `bson:"amount" json:"amount"`
+}
+
+// Invoice is written once per closed cycle and never updated.
+type Invoice struct {
+	ID          string            `bson:"_id,omitempty" json:"id"`
+	CycleID     string            `bson:"cycleId" json:"cycleId"`
+	UserID      string            `bson:"userId" json:"userId"`
+	MDN         string            `bson:"mdn" json:"mdn"`
+	PlanID      string            `bson:"planId" json:"planId"`
+	PeriodStart time.Time         `bson:"periodStart" json:"periodStart"`
+	PeriodEnd   time.Time         `bson:"periodEnd" json:"periodEnd"`
+	UsageMB     float64           `bson:"usageMb" json:"usageMb"`
+	Currency    string            `bson:"currency" json:"currency"`
+	LineItems   []InvoiceLineItem `bson:"lineItems" json:"lineItems"`
+	Subtotal    Money             `bson:"subtotal" json:"subtotal"`
+	Credits     Money             `bson:"credits" json:"credits"`
+	Tax         Money             `bson:"tax" json:"tax"`
+	Total       Money             `bson:"total" json:"total"`
+	IssuedAt    time.Time         `bson:"issuedAt" json:"issuedAt"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// LoginAttempts counts the recent failed logins for an account or a client
+// address. Keys are "account:<email>" or "ip:<address>"; the email is the one
+// presented, whether or not an account has it.
+type LoginAttempts struct {
+	Key           string     `bson:"_id" json:"-"`
+	Failures      int        `bson:"failures" json:"-"`
+	LastFailureAt time.Time  `bson:"lastFailureAt" json:"-"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty" json:"-"`
+	// ExpiresAt forgets the record once there have been no failures for a while
+	ExpiresAt time.Time `bson:"expiresAt" json:"-"`
+}
+
+// Locked reports whether logins for the key are refused at the given time.
+func (a *LoginAttempts) Locked(at time.Time) bool {
+	return a.LockedUntil != nil && at.Before(*a.LockedUntil)
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// MFA is a user's TOTP second factor. The secret is stored encrypted and the
+// recovery codes only as hashes; neither is ever returned after enrollment.
+type MFA struct {
+	// Secret is the encrypted TOTP key
+	Secret    string     `bson:"secret"`
+	Enabled   bool       `bson:"enabled"`
+	EnabledAt *time.Time `bson:"enabledAt,omitempty"`
+	// RecoveryCodes hold the hashes of the unused recovery codes
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+	// LastStep is the last TOTP time step accepted, so a code works once
+	LastStep int64 `bson:"lastStep"`
+}
+
+// MFAEnrollment is the response to starting an enrollment, the only time the
+// secret is returned. URI is the otpauth:// provisioning URI to show as a QR
+// code.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are returned once, when MFA is enabled. Each works once in
+// place of a TOTP code.
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
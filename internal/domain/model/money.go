@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCurrencyMismatch = errors.New("currencies do not match")
+
+// Money is an amount in the currency's minor units (cents for USD) so that
+// billing arithmetic never goes through floating point.
+type Money struct {
+	Amount   int64  `bson:"amount" json:"amount"`
+	Currency string `bson:"currency" json:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add fails with ErrCurrencyMismatch unless both amounts are in the same
+// currency, as does Sub.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.match(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.match(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// MulBasisPoints multiplies by bp/10000, rounding half away from zero.
+func (m Money) MulBasisPoints(bp int64) Money {
+	product := m.Amount * bp
+	rounded := product / 10000
+	if remainder := product % 10000; remainder >= 5000 {
+		rounded++
+	} else if remainder <= -5000 {
+		rounded--
+	}
+	return Money{Amount: rounded, Currency: m.Currency}
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) match(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
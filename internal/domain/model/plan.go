@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Plan is the price list a cycle is rated against. Overage is billed in whole
+// blocks of OverageUnitMB, any started block counting as a full one.
+type Plan struct {
+	ID                 string    `bson:"_id" json:"id"`
+	Name               string    `bson:"name" json:"name"`
+	Currency           string    `bson:"currency" json:"currency"`
+	BaseFee            int64     `bson:"baseFee" json:"baseFee"`
+	IncludedMB         float64   `bson:"includedMb" json:"includedMb"`
+	OverageUnitMB      float64   `bson:"overageUnitMb" json:"overageUnitMb"`
+	OverageRate        int64     `bson:"overageRate" json:"overageRate"`
+	TaxRateBasisPoints int64     `bson:"taxRateBasisPoints" json:"taxRateBasisPoints"`
+	CreatedAt          time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import "slices"
+
+// Principal is the caller of an operation: a signed-in user or an API key.
+type Principal struct {
+	UserID   string
+	APIKeyID string
+	Roles    []string
+	// Scopes of the API key
+	Scopes []string
+	// Permissions hold across all accounts
+	Permissions []string
+}
+
+func UserPrincipal(user *User) *Principal {
+	return &Principal{
+		UserID:      user.ID,
+		Roles:       user.Roles,
+		Permissions: expand(user.Roles, rolePermissions),
+	}
+}
+
+func APIKeyPrincipal(key *APIKey) *Principal {
+	return &Principal{
+		APIKeyID:    key.ID,
+		Scopes:      key.Scopes,
+		Permissions: expand(key.Scopes, scopePermissions),
+	}
+}
+
+// Can reports whether the principal holds the permission over data owned by
+// ownerID. Users hold a few permissions over their own data regardless of
+// role; ownerID is empty for data that belongs to no user.
+func (p *Principal) Can(permission, ownerID string) bool {
+	if slices.Contains(p.Permissions, permission) {
+		return true
+	}
+	return p.UserID != "" && ownerID == p.UserID && slices.Contains(selfPermissions, permission)
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// Actor identifies the principal in audit entries.
+func (p *Principal) Actor() string {
+	if p.APIKeyID != "" {
+		return "apikey:" + p.APIKeyID
+	}
+	return "user:" + p.UserID
+}
+
+func expand(grants []string, permissions map[string][]string) []string {
+	var expanded []string
+	for _, grant := range grants {
+		for _, permission := range permissions[grant] {
+			if !slices.Contains(expanded, permission) {
+				expanded = append(expanded, permission)
+			}
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimit is a token bucket: it holds up to Burst requests and refills at
+// Rate requests per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Window is how long an empty bucket takes to refill completely.
+func (l RateLimit) Window() time.Duration {
+	if l.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+// Result describes a bucket left holding tokens after a request was allowed
+// or denied.
+func (l RateLimit) Result(tokens float64, allowed bool) *RateLimitResult {
+	result := &RateLimitResult{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+	}
+	if l.Rate <= 0 {
+		return result
+	}
+	result.Reset = time.Duration((float64(l.Burst) - tokens) / l.Rate * float64(time.Second))
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / l.Rate * float64(time.Second))
+	}
+	return result
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed; it is
+	// only set when the request was denied
+	RetryAfter time.Duration
+}
@@ -0,0 +1,57 @@
+package model
+
+import "slices"
+
+// Roles are assigned to users; they grant permissions across all accounts.
+const (
+	RoleCustomer = "customer"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
+
+var Roles = []string{RoleCustomer, RoleSupport, RoleAdmin}
+
+// Permissions are checked by the application services. They share their
+// names with the API key scopes that grant them where the two overlap.
+const (
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionUsageRead     = "usage:read"
+	PermissionUsageWrite    = "usage:write"
+	PermissionCyclesAdmin   = "cycles:admin"
+	PermissionRolesAdmin    = "roles:admin"
+	PermissionKeysAdmin     = "keys:admin"
+	PermissionAuditRead     = "audit:read"
+	PermissionAnalyticsRead = "analytics:read"
+)
+
+var rolePermissions = map[string][]string{
+	// Customers only act on their own data, through selfPermissions
+	RoleCustomer: nil,
+	// Support agents can look at any account but change none
+	RoleSupport: {PermissionUsersRead, PermissionUsageRead},
+	RoleAdmin: {
+		PermissionUsersRead, PermissionUsersWrite, PermissionUsageRead, PermissionUsageWrite,
+		PermissionCyclesAdmin, PermissionRolesAdmin, PermissionKeysAdmin, PermissionAuditRead,
+		PermissionAnalyticsRead,
+	},
+}
+
+// No scope grants PermissionRolesAdmin: a key that could assign roles could
+// make any user an admin, so only admins assign them.
+var scopePermissions = map[string][]string{
+	ScopeUsageRead:     {PermissionUsageRead},
+	ScopeUsageWrite:    {PermissionUsageWrite},
+	ScopeCyclesAdmin:   {PermissionCyclesAdmin},
+	ScopeUsersAdmin:    {PermissionUsersRead, PermissionUsersWrite},
+	ScopeKeysAdmin:     {PermissionKeysAdmin},
+	ScopeAuditRead:     {PermissionAuditRead},
+	ScopeAnalyticsRead: {PermissionAnalyticsRead},
+}
+
+// selfPermissions are held by every signed-in user over their own account.
+var selfPermissions = []string{PermissionUsersRead, PermissionUsersWrite, PermissionUsageRead}
+
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Session is a signed-in user. Like API keys, only a hash of the session
+// token is stored.
+type Session struct {
+	ID     string `bson:"_id,omitempty" json:"-"`
+	UserID string `bson:"userId" json:"-"`
+	Hash   string `bson:"hash" json:"-"`
+	// MFA is set when the user presented a second factor at login
+	MFA       bool      `bson:"mfa" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"-"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"-"`
+}
+
+// IssuedSession is the response to a successful login, the only time the
+// token is returned. Users with MFA get a challenge instead, which is
+// exchanged for the session together with a code; ExpiresAt is then when the
+// challenge expires.
+type IssuedSession struct {
+	Token       string        `json:"token,omitempty"`
+	MFARequired bool          `json:"mfaRequired,omitempty"`
+	Challenge   string        `json:"challenge,omitempty"`
+	ExpiresAt   time.Time     `json:"expiresAt"`
+	User        *UserResponse `json:"user,omitempty"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Statement is everything needed to render a customer-facing usage statement
+// for one cycle of a line.
+type Statement struct {
+	User        *UserResponse
+	MDN         string
+	Cycle       *CycleResponse
+	Usage       []*DailyUsageResponse
+	TotalUsage  float64
+	PeakUsage   float64
+	PeakDate    *time.Time
+	GeneratedAt time.Time
+}
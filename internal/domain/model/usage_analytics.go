@@ -0,0 +1,25 @@
+package model
+
+type LineUsageTotal struct {
+	MDN           string  `bson:"_id" json:"mdn"`
+	TotalMB       float64 `bson:"totalMb" json:"totalUsage"`
+	DaysWithUsage int     `bson:"days" json:"daysWithUsage"`
+}
+
+// UsagePercentiles and UsageHistogramBucket describe the usage totals of the
+// cycles that ended in a period.
+type UsagePercentiles struct {
+	CycleCount int     `bson:"cycleCount" json:"cycleCount"`
+	P50        float64 `bson:"p50" json:"p50"`
+	P90        float64 `bson:"p90" json:"p90"`
+	P99        float64 `bson:"p99" json:"p99"`
+	Min        float64 `bson:"min" json:"min"`
+	Max        float64 `bson:"max" json:"max"`
+	Mean       float64 `bson:"mean" json:"mean"`
+}
+
+type UsageHistogramBucket struct {
+	MinMB      float64 `json:"minUsage"`
+	MaxMB      float64 `json:"maxUsage"`
+	CycleCount int     `json:"cycleCount"`
+}
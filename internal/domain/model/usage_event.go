@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+const (
+	UsageEventDailyUsage = "usage"
+	UsageEventThreshold  = "threshold"
+)
+
+// UsageEvent is pushed to live subscribers of a line. Brokers deliver raw
+// daily usage events; the cycle total and threshold crossings are filled in
+// by the stream service.
+type UsageEvent struct {
+	ID          string    `json:"-"`
+	Type        string    `json:"type"`
+	MDN         string    `json:"mdn"`
+	UserID      string    `json:"userId"`
+	UsageDate   time.Time `json:"date"`
+	DailyUsage  float64   `json:"dailyUsage"`
+	CycleID     string    `json:"cycleId,omitempty"`
+	CycleUsage  float64   `json:"cycleUsage,omitempty"`
+	ThresholdMB float64   `json:"thresholdMb,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// CycleUsageTotal is the aggregated usage of a single cycle as computed by the
+// daily usage repository. AlignedMB only counts the first N days of the cycle,
+// where N is the alignment window requested by the caller.
+type CycleUsageTotal struct {
+	CycleID       string  `bson:"_id"`
+	TotalMB       float64 `bson:"totalMb"`
+	DaysWithUsage int     `bson:"days"`
+	AlignedMB     float64 `bson:"alignedMb"`
+}
+
+type CycleTrend struct {
+	CycleID           string    `json:"cycleId"`
+	StartDate         time.Time `json:"startDate"`
+	EndDate           time.Time `json:"endDate"`
+	Partial           bool      `json:"partial"`
+	DaysElapsed       int       `json:"daysElapsed"`
+	TotalUsage        float64   `json:"totalUsage"`
+	AlignedUsage      *float64  `json:"alignedUsage,omitempty"`
+	AverageDailyUsage float64   `json:"averageDailyUsage"`
+	Delta             *float64  `json:"delta,omitempty"`
+	PercentChange     *float64  `json:"percentChange,omitempty"`
+}
+
+type UsageTrendResponse struct {
+	MDN         string        `json:"mdn"`
+	AlignedDays int           `json:"alignedDays,omitempty"`
+	Cycles      []*CycleTrend `json:"cycles"`
+}
@@ -3,31 +3,80 @@ package model
 import "time"
 
 type User struct {
-	ID        string    `bson:"_id,omitempty" json:"id"`
-	FirstName string    `bson:"firstName" json:"firstName"`
-	LastName  string    `bson:"lastName" json:"lastName"`
-	Email     string    `bson:"email" json:"email"`
-	Password  string    `bson:"password" json:"-"`
-	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	ID            string    `bson:"_id,omitempty" json:"id"`
+	FirstName     string    `bson:"firstName" json:"firstName"`
+	LastName      string    `bson:"lastName" json:"lastName"`
+	Email         string    `bson:"email" json:"email"`
+	EmailVerified bool      `bson:"emailVerified" json:"emailVerified"`
+	PendingEmail  string    `bson:"pendingEmail,omitempty" json:"pendingEmail,omitempty"`
+	Password      string    `bson:"password" json:"-"`
+	Roles         []string  `bson:"roles,omitempty" json:"roles"`
+	MFA           *MFA      `bson:"mfa,omitempty" json:"-"`
+	CreatedAt     time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time `bson:"updatedAt" json:"updatedAt"`
+	// Version counts the writes to the user; updates only apply to the
+	// version they were based on
+	Version int64 `bson:"version" json:"version"`
+	// DeletedAt is set when the account is deleted. Its personal data is kept
+	// for the retention window and then anonymized, at AnonymizedAt
+	DeletedAt    *time.Time `bson:"deletedAt,omitempty" json:"-"`
+	AnonymizedAt *time.Time `bson:"anonymizedAt,omitempty" json:"-"`
 }
 
 type UserResponse struct {
-	ID        string    `json:"id"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID            string    `json:"id"`
+	FirstName     string    `json:"firstName"`
+	LastName      string    `json:"lastName"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	PendingEmail  string    `json:"pendingEmail,omitempty"`
+	Roles         []string  `json:"roles"`
+	MFAEnabled    bool      `json:"mfaEnabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	Version       int64     `json:"version"`
 }
 
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
-		ID:        u.ID,
-		FirstName: u.FirstName,
-		LastName:  u.LastName,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:            u.ID,
+		FirstName:     u.FirstName,
+		LastName:      u.LastName,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		PendingEmail:  u.PendingEmail,
+		Roles:         u.Roles,
+		MFAEnabled:    u.MFA != nil && u.MFA.Enabled,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		Version:       u.Version,
 	}
-}
\ No newline at end of file
+}
+
+// Sort orders of user searches. Names and emails are stored encrypted, so
+// users can only be ordered by their timestamps.
+const (
+	UserSortCreatedAt = "createdAt"
+	UserSortUpdatedAt = "updatedAt"
+)
+
+// UserFilter selects users for admin tooling. Empty fields match all.
+type UserFilter struct {
+	// Name and Email match case-insensitive prefixes of either name or the
+	// email; prefixes shorter than three characters match no one
+	Name        string
+	Email       string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Sort        string
+	Descending  bool
+	// After is the cursor of the previous page
+	After string
+	Limit int
+}
+
+type UserPage struct {
+	Users []*UserResponse `json:"users"`
+	// NextCursor fetches the next page; it is empty on the last one
+	NextCursor string `json:"nextCursor,omitempty"`
+}
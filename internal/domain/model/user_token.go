@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Purposes of the single-use tokens mailed to users.
+const (
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenResetPassword = "reset_password"
+	UserTokenChangeEmail   = "change_email"
+	UserTokenMFAChallenge  = "mfa_challenge"
+)
+
+// UserToken proves control of a user's email address for one purpose. Only a
+// hash of the token is stored, and it can be used once before it expires.
+type UserToken struct {
+	ID        string     `bson:"_id,omitempty"`
+	UserID    string     `bson:"userId"`
+	Purpose   string     `bson:"purpose"`
+	Email     string     `bson:"email"`
+	Hash      string     `bson:"hash"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	UsedAt    *time.Time `bson:"usedAt,omitempty"`
+}
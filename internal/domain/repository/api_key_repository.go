@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	GetByID(ctx context.Context, id string) (*model.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	List(ctx context.Context) ([]*model.APIKey, error)
+	// Rotate replaces the hash and prefix of a key that has not been revoked
+	Rotate(ctx context.Context, id, prefix, hash string, at time.Time) (*model.APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) (*model.APIKey, error)
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
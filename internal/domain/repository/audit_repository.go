@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// AuditRepository is append-only: entries are never deleted, and the only
+// update is RedactFields, which erases a subject's personal data.
+type AuditRepository interface {
+	Append(ctx context.Context, entry *model.AuditEntry) error
+	Find(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error)
+	// RedactFields replaces the before and after values of fields in every
+	// entry about target with model.RedactedValue.
+	RedactFields(ctx context.Context, target string, fields []string) error
+}
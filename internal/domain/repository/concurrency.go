@@ -0,0 +1,9 @@
+package repository
+
+import "errors"
+
+// ErrConcurrentModification is returned by the Update methods for a write
+// based on a version of a document that has been changed since. Documents
+// carry a Version that every write increments; those stored before
+// versioning have none, which counts as version 0.
+var ErrConcurrentModification = errors.New("modified concurrently; read it again and retry")
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+type CreditRepository interface {
+	Create(ctx context.Context, credit *model.Credit) error
+	GetByCycleID(ctx context.Context, cycleID string) ([]*model.Credit, error)
+}
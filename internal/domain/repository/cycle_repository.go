@@ -11,6 +11,12 @@ type CycleRepository interface {
 	Create(ctx context.Context, cycle *model.Cycle) error
 	GetByID(ctx context.Context, id string) (*model.Cycle, error)
 	GetByMDN(ctx context.Context, mdn string) ([]*model.Cycle, error)
+	// StreamByMDN calls fn for each cycle of the MDN, newest first, without loading them all into memory
+	StreamByMDN(ctx context.Context, mdn string, fn func(*model.Cycle) error) error
 	GetByUserID(ctx context.Context, userID string) ([]*model.Cycle, error)
+	// GetByMDNs and GetByUserIDs are the batched forms of GetByMDN and GetByUserID, newest first
+	GetByMDNs(ctx context.Context, mdns []string) ([]*model.Cycle, error)
+	GetByUserIDs(ctx context.Context, userIDs []string) ([]*model.Cycle, error)
 	GetCurrentCycle(ctx context.Context, userID, mdn string, currentDate time.Time) (*model.Cycle, error)
+	GetEndedBetween(ctx context.Context, from, to time.Time) ([]*model.Cycle, error)
 }
\ No newline at end of file
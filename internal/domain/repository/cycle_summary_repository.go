@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// ErrCycleSummaryNotFound is returned when no usage has been folded into a cycle yet.
+var ErrCycleSummaryNotFound = errors.New("cycle summary not found")
+
+type CycleSummaryRepository interface {
+	GetByCycleID(ctx context.Context, cycleID string) (*model.CycleSummary, error)
+	// ApplyUsage folds a single day's change into the cycle summary, creating it if needed.
+	// previousMB is the day's value before the change and isNewDay reports whether the day had no usage yet.
+	ApplyUsage(ctx context.Context, cycle *model.Cycle, usageDate time.Time, usedMB, previousMB float64, isNewDay bool) (*model.CycleSummary, error)
+	// Rebuild recomputes a cycle summary from the raw daily usage documents.
+	Rebuild(ctx context.Context, cycleID string) error
+	RebuildByMDN(ctx context.Context, mdn string) error
+	RebuildAll(ctx context.Context) error
+}
@@ -8,7 +8,23 @@ import (
 )
 
 type DailyUsageRepository interface {
+	// Create returns ErrConcurrentModification if the owner's line already
+	// has a record for the day
 	Create(ctx context.Context, usage *model.DailyUsage) error
 	GetByDateRange(ctx context.Context, userId, mdn string, startDate, endDate time.Time) ([]*model.DailyUsage, error)
+	// StreamByDateRange is GetByDateRange for exports: records are decoded and handed to fn one at a time
+	StreamByDateRange(ctx context.Context, userID, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error
+	// StreamByMDN streams the usage of an MDN across all of its owners
+	StreamByMDN(ctx context.Context, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error
+	// GetByCycles loads the usage of several cycles, each for its own owner and date range, in one query
+	GetByCycles(ctx context.Context, cycles []*model.Cycle) ([]*model.DailyUsage, error)
+	// Update stores the usage if it is still at usage.Version and returns
+	// ErrConcurrentModification otherwise
 	Update(ctx context.Context, usage *model.DailyUsage) error
+	// GetCycleTotals sums usage for each of the given cycles in a single query.
+	// When alignDays is positive, AlignedMB only counts the first alignDays days of each cycle.
+	GetCycleTotals(ctx context.Context, mdn string, cycles []*model.Cycle, alignDays int) ([]*model.CycleUsageTotal, error)
+	// RemoveDuplicates keeps only the last written record of each owner's
+	// line and day, and returns the MDNs it removed records of
+	RemoveDuplicates(ctx context.Context) ([]string, error)
 }
\ No newline at end of file
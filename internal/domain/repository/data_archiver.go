@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"io"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// DataArchiver packs the tables of a data export into a single archive.
+type DataArchiver interface {
+	Write(w io.Writer, tables []*model.ExportTable) error
+	// ContentType is the media type of the archives it writes
+	ContentType() string
+}
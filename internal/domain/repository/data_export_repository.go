@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var ErrDataExportNotFound = errors.New("data export not found")
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *model.DataExport) error
+	// GetByID returns an export without its archive, unless it has expired
+	GetByID(ctx context.Context, id string) (*model.DataExport, error)
+	// Complete stores the archive of a running export and marks it ready
+	Complete(ctx context.Context, id string, archive []byte, at, expiresAt time.Time) error
+	// Fail marks a running export failed with the reason
+	Fail(ctx context.Context, id, reason string, at time.Time) error
+	// GetArchive returns the archive of a ready export
+	GetArchive(ctx context.Context, id string) ([]byte, error)
+}
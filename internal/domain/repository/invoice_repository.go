@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var (
+	ErrInvoiceNotFound      = errors.New("invoice not found")
+	ErrInvoiceAlreadyExists = errors.New("cycle has already been invoiced")
+)
+
+// InvoiceRepository is append-only: invoices are never updated once issued.
+type InvoiceRepository interface {
+	Create(ctx context.Context, invoice *model.Invoice) error
+	GetByID(ctx context.Context, id string) (*model.Invoice, error)
+	GetByCycleID(ctx context.Context, cycleID string) (*model.Invoice, error)
+	GetByUserID(ctx context.Context, userID string) ([]*model.Invoice, error)
+}
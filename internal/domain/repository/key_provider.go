@@ -0,0 +1,18 @@
+package repository
+
+import "errors"
+
+var ErrEncryptionKeyNotFound = errors.New("encryption key not found")
+
+// KeyProvider holds the 32-byte keys that encrypt personal data at rest: the
+// key-encryption keys, by ID, that wrap the data key stored with each
+// document, the key of the blind indexes that encrypted emails are looked up
+// by, and the key of the prefix indexes that users are searched by. Rotating means making a new key current while the older ones
+// stay available until no document refers to them.
+type KeyProvider interface {
+	// CurrentKeyID names the key that new data keys are wrapped with
+	CurrentKeyID() string
+	Key(id string) ([]byte, error)
+	IndexKey() []byte
+	SearchKey() []byte
+}
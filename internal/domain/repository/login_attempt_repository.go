@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+type LoginAttemptRepository interface {
+	// Get returns the attempts recorded for key; unknown keys have none
+	Get(ctx context.Context, key string) (*model.LoginAttempts, error)
+	// RecordFailure counts a failure at the given time and returns the updated
+	// record, which is forgotten once retention passes without another failure
+	RecordFailure(ctx context.Context, key string, at time.Time, retention time.Duration) (*model.LoginAttempts, error)
+	// Lock refuses logins for key until the given time
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets the failures and any lock of key
+	Reset(ctx context.Context, key string) error
+}
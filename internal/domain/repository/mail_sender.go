@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// MailSender delivers mail to users, through SMTP or, in development, the log.
+type MailSender interface {
+	Send(ctx context.Context, mail *model.Mail) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var (
+	ErrPlanNotFound      = errors.New("plan not found")
+	ErrPlanAlreadyExists = errors.New("plan already exists")
+)
+
+type PlanRepository interface {
+	Create(ctx context.Context, plan *model.Plan) error
+	GetByID(ctx context.Context, id string) (*model.Plan, error)
+}
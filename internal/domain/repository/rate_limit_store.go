@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// RateLimitStore keeps token buckets by key.
+type RateLimitStore interface {
+	// Take refills the bucket for key and removes one token from it if it has
+	// one. Buckets that do not exist yet start full.
+	Take(ctx context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error)
+}
@@ -0,0 +1,8 @@
+package repository
+
+// SecretCipher encrypts secrets the service has to read back, such as TOTP
+// keys, before they are stored.
+type SecretCipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	GetByHash(ctx context.Context, hash string) (*model.Session, error)
+	Delete(ctx context.Context, id string) error
+	// DeleteByUserID signs the user out everywhere
+	DeleteByUserID(ctx context.Context, userID string) error
+}
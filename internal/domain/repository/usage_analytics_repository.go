@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// UsageAnalyticsRepository answers fleet-wide questions over all lines.
+// GetTopConsumers totals usage per MDN within [startDate, endDate]; the
+// percentiles and histogram are of the totals of the cycles that ended within
+// it, each over the cycle's own days.
+type UsageAnalyticsRepository interface {
+	GetTopConsumers(ctx context.Context, startDate, endDate time.Time, limit int) ([]*model.LineUsageTotal, error)
+	GetUsagePercentiles(ctx context.Context, startDate, endDate time.Time) (*model.UsagePercentiles, error)
+	GetUsageHistogram(ctx context.Context, startDate, endDate time.Time, buckets int) ([]*model.UsageHistogramBucket, error)
+}
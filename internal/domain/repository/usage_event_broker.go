@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+// UsageEventBroker fans out daily usage changes to live subscribers of a line.
+type UsageEventBroker interface {
+	// Publish announces a usage change made by this process. Brokers that
+	// observe the database directly may ignore it.
+	Publish(ctx context.Context, event *model.UsageEvent) error
+	// Subscribe delivers events for the MDN until ctx is done, then closes the
+	// channel. A non-empty lastEventID replays the events that followed it when
+	// the broker still has them. The channel is also closed if the subscriber
+	// falls too far behind; it is expected to reconnect with its last event ID.
+	Subscribe(ctx context.Context, mdn, lastEventID string) (<-chan *model.UsageEvent, error)
+}
@@ -2,15 +2,52 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
 )
 
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrMFAFactorUsed is returned for a TOTP step or recovery code that was
+	// already accepted, or never existed
+	ErrMFAFactorUsed = errors.New("mfa factor already used")
+	// ErrInvalidCursor is returned for a page cursor that was not returned
+	// by the same search
+	ErrInvalidCursor = errors.New("invalid page cursor")
+)
+
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id string) (*model.User, error)
 	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	// Search returns a page of the users matching filter that are not
+	// deleted, and the cursor of the next page, empty on the last one
+	Search(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error)
+	// Update writes the user if it is still at user.Version, and then
+	// advances the version
 	Update(ctx context.Context, user *model.User) error
+	UpdateRoles(ctx context.Context, id string, roles []string) error
+	// SetMFA replaces the user's MFA enrollment; nil removes it
+	SetMFA(ctx context.Context, id string, mfa *model.MFA) error
+	// UseTOTPStep records step as the last accepted one, unless a later or
+	// equal step already was
+	UseTOTPStep(ctx context.Context, id string, step int64) error
+	// UseRecoveryCode removes the recovery code with the given hash
+	UseRecoveryCode(ctx context.Context, id string, hash string) error
 	Delete(ctx context.Context, id string) error
-
+	// SoftDelete marks a user deleted. Deleted users are not found by the
+	// lookups above, while their cycles, usage and invoices keep the ID
+	SoftDelete(ctx context.Context, id string, at time.Time) error
+	// GetDeletedBefore returns the users deleted before the given time whose
+	// personal data has not been anonymized yet
+	GetDeletedBefore(ctx context.Context, before time.Time) ([]*model.User, error)
+	// Anonymize replaces the personal data of a deleted user, leaving a
+	// record that only holds the ID
+	Anonymize(ctx context.Context, id string, at time.Time) error
+	// Rekey moves the personal data of every user to the current encryption
+	// key, indexes users stored before search indexes, and returns how many
+	// users it updated
+	Rekey(ctx context.Context) (int, error)
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+var ErrUserTokenNotFound = errors.New("user token not found")
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *model.UserToken) error
+	// Consume marks an unused, unexpired token as used and returns it, so a
+	// token can only ever be consumed once
+	Consume(ctx context.Context, purpose, hash string, at time.Time) (*model.UserToken, error)
+}
@@ -1,22 +1,36 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	MongoDB  MongoDBConfig
-	LogLevel string
+	Server    ServerConfig
+	MongoDB   MongoDBConfig
+	Billing   BillingConfig
+	Statement StatementConfig
+	Stream    StreamConfig
+	GraphQL   GraphQLConfig
+	RateLimit RateLimitConfig
+	Auth      AuthConfig
+	Mail      MailConfig
+	Retention RetentionConfig
+	PII       PIIConfig
+	LogLevel  string
 }
 
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port     string
+	GRPCPort string
+	GinMode  string
 }
 
 type MongoDBConfig struct {
@@ -25,19 +39,168 @@ type MongoDBConfig struct {
 	Timeout  time.Duration
 }
 
+type BillingConfig struct {
+	// DefaultPlanID is used to rate cycles that were created without a plan
+	DefaultPlanID string
+}
+
+type StatementConfig struct {
+	// TemplateDir may contain statement.html.tmpl and statement.pdf.tmpl to replace the built-in templates
+	TemplateDir string
+}
+
+type StreamConfig struct {
+	// HeartbeatInterval keeps idle live usage streams open through proxies
+	HeartbeatInterval time.Duration
+	// AlertThresholdsMB are cycle totals that raise a threshold event when crossed
+	AlertThresholdsMB []float64
+}
+
+type GraphQLConfig struct {
+	// MaxComplexity rejects queries whose estimated cost is higher
+	MaxComplexity int
+}
+
+type RateLimitConfig struct {
+	// Store is "memory" (per replica), "mongo" (shared by all replicas) or "off"
+	Store string
+	// Default applies to route groups without their own limit
+	Default model.RateLimit
+	// Groups maps the first path segment after the version prefix
+	// (users, usage, lines, admin, graphql, ...) to its limit
+	Groups map[string]model.RateLimit
+}
+
+type AuthConfig struct {
+	// BootstrapAPIKey, when set, is registered with every scope at startup so
+	// operators can create the real keys; revoke it once they exist
+	BootstrapAPIKey string
+	// SessionTTL is how long a login session stays valid
+	SessionTTL time.Duration
+	// VerificationTTL and ResetTTL bound the mailed single-use tokens
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	// MFAKey is the base64 of the 32-byte key that encrypts TOTP secrets
+	MFAKey []byte
+	// MFAIssuer names the service in authenticator apps
+	MFAIssuer string
+	// MFARequiredRoles are only granted to sessions opened with a second factor
+	MFARequiredRoles []string
+	// MFAChallengeTTL is how long the second login step may take
+	MFAChallengeTTL time.Duration
+	// Lockout slows down and locks out repeated failed logins
+	Lockout LockoutConfig
+}
+
+type LockoutConfig struct {
+	// MaxAccountFailures and MaxIPFailures lock an email or a client address
+	// after that many failed logins within Window
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration
+	Duration           time.Duration
+	// BaseDelay after a failure doubles with each further one up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+type MailConfig struct {
+	// Sender is "smtp", or "log" to write mail to the log in development
+	Sender       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	// LinkBaseURL serves the /verify-email and /reset-password pages that
+	// mailed links point to
+	LinkBaseURL string
+}
+
+type RetentionConfig struct {
+	// DeletedUserWindow is how long the personal data of a deleted user is
+	// kept before the anonymize-users run removes it
+	DeletedUserWindow time.Duration
+	// DataExportTTL is how long a personal data export can be downloaded
+	DataExportTTL time.Duration
+}
+
+type PIIConfig struct {
+	// KeyFile is the JSON file with the keys that encrypt personal data at
+	// rest; see secrets.SetupFileKeyProvider for its format
+	KeyFile string
+}
+
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:     getEnv("PORT", "8080"),
+			GRPCPort: getEnv("GRPC_PORT", "9090"),
+			GinMode:  getEnv("GIN_MODE", "debug"),
 		},
 		MongoDB: MongoDBConfig{
 			URI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
 			Database: getEnv("MONGO_DATABASE", "phone_usage_db"),
 			Timeout:  getDurationEnv("MONGO_TIMEOUT", 10*time.Second),
 		},
+		Billing: BillingConfig{
+			DefaultPlanID: getEnv("BILLING_DEFAULT_PLAN", ""),
+		},
+		Statement: StatementConfig{
+			TemplateDir: getEnv("STATEMENT_TEMPLATE_DIR", ""),
+		},
+		Stream: StreamConfig{
+			HeartbeatInterval: getDurationEnv("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+			AlertThresholdsMB: getFloatListEnv("USAGE_ALERT_THRESHOLDS_MB", []float64{1024, 5120, 10240}),
+		},
+		GraphQL: GraphQLConfig{
+			MaxComplexity: getIntEnv("GRAPHQL_MAX_COMPLEXITY", 5000),
+		},
+		RateLimit: RateLimitConfig{
+			Store:   getEnv("RATE_LIMIT_STORE", "memory"),
+			Default: getRateLimitEnv("RATE_LIMIT_DEFAULT", model.RateLimit{Rate: 10, Burst: 20}),
+			Groups: getRateLimitGroupsEnv("RATE_LIMIT_GROUPS", map[string]model.RateLimit{
+				"users":   {Rate: 1, Burst: 10},
+				"usage":   {Rate: 20, Burst: 50},
+				"graphql": {Rate: 5, Burst: 20},
+				"auth":    {Rate: 1, Burst: 10},
+			}),
+		},
+		Auth: AuthConfig{
+			BootstrapAPIKey:  getEnv("AUTH_BOOTSTRAP_API_KEY", ""),
+			SessionTTL:       getDurationEnv("AUTH_SESSION_TTL", 24*time.Hour),
+			VerificationTTL:  getDurationEnv("AUTH_VERIFICATION_TTL", 48*time.Hour),
+			ResetTTL:         getDurationEnv("AUTH_RESET_TTL", time.Hour),
+			MFAIssuer:        getEnv("AUTH_MFA_ISSUER", "Phone Usage Service"),
+			MFARequiredRoles: getListEnv("AUTH_MFA_REQUIRED_ROLES", []string{model.RoleSupport, model.RoleAdmin}),
+			MFAChallengeTTL:  getDurationEnv("AUTH_MFA_CHALLENGE_TTL", 5*time.Minute),
+			Lockout: LockoutConfig{
+				MaxAccountFailures: getIntEnv("AUTH_LOCKOUT_ACCOUNT_FAILURES", 5),
+				MaxIPFailures:      getIntEnv("AUTH_LOCKOUT_IP_FAILURES", 50),
+				Window:             getDurationEnv("AUTH_LOCKOUT_WINDOW", 15*time.Minute),
+				Duration:           getDurationEnv("AUTH_LOCKOUT_DURATION", 15*time.Minute),
+				BaseDelay:          getDurationEnv("AUTH_LOCKOUT_BASE_DELAY", 500*time.Millisecond),
+				MaxDelay:           getDurationEnv("AUTH_LOCKOUT_MAX_DELAY", 30*time.Second),
+			},
+		},
+		Mail: MailConfig{
+			Sender:       getEnv("MAIL_SENDER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			LinkBaseURL:  getEnv("MAIL_LINK_BASE_URL", "http://localhost:8080"),
+		},
+		Retention: RetentionConfig{
+			DeletedUserWindow: getDurationEnv("RETENTION_DELETED_USER_WINDOW", 30*24*time.Hour),
+			DataExportTTL:     getDurationEnv("RETENTION_DATA_EXPORT_TTL", 72*time.Hour),
+		},
+		PII: PIIConfig{
+			KeyFile: getEnv("PII_KEY_FILE", ""),
+		},
 		LogLevel: getEnv("LOG_LEVEL", "info"),
 	}
 
@@ -45,6 +208,42 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("MONGO_URI is required")
 	}
 
+	if key := config.Auth.BootstrapAPIKey; key != "" && len(key) < 32 {
+		return nil, fmt.Errorf("AUTH_BOOTSTRAP_API_KEY must be at least 32 characters")
+	}
+
+	mfaKey, err := base64.StdEncoding.DecodeString(getEnv("AUTH_MFA_KEY", ""))
+	if err != nil || len(mfaKey) != 32 {
+		return nil, fmt.Errorf("AUTH_MFA_KEY must be the base64 of a 32-byte key")
+	}
+	config.Auth.MFAKey = mfaKey
+
+	if config.PII.KeyFile == "" {
+		return nil, fmt.Errorf("PII_KEY_FILE is required")
+	}
+
+	for _, role := range config.Auth.MFARequiredRoles {
+		if !model.ValidRole(role) {
+			return nil, fmt.Errorf("AUTH_MFA_REQUIRED_ROLES names unknown role %q", role)
+		}
+	}
+
+	switch config.Mail.Sender {
+	case "log":
+	case "smtp":
+		if config.Mail.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required when MAIL_SENDER is smtp")
+		}
+	default:
+		return nil, fmt.Errorf("MAIL_SENDER must be smtp or log, got %q", config.Mail.Sender)
+	}
+
+	switch config.RateLimit.Store {
+	case "memory", "mongo", "off":
+	default:
+		return nil, fmt.Errorf("RATE_LIMIT_STORE must be memory, mongo or off, got %q", config.RateLimit.Store)
+	}
+
 	return config, nil
 }
 
@@ -63,3 +262,89 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getFloatListEnv(key string, defaultValue []float64) []float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var values []float64
+	for _, part := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, f)
+	}
+	return values
+}
+
+func getListEnv(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// parseRateLimit reads "rate:burst", where rate is requests per second.
+func parseRateLimit(value string) (model.RateLimit, bool) {
+	rateValue, burstValue, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return model.RateLimit{}, false
+	}
+	rate, err := strconv.ParseFloat(rateValue, 64)
+	if err != nil || rate <= 0 {
+		return model.RateLimit{}, false
+	}
+	burst, err := strconv.Atoi(burstValue)
+	if err != nil || burst < 1 {
+		return model.RateLimit{}, false
+	}
+	return model.RateLimit{Rate: rate, Burst: burst}, true
+}
+
+func getRateLimitEnv(key string, defaultValue model.RateLimit) model.RateLimit {
+	if limit, ok := parseRateLimit(os.Getenv(key)); ok {
+		return limit
+	}
+	return defaultValue
+}
+
+// getRateLimitGroupsEnv reads "group=rate:burst,..." and overrides the
+// defaults of the groups it names.
+func getRateLimitGroupsEnv(key string, defaultValue map[string]model.RateLimit) map[string]model.RateLimit {
+	groups := make(map[string]model.RateLimit, len(defaultValue))
+	for group, limit := range defaultValue {
+		groups[group] = limit
+	}
+	value := os.Getenv(key)
+	if value == "" {
+		return groups
+	}
+	for _, part := range strings.Split(value, ",") {
+		group, limitValue, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if limit, ok := parseRateLimit(limitValue); ok {
+			groups[strings.TrimSpace(group)] = limit
+		}
+	}
+	return groups
+}
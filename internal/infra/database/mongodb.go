@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,10 +16,37 @@ type MongoDB struct {
 	Database *mongo.Database
 }
 
+// ErrDuplicateUsage means daily_usage holds several records of an owner's
+// line and day, which keeps its unique index from being built.
+var ErrDuplicateUsage = errors.New("daily usage has duplicate records; run cmd/dedupe-usage before starting this version")
+
 func Connect(uri, database string, timeout time.Duration) (*MongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	mongodb, err := open(ctx, uri, database)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mongodb.createIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	return mongodb, nil
+}
+
+// Open connects like Connect, without creating the indexes, for the
+// migrations that have to run before they can be built.
+func Open(uri, database string, timeout time.Duration) (*MongoDB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return open(ctx, uri, database)
+}
+
+func open(ctx context.Context, uri, database string) (*MongoDB, error) {
+
 	clientOptions := options.Client().
 		ApplyURI(uri).
 		SetMaxPoolSize(100).
@@ -41,23 +69,47 @@ func Connect(uri, database string, timeout time.Duration) (*MongoDB, error) {
 		Database: db,
 	}
 
-	if err := mongodb.createIndexes(ctx); err != nil {
-		return nil, fmt.Errorf("failed to create indexes: %w", err)
-	}
-
 	return mongodb, nil
 }
 
 func (m *MongoDB) createIndexes(ctx context.Context) error {
 	userIndexes := []mongo.IndexModel{
+		// Encrypted emails are looked up, and kept unique, by their blind
+		// index
 		{
-			Keys:    bson.D{{Key: "email", Value: 1}},
-			Options: options.Index().SetUnique(true),
+			Keys:    bson.D{{Key: "emailIndex", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		// Admin searches match prefixes by their blind indexes and page
+		// through users by timestamp
+		{
+			Keys: bson.D{{Key: "searchTokens", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "createdAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "updatedAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
 		},
 	}
 	if _, err := m.Database.Collection("users").Indexes().CreateMany(ctx, userIndexes); err != nil {
 		return fmt.Errorf("failed to create user indexes: %w", err)
 	}
+	// The unique index on the email of earlier versions only covers
+	// ciphertexts now, which are never equal
+	if _, err := m.Database.Collection("users").Indexes().DropOne(ctx, "email_1"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("failed to drop the email index: %w", err)
+	}
 
 	cycleIndexes := []mongo.IndexModel{
 		{
@@ -80,6 +132,9 @@ func (m *MongoDB) createIndexes(ctx context.Context) error {
 				{Key: "endDate", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{{Key: "endDate", Value: 1}},
+		},
 	}
 	if _, err := m.Database.Collection("cycles").Indexes().CreateMany(ctx, cycleIndexes); err != nil {
 		return fmt.Errorf("failed to create cycle indexes: %w", err)
@@ -93,14 +148,168 @@ func (m *MongoDB) createIndexes(ctx context.Context) error {
 				{Key: "usageDate", Value: -1},
 			},
 		},
+		{
+			// One record per owner, line and day, so concurrent first writes
+			// of a day cannot both be counted
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "mdn", Value: 1},
+				{Key: "usageDate", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
 		{
 			Keys: bson.D{{Key: "usageDate", Value: 1}},
 		},
+		{
+			Keys: bson.D{
+				{Key: "mdn", Value: 1},
+				{Key: "usageDate", Value: 1},
+			},
+		},
 	}
 	if _, err := m.Database.Collection("daily_usage").Indexes().CreateMany(ctx, usageIndexes); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to create usage indexes: %w: %v", ErrDuplicateUsage, err)
+		}
 		return fmt.Errorf("failed to create usage indexes: %w", err)
 	}
 
+	summaryIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "mdn", Value: 1},
+				{Key: "startDate", Value: -1},
+			},
+		},
+	}
+	if _, err := m.Database.Collection("cycle_summaries").Indexes().CreateMany(ctx, summaryIndexes); err != nil {
+		return fmt.Errorf("failed to create cycle summary indexes: %w", err)
+	}
+
+	invoiceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "cycleId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "periodStart", Value: -1},
+			},
+		},
+	}
+	if _, err := m.Database.Collection("invoices").Indexes().CreateMany(ctx, invoiceIndexes); err != nil {
+		return fmt.Errorf("failed to create invoice indexes: %w", err)
+	}
+
+	creditIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "cycleId", Value: 1}},
+		},
+	}
+	if _, err := m.Database.Collection("credits").Indexes().CreateMany(ctx, creditIndexes); err != nil {
+		return fmt.Errorf("failed to create credit indexes: %w", err)
+	}
+
+	apiKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := m.Database.Collection("api_keys").Indexes().CreateMany(ctx, apiKeyIndexes); err != nil {
+		return fmt.Errorf("failed to create api key indexes: %w", err)
+	}
+
+	sessionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	}
+	if _, err := m.Database.Collection("sessions").Indexes().CreateMany(ctx, sessionIndexes); err != nil {
+		return fmt.Errorf("failed to create session indexes: %w", err)
+	}
+
+	userTokenIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.Database.Collection("user_tokens").Indexes().CreateMany(ctx, userTokenIndexes); err != nil {
+		return fmt.Errorf("failed to create user token indexes: %w", err)
+	}
+
+	auditIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "actor", Value: 1},
+				{Key: "at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "target", Value: 1},
+				{Key: "at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "action", Value: 1},
+				{Key: "at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "at", Value: -1}},
+		},
+	}
+	if _, err := m.Database.Collection("audit_log").Indexes().CreateMany(ctx, auditIndexes); err != nil {
+		return fmt.Errorf("failed to create audit log indexes: %w", err)
+	}
+
+	loginAttemptIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.Database.Collection("login_attempts").Indexes().CreateMany(ctx, loginAttemptIndexes); err != nil {
+		return fmt.Errorf("failed to create login attempt indexes: %w", err)
+	}
+
+	dataExportIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.Database.Collection("data_exports").Indexes().CreateMany(ctx, dataExportIndexes); err != nil {
+		return fmt.Errorf("failed to create data export indexes: %w", err)
+	}
+
+	rateLimitIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	if _, err := m.Database.Collection("rate_limits").Indexes().CreateMany(ctx, rateLimitIndexes); err != nil {
+		return fmt.Errorf("failed to create rate limit indexes: %w", err)
+	}
+
 	return nil
 }
 
@@ -111,3 +320,23 @@ func (m *MongoDB) Disconnect(ctx context.Context) error {
 func (m *MongoDB) HealthCheck(ctx context.Context) error {
 	return m.Client.Ping(ctx, nil)
 }
+
+// SupportsChangeStreams reports whether the deployment is a replica set or a
+// sharded cluster; standalone servers cannot open change streams.
+func (m *MongoDB) SupportsChangeStreams(ctx context.Context) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := m.Database.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
+
+// isIndexNotFound tells whether err is the server refusing to drop an index
+// that does not exist.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Name == "IndexNotFound")
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+type zipArchiver struct{}
+
+// SetupZIPArchiver writes each table of a data export into a ZIP archive
+// twice, as <name>.json for machines and <name>.csv for spreadsheets.
+func SetupZIPArchiver() repository.DataArchiver {
+	return zipArchiver{}
+}
+
+func (zipArchiver) ContentType() string {
+	return "application/zip"
+}
+
+func (zipArchiver) Write(w io.Writer, tables []*model.ExportTable) error {
+	archive := zip.NewWriter(w)
+	for _, table := range tables {
+		for _, format := range []Format{FormatJSON, FormatCSV} {
+			if err := writeTable(archive, table, format); err != nil {
+				return fmt.Errorf("failed to write %s.%s: %w", table.Name, format.Extension(), err)
+			}
+		}
+	}
+	return archive.Close()
+}
+
+func writeTable(archive *zip.Writer, table *model.ExportTable, format Format) error {
+	entry, err := archive.Create(table.Name + "." + format.Extension())
+	if err != nil {
+		return err
+	}
+	writer, err := NewRowWriter(format, entry, table.Columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"mime"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+var contentTypes = map[Format]string{
+	FormatJSON:   "application/json",
+	FormatCSV:    "text/csv",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Aliases seen in the wild for the same formats
+var mediaTypes = map[string]Format{
+	"application/json":     FormatJSON,
+	"text/csv":             FormatCSV,
+	"application/csv":      FormatCSV,
+	"application/x-ndjson": FormatNDJSON,
+	"application/ndjson":   FormatNDJSON,
+	"application/jsonl":    FormatNDJSON,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": FormatXLSX,
+}
+
+func ParseFormat(value string) (Format, bool) {
+	format := Format(strings.ToLower(value))
+	_, ok := contentTypes[format]
+	return format, ok
+}
+
+// FromAccept picks the first supported media type listed in an Accept header,
+// falling back to JSON. Quality values are not weighed: clients asking for an
+// export list a single type.
+func FromAccept(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := mediaTypes[mediaType]; ok {
+			return format
+		}
+	}
+	return FormatJSON
+}
+
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RowWriter writes one record at a time so that exports never hold more than
+// the current row in memory. Cell values may be strings, numbers, bools or
+// time.Time; Close must be called to complete the output.
+type RowWriter interface {
+	Write(row []any) error
+	Close() error
+}
+
+func NewRowWriter(format Format, w io.Writer, columns []string) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return &jsonWriter{out: bufio.NewWriter(w), columns: columns, separator: "\n"}, nil
+	case FormatJSON:
+		return &jsonWriter{out: bufio.NewWriter(w), columns: columns, separator: ",", open: "[", close: "]"}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+type csvWriter struct {
+	out *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{out: out}, nil
+}
+
+func (c *csvWriter) Write(row []any) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatCell(value)
+	}
+	return c.out.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.out.Flush()
+	return c.out.Error()
+}
+
+// jsonWriter emits each row as an object keyed by column name, either one per
+// line (NDJSON) or as the elements of a single array (JSON).
+type jsonWriter struct {
+	out       *bufio.Writer
+	columns   []string
+	separator string
+	open      string
+	close     string
+	rows      int
+}
+
+func (j *jsonWriter) Write(row []any) error {
+	if j.rows == 0 {
+		j.out.WriteString(j.open)
+	} else if j.separator != "\n" {
+		j.out.WriteString(j.separator)
+	}
+
+	j.out.WriteByte('{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.out.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		j.out.Write(key)
+		j.out.WriteByte(':')
+		value, err := json.Marshal(row[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", column, err)
+		}
+		j.out.Write(value)
+	}
+	j.out.WriteByte('}')
+	if j.separator == "\n" {
+		j.out.WriteByte('\n')
+	}
+
+	j.rows++
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	if j.rows == 0 {
+		j.out.WriteString(j.open)
+	}
+	j.out.WriteString(j.close)
+	return j.out.Flush()
+}
+
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
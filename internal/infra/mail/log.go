@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"context"
+	"log"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+type logSender struct{}
+
+// SetupLogSender writes mail to the log instead of sending it, for local
+// development. Messages contain live verification and reset links, so it
+// must not be used where logs are shared.
+func SetupLogSender() repository.MailSender {
+	return logSender{}
+}
+
+func (logSender) Send(ctx context.Context, mail *model.Mail) error {
+	log.Printf("Mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
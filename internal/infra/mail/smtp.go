@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type smtpSender struct {
+	cfg  SMTPConfig
+	auth smtp.Auth
+}
+
+// SetupSMTPSender sends mail through an SMTP relay, upgrading to TLS when
+// the server offers STARTTLS. Authentication is skipped without a username.
+func SetupSMTPSender(cfg SMTPConfig) repository.MailSender {
+	sender := &smtpSender{cfg: cfg}
+	if cfg.Username != "" {
+		sender.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return sender
+}
+
+// Send does not honour ctx once the message is handed to net/smtp, which has
+// no context support; the relay's own timeouts apply.
+func (s *smtpSender) Send(ctx context.Context, mail *model.Mail) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Header values are written verbatim, so line breaks would let a caller
+	// add headers of their own
+	if strings.ContainsAny(mail.To+mail.Subject, "\r\n") {
+		return fmt.Errorf("mail header contains a line break")
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mail.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	if err := smtp.SendMail(addr, s.auth, s.cfg.From, []string{mail.To}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAPIKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupAPIKeyRepository(db *mongo.Database) repository.APIKeyRepository {
+	return &mongoAPIKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (m *mongoAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	key.CreatedAt = time.Now()
+
+	result, err := m.collection.InsertOne(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		key.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoAPIKeyRepository) GetByID(ctx context.Context, id string) (*model.APIKey, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+
+	return m.findOne(ctx, bson.M{"_id": objectID})
+}
+
+func (m *mongoAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	return m.findOne(ctx, bson.M{"hash": hash})
+}
+
+func (m *mongoAPIKeyRepository) findOne(ctx context.Context, filter bson.M) (*model.APIKey, error) {
+	var key model.APIKey
+	err := m.collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (m *mongoAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*model.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (m *mongoAPIKeyRepository) Rotate(ctx context.Context, id, prefix, hash string, at time.Time) (*model.APIKey, error) {
+	return m.updateActive(ctx, id, bson.M{"prefix": prefix, "hash": hash, "rotatedAt": at})
+}
+
+func (m *mongoAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (*model.APIKey, error) {
+	return m.updateActive(ctx, id, bson.M{"revokedAt": at})
+}
+
+// updateActive only matches keys that have not been revoked, so a rotation
+// racing a revocation cannot bring the key back.
+func (m *mongoAPIKeyRepository) updateActive(ctx context.Context, id string, set bson.M) (*model.APIKey, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "revokedAt": bson.M{"$exists": false}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var key model.APIKey
+	err = m.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (m *mongoAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repository.ErrAPIKeyNotFound
+	}
+
+	_, err = m.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$max": bson.M{"lastUsedAt": at}})
+	if err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+
+	return nil
+}
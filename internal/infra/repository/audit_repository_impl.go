@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupAuditRepository(db *mongo.Database) repository.AuditRepository {
+	return &mongoAuditRepository{
+		collection: db.Collection("audit_log"),
+	}
+}
+
+func (m *mongoAuditRepository) Append(ctx context.Context, entry *model.AuditEntry) error {
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	result, err := m.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		entry.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoAuditRepository) Find(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	query := bson.M{}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.Target != "" {
+		query["target"] = filter.Target
+	}
+	at := bson.M{}
+	if !filter.From.IsZero() {
+		at["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		at["$lte"] = filter.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	cursor, err := m.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []*model.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (m *mongoAuditRepository) RedactFields(ctx context.Context, target string, fields []string) error {
+	for _, side := range []string{"before", "after"} {
+		for _, field := range fields {
+			path := side + "." + field
+			filter := bson.M{"target": target, path: bson.M{"$exists": true}}
+			update := bson.M{"$set": bson.M{path: model.RedactedValue}}
+			if _, err := m.collection.UpdateMany(ctx, filter, update); err != nil {
+				return fmt.Errorf("failed to redact audit entries: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
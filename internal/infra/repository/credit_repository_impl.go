@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCreditRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupCreditRepository(db *mongo.Database) repository.CreditRepository {
+	return &mongoCreditRepository{
+		collection: db.Collection("credits"),
+	}
+}
+
+func (m *mongoCreditRepository) Create(ctx context.Context, credit *model.Credit) error {
+	credit.CreatedAt = time.Now()
+
+	result, err := m.collection.InsertOne(ctx, credit)
+	if err != nil {
+		return fmt.Errorf("failed to create credit: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		credit.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoCreditRepository) GetByCycleID(ctx context.Context, cycleID string) ([]*model.Credit, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{"cycleId": cycleID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credits by cycle ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var credits []*model.Credit
+	if err := cursor.All(ctx, &credits); err != nil {
+		return nil, fmt.Errorf("failed to decode credits: %w", err)
+	}
+
+	return credits, nil
+}
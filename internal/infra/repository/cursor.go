@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// streamCursor decodes documents one at a time and hands each to fn, closing
+// the cursor when done. Iteration stops at the first error returned by fn.
+func streamCursor[T any](ctx context.Context, cursor *mongo.Cursor, fn func(*T) error) error {
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var document T
+		if err := cursor.Decode(&document); err != nil {
+			return fmt.Errorf("failed to decode document: %w", err)
+		}
+		if err := fn(&document); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor failed: %w", err)
+	}
+	return nil
+}
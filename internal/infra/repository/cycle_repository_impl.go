@@ -31,6 +31,7 @@ func SetupCycleRepository(db *mongo.Database) repository.CycleRepository {
 
 func (m *mongoCycleRepository) Create(ctx context.Context, cycle *model.Cycle) error {
 	cycle.CreatedAt = time.Now()
+	cycle.Version = 1
 
 	result, err := m.collection.InsertOne(ctx, cycle)
 	if err != nil {
@@ -80,6 +81,17 @@ func (m *mongoCycleRepository) GetByMDN(ctx context.Context, mdn string) ([]*mod
 	return cycles, nil
 }
 
+func (m *mongoCycleRepository) StreamByMDN(ctx context.Context, mdn string, fn func(*model.Cycle) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "startDate", Value: -1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{"mdn": mdn}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to get cycles by MDN: %w", err)
+	}
+
+	return streamCursor(ctx, cursor, fn)
+}
+
 func (m *mongoCycleRepository) GetByUserID(ctx context.Context, userID string) ([]*model.Cycle, error) {
 	opts := options.Find().SetSort(bson.D{{Key: "startDate", Value: -1}})
 
@@ -97,6 +109,31 @@ func (m *mongoCycleRepository) GetByUserID(ctx context.Context, userID string) (
 	return cycles, nil
 }
 
+func (m *mongoCycleRepository) GetByMDNs(ctx context.Context, mdns []string) ([]*model.Cycle, error) {
+	return m.findNewestFirst(ctx, bson.M{"mdn": bson.M{"$in": mdns}})
+}
+
+func (m *mongoCycleRepository) GetByUserIDs(ctx context.Context, userIDs []string) ([]*model.Cycle, error) {
+	return m.findNewestFirst(ctx, bson.M{"userId": bson.M{"$in": userIDs}})
+}
+
+func (m *mongoCycleRepository) findNewestFirst(ctx context.Context, filter bson.M) ([]*model.Cycle, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "startDate", Value: -1}})
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cycles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var cycles []*model.Cycle
+	if err := cursor.All(ctx, &cycles); err != nil {
+		return nil, fmt.Errorf("failed to decode cycles: %w", err)
+	}
+
+	return cycles, nil
+}
+
 func (r *mongoCycleRepository) GetCurrentCycle(ctx context.Context, userID, mdn string, currentDate time.Time) (*model.Cycle, error) {
 	filter := bson.M{
 		"userId":    userID,
@@ -116,3 +153,23 @@ func (r *mongoCycleRepository) GetCurrentCycle(ctx context.Context, userID, mdn
 
 	return &cycle, nil
 }
+
+func (m *mongoCycleRepository) GetEndedBetween(ctx context.Context, from, to time.Time) ([]*model.Cycle, error) {
+	filter := bson.M{
+		"endDate": bson.M{"$gte": from, "$lt": to},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "endDate", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ended cycles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var cycles []*model.Cycle
+	if err := cursor.All(ctx, &cycles); err != nil {
+		return nil, fmt.Errorf("failed to decode cycles: %w", err)
+	}
+
+	return cycles, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCycleSummaryRepository struct {
+	collection *mongo.Collection
+	cycles     *mongo.Collection
+}
+
+func SetupCycleSummaryRepository(db *mongo.Database) repository.CycleSummaryRepository {
+	return &mongoCycleSummaryRepository{
+		collection: db.Collection("cycle_summaries"),
+		cycles:     db.Collection("cycles"),
+	}
+}
+
+func (m *mongoCycleSummaryRepository) GetByCycleID(ctx context.Context, cycleID string) (*model.CycleSummary, error) {
+	objectID, err := primitive.ObjectIDFromHex(cycleID)
+	if err != nil {
+		return nil, repository.ErrCycleSummaryNotFound
+	}
+
+	var summary model.CycleSummary
+	err = m.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&summary)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrCycleSummaryNotFound
+		}
+		return nil, fmt.Errorf("failed to get cycle summary: %w", err)
+	}
+
+	return &summary, nil
+}
+
+// ApplyUsage uses an update pipeline so the total, day count and peak are all
+// derived from the stored document atomically, without a read-modify-write.
+func (m *mongoCycleSummaryRepository) ApplyUsage(ctx context.Context, cycle *model.Cycle, usageDate time.Time, usedMB, previousMB float64, isNewDay bool) (*model.CycleSummary, error) {
+	objectID, err := primitive.ObjectIDFromHex(cycle.ID)
+	if err != nil {
+		return nil, ErrCycleNotFound
+	}
+
+	dayIncrement := 0
+	if isNewDay {
+		dayIncrement = 1
+	}
+	currentPeak := bson.M{"$ifNull": bson.A{"$peakMb", -1}}
+	isPeak := bson.M{"$gte": bson.A{usedMB, currentPeak}}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"mdn":         bson.M{"$literal": cycle.MDN},
+			"userId":      bson.M{"$literal": cycle.UserID},
+			"startDate":   cycle.StartDate,
+			"endDate":     cycle.EndDate,
+			"totalMb":     bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$totalMb", 0}}, usedMB - previousMB}},
+			"dayCount":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$dayCount", 0}}, dayIncrement}},
+			"peakMb":      bson.M{"$cond": bson.A{isPeak, usedMB, "$peakMb"}},
+			"peakDate":    bson.M{"$cond": bson.A{isPeak, usageDate, "$peakDate"}},
+			"lastUpdated": "$$NOW",
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var summary model.CycleSummary
+	if err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, update, opts).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("failed to apply usage to cycle summary: %w", err)
+	}
+
+	return &summary, nil
+}
+
+func (m *mongoCycleSummaryRepository) Rebuild(ctx context.Context, cycleID string) error {
+	objectID, err := primitive.ObjectIDFromHex(cycleID)
+	if err != nil {
+		return ErrCycleNotFound
+	}
+	return m.rebuild(ctx, bson.M{"_id": objectID})
+}
+
+func (m *mongoCycleSummaryRepository) RebuildByMDN(ctx context.Context, mdn string) error {
+	return m.rebuild(ctx, bson.M{"mdn": mdn})
+}
+
+func (m *mongoCycleSummaryRepository) RebuildAll(ctx context.Context) error {
+	return m.rebuild(ctx, bson.M{})
+}
+
+// rebuild recomputes the summaries of every cycle matching filter entirely
+// server-side: each cycle joins its own daily usage and the result is merged
+// over whatever summary was stored before.
+func (m *mongoCycleSummaryRepository) rebuild(ctx context.Context, filter bson.M) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "daily_usage",
+			"let": bson.M{
+				"mdn":       "$mdn",
+				"userId":    "$userId",
+				"startDate": "$startDate",
+				"endDate":   "$endDate",
+			},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$mdn", "$$mdn"}},
+					bson.M{"$eq": bson.A{"$userId", "$$userId"}},
+					bson.M{"$gte": bson.A{"$usageDate", "$$startDate"}},
+					bson.M{"$lte": bson.A{"$usageDate", "$$endDate"}},
+				}}}},
+				bson.M{"$sort": bson.D{{Key: "usedInMb", Value: -1}, {Key: "usageDate", Value: 1}}},
+				bson.M{"$project": bson.M{"usageDate": 1, "usedInMb": 1}},
+			},
+			"as": "usage",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"mdn":         1,
+			"userId":      1,
+			"startDate":   1,
+			"endDate":     1,
+			"totalMb":     bson.M{"$sum": "$usage.usedInMb"},
+			"dayCount":    bson.M{"$size": "$usage"},
+			"peak":        bson.M{"$arrayElemAt": bson.A{"$usage", 0}},
+			"lastUpdated": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"peakMb":   bson.M{"$ifNull": bson.A{"$peak.usedInMb", 0}},
+			"peakDate": "$peak.usageDate",
+		}}},
+		{{Key: "$unset", Value: "peak"}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "cycle_summaries",
+			"on":             "_id",
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	}
+
+	cursor, err := m.cycles.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild cycle summaries: %w", err)
+	}
+	return cursor.Close(ctx)
+}
@@ -26,9 +26,14 @@ func SetupDailyUsageRepository(db *mongo.Database) repository.DailyUsageReposito
 func (m *mongoDailyUsageRepository) Create(ctx context.Context, usage *model.DailyUsage) error {
 	usage.CreatedAt = time.Now()
 	usage.UpdatedAt = time.Now()
+	usage.Version = 1
 
 	result, err := m.collection.InsertOne(ctx, usage)
 	if err != nil {
+		// Another writer recorded the day first
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrConcurrentModification
+		}
 		return fmt.Errorf("failed to create usage: %w", err)
 	}
 
@@ -65,6 +70,72 @@ func (m *mongoDailyUsageRepository) GetByDateRange(ctx context.Context, userID,
 	return usageRecords, nil
 }
 
+func (m *mongoDailyUsageRepository) StreamByDateRange(ctx context.Context, userID, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error {
+	filter := bson.M{
+		"userId": userID,
+		"mdn":    mdn,
+		"usageDate": bson.M{
+			"$gte": startDate,
+			"$lte": endDate,
+		},
+	}
+
+	return m.stream(ctx, filter, fn)
+}
+
+func (m *mongoDailyUsageRepository) StreamByMDN(ctx context.Context, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error {
+	filter := bson.M{
+		"mdn": mdn,
+		"usageDate": bson.M{
+			"$gte": startDate,
+			"$lte": endDate,
+		},
+	}
+
+	return m.stream(ctx, filter, fn)
+}
+
+func (m *mongoDailyUsageRepository) stream(ctx context.Context, filter bson.M, fn func(*model.DailyUsage) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "usageDate", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	return streamCursor(ctx, cursor, fn)
+}
+
+func (m *mongoDailyUsageRepository) GetByCycles(ctx context.Context, cycles []*model.Cycle) ([]*model.DailyUsage, error) {
+	if len(cycles) == 0 {
+		return nil, nil
+	}
+
+	windows := make(bson.A, 0, len(cycles))
+	for _, cycle := range cycles {
+		windows = append(windows, bson.M{
+			"userId":    cycle.UserID,
+			"mdn":       cycle.MDN,
+			"usageDate": bson.M{"$gte": cycle.StartDate, "$lte": cycle.EndDate},
+		})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "usageDate", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{"$or": windows}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by cycles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var usageRecords []*model.DailyUsage
+	if err := cursor.All(ctx, &usageRecords); err != nil {
+		return nil, fmt.Errorf("failed to decode usage records: %w", err)
+	}
+
+	return usageRecords, nil
+}
+
 func (m *mongoDailyUsageRepository) Update(ctx context.Context, usage *model.DailyUsage) error {
 	objectID, err := primitive.ObjectIDFromHex(usage.ID)
 	if err != nil {
@@ -77,17 +148,140 @@ func (m *mongoDailyUsageRepository) Update(ctx context.Context, usage *model.Dai
 		"$set": bson.M{
 			"usedInMb":  usage.UsedInMB,
 			"updatedAt": usage.UpdatedAt,
+			"version":   usage.Version + 1,
 		},
 	}
 
-	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	result, err := m.collection.UpdateOne(ctx, atVersion(bson.M{"_id": objectID}, usage.Version), update)
 	if err != nil {
 		return fmt.Errorf("failed to update usage: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
+		exists, err := m.collection.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			return fmt.Errorf("failed to update usage: %w", err)
+		}
+		if exists > 0 {
+			return repository.ErrConcurrentModification
+		}
 		return fmt.Errorf("usage record not found")
 	}
 
+	usage.Version++
 	return nil
 }
+
+// GetCycleTotals buckets every usage document of the MDN into the cycle that
+// covers it (matching on owner and date range) and sums each bucket server-side.
+func (m *mongoDailyUsageRepository) GetCycleTotals(ctx context.Context, mdn string, cycles []*model.Cycle, alignDays int) ([]*model.CycleUsageTotal, error) {
+	if len(cycles) == 0 {
+		return []*model.CycleUsageTotal{}, nil
+	}
+
+	minStart, maxEnd := cycles[0].StartDate, cycles[0].EndDate
+	branches := make(bson.A, 0, len(cycles))
+	for _, cycle := range cycles {
+		if cycle.StartDate.Before(minStart) {
+			minStart = cycle.StartDate
+		}
+		if cycle.EndDate.After(maxEnd) {
+			maxEnd = cycle.EndDate
+		}
+		branches = append(branches, bson.M{
+			"case": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$userId", cycle.UserID}},
+				bson.M{"$gte": bson.A{"$usageDate", cycle.StartDate}},
+				bson.M{"$lte": bson.A{"$usageDate", cycle.EndDate}},
+			}},
+			"then": bson.M{"cycleId": cycle.ID, "startDate": cycle.StartDate},
+		})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"mdn":       mdn,
+			"usageDate": bson.M{"$gte": minStart, "$lte": maxEnd},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"cycle": bson.M{"$switch": bson.M{"branches": branches, "default": nil}},
+		}}},
+		{{Key: "$match", Value: bson.M{"cycle": bson.M{"$ne": nil}}}},
+		{{Key: "$addFields", Value: bson.M{
+			"dayOfCycle": bson.M{"$floor": bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{"$usageDate", "$cycle.startDate"}},
+				24 * 60 * 60 * 1000,
+			}}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$cycle.cycleId",
+			"totalMb": bson.M{"$sum": "$usedInMb"},
+			"days":    bson.M{"$sum": 1},
+			"alignedMb": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{"$dayOfCycle", alignDays}},
+				"$usedInMb",
+				0,
+			}}},
+		}}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate cycle totals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*model.CycleUsageTotal
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, fmt.Errorf("failed to decode cycle totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// RemoveDuplicates clears the way for the unique index on owner, line and
+// day. Records written before it existed could be duplicated by concurrent
+// first writes of a day; the last one written is what its writer saw last.
+func (m *mongoDailyUsageRepository) RemoveDuplicates(ctx context.Context) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"userId": "$userId", "mdn": "$mdn", "usageDate": "$usageDate"},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	}
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var mdns []string
+	seen := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var group struct {
+			Key struct {
+				MDN string `bson:"mdn"`
+			} `bson:"_id"`
+			IDs []primitive.ObjectID `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return mdns, fmt.Errorf("failed to decode duplicate usage: %w", err)
+		}
+
+		if _, err := m.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}}); err != nil {
+			return mdns, fmt.Errorf("failed to remove duplicate usage: %w", err)
+		}
+		if !seen[group.Key.MDN] {
+			seen[group.Key.MDN] = true
+			mdns = append(mdns, group.Key.MDN)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return mdns, fmt.Errorf("failed to find duplicate usage: %w", err)
+	}
+
+	return mdns, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoDataExportRepository keeps the archive in the export document, next to
+// its status, so that the TTL index on expiresAt removes both together. The
+// archive is left out of every read but GetArchive. A document holds at most
+// 16MB, which Complete reports as an error for the export to fail with.
+type mongoDataExportRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupDataExportRepository(db *mongo.Database) repository.DataExportRepository {
+	return &mongoDataExportRepository{
+		collection: db.Collection("data_exports"),
+	}
+}
+
+func (m *mongoDataExportRepository) Create(ctx context.Context, export *model.DataExport) error {
+	result, err := m.collection.InsertOne(ctx, export)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		export.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoDataExportRepository) GetByID(ctx context.Context, id string) (*model.DataExport, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrDataExportNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "expiresAt": bson.M{"$gt": time.Now()}}
+	opts := options.FindOne().SetProjection(bson.M{"archive": 0})
+
+	var export model.DataExport
+	if err := m.collection.FindOne(ctx, filter, opts).Decode(&export); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrDataExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	return &export, nil
+}
+
+func (m *mongoDataExportRepository) Complete(ctx context.Context, id string, archive []byte, at, expiresAt time.Time) error {
+	return m.finish(ctx, id, bson.M{
+		"status":      model.DataExportReady,
+		"archive":     archive,
+		"size":        len(archive),
+		"completedAt": at,
+		"expiresAt":   expiresAt,
+	})
+}
+
+func (m *mongoDataExportRepository) Fail(ctx context.Context, id, reason string, at time.Time) error {
+	return m.finish(ctx, id, bson.M{
+		"status":      model.DataExportFailed,
+		"error":       reason,
+		"completedAt": at,
+	})
+}
+
+// finish only applies to running exports, so an export is completed or
+// failed once.
+func (m *mongoDataExportRepository) finish(ctx context.Context, id string, set bson.M) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repository.ErrDataExportNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "status": model.DataExportRunning}
+	result, err := m.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update data export: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return repository.ErrDataExportNotFound
+	}
+
+	return nil
+}
+
+func (m *mongoDataExportRepository) GetArchive(ctx context.Context, id string) ([]byte, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrDataExportNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "status": model.DataExportReady, "expiresAt": bson.M{"$gt": time.Now()}}
+	opts := options.FindOne().SetProjection(bson.M{"archive": 1})
+
+	var document struct {
+		Archive []byte `bson:"archive"`
+	}
+	if err := m.collection.FindOne(ctx, filter, opts).Decode(&document); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrDataExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get data export archive: %w", err)
+	}
+
+	return document.Archive, nil
+}
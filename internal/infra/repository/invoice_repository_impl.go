@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoInvoiceRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupInvoiceRepository(db *mongo.Database) repository.InvoiceRepository {
+	return &mongoInvoiceRepository{
+		collection: db.Collection("invoices"),
+	}
+}
+
+// Create relies on the unique cycleId index so that two concurrent invoicing
+// runs for the same cycle cannot both succeed.
+func (m *mongoInvoiceRepository) Create(ctx context.Context, invoice *model.Invoice) error {
+	invoice.IssuedAt = time.Now()
+
+	result, err := m.collection.InsertOne(ctx, invoice)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrInvoiceAlreadyExists
+		}
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		invoice.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoInvoiceRepository) GetByID(ctx context.Context, id string) (*model.Invoice, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrInvoiceNotFound
+	}
+
+	return m.findOne(ctx, bson.M{"_id": objectID})
+}
+
+func (m *mongoInvoiceRepository) GetByCycleID(ctx context.Context, cycleID string) (*model.Invoice, error) {
+	return m.findOne(ctx, bson.M{"cycleId": cycleID})
+}
+
+func (m *mongoInvoiceRepository) GetByUserID(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "periodStart", Value: -1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices by user ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var invoices []*model.Invoice
+	if err := cursor.All(ctx, &invoices); err != nil {
+		return nil, fmt.Errorf("failed to decode invoices: %w", err)
+	}
+
+	return invoices, nil
+}
+
+func (m *mongoInvoiceRepository) findOne(ctx context.Context, filter bson.M) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := m.collection.FindOne(ctx, filter).Decode(&invoice)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrInvoiceNotFound
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+
+	return &invoice, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoLoginAttemptRepository relies on a TTL index on expiresAt to forget
+// keys without recent failures.
+type mongoLoginAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupLoginAttemptRepository(db *mongo.Database) repository.LoginAttemptRepository {
+	return &mongoLoginAttemptRepository{
+		collection: db.Collection("login_attempts"),
+	}
+}
+
+func (m *mongoLoginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	var attempts model.LoginAttempts
+	err := m.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempts)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &model.LoginAttempts{Key: key}, nil
+		}
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	return &attempts, nil
+}
+
+// RecordFailure increments atomically, so concurrent failures are all
+// counted. A lock outlives the retention of the failures that caused it.
+func (m *mongoLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, retention time.Duration) (*model.LoginAttempts, error) {
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"lastFailureAt": at},
+		"$max": bson.M{"expiresAt": at.Add(retention)},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempts model.LoginAttempts
+	if err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&attempts); err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return &attempts, nil
+}
+
+func (m *mongoLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"lockedUntil": until},
+		"$max": bson.M{"expiresAt": until},
+	}
+	if _, err := m.collection.UpdateOne(ctx, bson.M{"_id": key}, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (m *mongoLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
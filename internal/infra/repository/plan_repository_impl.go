@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoPlanRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupPlanRepository(db *mongo.Database) repository.PlanRepository {
+	return &mongoPlanRepository{
+		collection: db.Collection("plans"),
+	}
+}
+
+func (m *mongoPlanRepository) Create(ctx context.Context, plan *model.Plan) error {
+	plan.CreatedAt = time.Now()
+
+	if _, err := m.collection.InsertOne(ctx, plan); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrPlanAlreadyExists
+		}
+		return fmt.Errorf("failed to create plan: %w", err)
+	}
+
+	return nil
+}
+
+func (m *mongoPlanRepository) GetByID(ctx context.Context, id string) (*model.Plan, error) {
+	var plan model.Plan
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&plan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get plan: %w", err)
+	}
+
+	return &plan, nil
+}
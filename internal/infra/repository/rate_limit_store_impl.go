@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memorySweepInterval is how many takes pass between sweeps of idle buckets.
+const memorySweepInterval = 1024
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// memoryRateLimitStore keeps buckets in process, so each replica enforces
+// its limits separately.
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	windows map[string]time.Duration
+	takes   int
+}
+
+func SetupMemoryRateLimitStore() repository.RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+		windows: make(map[string]time.Duration),
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%memorySweepInterval == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+		s.windows[key] = limit.Window()
+	}
+
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return limit.Result(b.tokens, allowed), nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again;
+// recreating them later gives the same result.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if window := s.windows[key]; window > 0 && now.Sub(b.updatedAt) >= window {
+			delete(s.buckets, key)
+			delete(s.windows, key)
+		}
+	}
+}
+
+// mongoRateLimitStore shares buckets between replicas. Refill uses the
+// server clock ($$NOW) so replicas with drifting clocks agree, and a TTL
+// index on expiresAt removes buckets once they would be full again.
+type mongoRateLimitStore struct {
+	collection *mongo.Collection
+}
+
+func SetupMongoRateLimitStore(db *mongo.Database) repository.RateLimitStore {
+	return &mongoRateLimitStore{collection: db.Collection("rate_limits")}
+}
+
+func (s *mongoRateLimitStore) Take(ctx context.Context, key string, limit model.RateLimit) (*model.RateLimitResult, error) {
+	burst := float64(limit.Burst)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updatedAt", "$$NOW"}}}},
+		1000,
+	}}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{elapsedSeconds, limit.Rate}},
+			}}}},
+			"updatedAt": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":    bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", limit.Window().Milliseconds()}},
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var state struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&state)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two replicas created the bucket at once; the loser updates the winner's
+		err = s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&state)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return limit.Result(state.Tokens, state.Allowed), nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoSessionRepository relies on a TTL index on expiresAt to remove expired
+// sessions; lookups also ignore them, since the TTL monitor runs only once a
+// minute.
+type mongoSessionRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupSessionRepository(db *mongo.Database) repository.SessionRepository {
+	return &mongoSessionRepository{
+		collection: db.Collection("sessions"),
+	}
+}
+
+func (m *mongoSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	session.CreatedAt = time.Now()
+
+	result, err := m.collection.InsertOne(ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		session.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoSessionRepository) GetByHash(ctx context.Context, hash string) (*model.Session, error) {
+	filter := bson.M{"hash": hash, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	var session model.Session
+	err := m.collection.FindOne(ctx, filter).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (m *mongoSessionRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return repository.ErrSessionNotFound
+	}
+
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": objectID}); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+func (m *mongoSessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	if _, err := m.collection.DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return nil
+}
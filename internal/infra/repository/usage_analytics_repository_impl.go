@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoUsageAnalyticsRepository struct {
+	collection *mongo.Collection
+	cycles     *mongo.Collection
+}
+
+func SetupUsageAnalyticsRepository(db *mongo.Database) repository.UsageAnalyticsRepository {
+	return &mongoUsageAnalyticsRepository{
+		collection: db.Collection("daily_usage"),
+		cycles:     db.Collection("cycles"),
+	}
+}
+
+// lineTotalsStages matches on usageDate alone so the range scan is served by
+// the usageDate index, then totals each MDN.
+func lineTotalsStages(startDate, endDate time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"usageDate": bson.M{"$gte": startDate, "$lte": endDate},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$mdn",
+			"totalMb": bson.M{"$sum": "$usedInMb"},
+			"days":    bson.M{"$sum": 1},
+		}}},
+	}
+}
+
+func (m *mongoUsageAnalyticsRepository) GetTopConsumers(ctx context.Context, startDate, endDate time.Time, limit int) ([]*model.LineUsageTotal, error) {
+	pipeline := append(lineTotalsStages(startDate, endDate),
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "totalMb", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	cursor, err := m.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate top consumers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var totals []*model.LineUsageTotal
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, fmt.Errorf("failed to decode top consumers: %w", err)
+	}
+
+	return totals, nil
+}
+
+// cycleTotalsStages totals each cycle that ended within [startDate, endDate]
+// over its own days, so a cycle counts once and in full however its days fall
+// in the range. It runs on cycles, matched by the endDate index, and looks up
+// each cycle's usage by owner, line and day.
+func cycleTotalsStages(startDate, endDate time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"endDate": bson.M{"$gte": startDate, "$lte": endDate},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "daily_usage",
+			"let": bson.M{
+				"userId":    "$userId",
+				"mdn":       "$mdn",
+				"startDate": "$startDate",
+				"endDate":   "$endDate",
+			},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+					bson.M{"$eq": bson.A{"$userId", "$$userId"}},
+					bson.M{"$eq": bson.A{"$mdn", "$$mdn"}},
+					bson.M{"$gte": bson.A{"$usageDate", "$$startDate"}},
+					bson.M{"$lte": bson.A{"$usageDate", "$$endDate"}},
+				}}}}},
+				{{Key: "$group", Value: bson.M{
+					"_id":     nil,
+					"totalMb": bson.M{"$sum": "$usedInMb"},
+				}}},
+			},
+			"as": "usage",
+		}}},
+		// Cycles without usage total zero
+		{{Key: "$project", Value: bson.M{
+			"totalMb": bson.M{"$ifNull": bson.A{
+				bson.M{"$arrayElemAt": bson.A{"$usage.totalMb", 0}},
+				0.0,
+			}},
+		}}},
+	}
+}
+
+// GetUsagePercentiles uses the nearest-rank method: the p-th percentile of n
+// cycle totals is the ceil(p*n)-th smallest. Each is read by sorting the
+// totals and skipping to its rank, so the totals are never collected into a
+// single document and the 16MB document limit does not bound the fleet.
+func (m *mongoUsageAnalyticsRepository) GetUsagePercentiles(ctx context.Context, startDate, endDate time.Time) (*model.UsagePercentiles, error) {
+	pipeline := append(cycleTotalsStages(startDate, endDate),
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":        nil,
+			"cycleCount": bson.M{"$sum": 1},
+			"mean":       bson.M{"$avg": "$totalMb"},
+			"min":        bson.M{"$min": "$totalMb"},
+			"max":        bson.M{"$max": "$totalMb"},
+		}}},
+	)
+
+	cursor, err := m.cycles.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage percentiles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	percentiles := &model.UsagePercentiles{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(percentiles); err != nil {
+			return nil, fmt.Errorf("failed to decode usage percentiles: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage percentiles: %w", err)
+	}
+
+	if percentiles.CycleCount == 0 {
+		return percentiles, nil
+	}
+
+	for _, percentile := range []struct {
+		perMille int
+		value    *float64
+	}{
+		{500, &percentiles.P50},
+		{900, &percentiles.P90},
+		{990, &percentiles.P99},
+	} {
+		// ceil(p*n) in integers, as p*n in floating point can land just above
+		// a whole number
+		rank := (percentile.perMille*percentiles.CycleCount + 999) / 1000
+		if *percentile.value, err = m.rankedCycleTotal(ctx, startDate, endDate, rank); err != nil {
+			return nil, err
+		}
+	}
+
+	return percentiles, nil
+}
+
+// rankedCycleTotal is the rank-th smallest cycle total, counting from one.
+func (m *mongoUsageAnalyticsRepository) rankedCycleTotal(ctx context.Context, startDate, endDate time.Time, rank int) (float64, error) {
+	pipeline := append(cycleTotalsStages(startDate, endDate),
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "totalMb", Value: 1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$skip", Value: rank - 1}},
+		bson.D{{Key: "$limit", Value: 1}},
+	)
+
+	cursor, err := m.cycles.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate usage percentile: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var total struct {
+		TotalMB float64 `bson:"totalMb"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&total); err != nil {
+			return 0, fmt.Errorf("failed to decode usage percentile: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read usage percentile: %w", err)
+	}
+
+	return total.TotalMB, nil
+}
+
+func (m *mongoUsageAnalyticsRepository) GetUsageHistogram(ctx context.Context, startDate, endDate time.Time, buckets int) ([]*model.UsageHistogramBucket, error) {
+	pipeline := append(cycleTotalsStages(startDate, endDate),
+		bson.D{{Key: "$bucketAuto", Value: bson.M{
+			"groupBy": "$totalMb",
+			"buckets": buckets,
+			"output":  bson.M{"cycleCount": bson.M{"$sum": 1}},
+		}}},
+	)
+
+	cursor, err := m.cycles.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage histogram: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID struct {
+			Min float64 `bson:"min"`
+			Max float64 `bson:"max"`
+		} `bson:"_id"`
+		CycleCount int `bson:"cycleCount"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode usage histogram: %w", err)
+	}
+
+	histogram := make([]*model.UsageHistogramBucket, len(results))
+	for i, result := range results {
+		histogram[i] = &model.UsageHistogramBucket{
+			MinMB:      result.ID.Min,
+			MaxMB:      result.ID.Max,
+			CycleCount: result.CycleCount,
+		}
+	}
+
+	return histogram, nil
+}
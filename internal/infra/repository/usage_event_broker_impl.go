@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	subscriberBuffer    = 64
+	memoryHistoryPerMDN = 256
+	// memoryResumeWindow is how long the history of a line is kept after its
+	// last subscriber left, for clients that reconnect
+	memoryResumeWindow = 5 * time.Minute
+)
+
+// SetupUsageEventBroker picks the change stream broker when the deployment
+// supports change streams (replica sets and sharded clusters), and the
+// in-process broker otherwise.
+func SetupUsageEventBroker(db *mongo.Database, changeStreams bool) repository.UsageEventBroker {
+	if changeStreams {
+		return &changeStreamUsageEventBroker{collection: db.Collection("daily_usage")}
+	}
+	return newMemoryUsageEventBroker()
+}
+
+// changeStreamUsageEventBroker sees every write to daily_usage, including
+// those made by other replicas of the service or by batch imports. Event IDs
+// are change stream resume tokens, so any replica can resume any client.
+type changeStreamUsageEventBroker struct {
+	collection *mongo.Collection
+}
+
+func (b *changeStreamUsageEventBroker) Publish(ctx context.Context, event *model.UsageEvent) error {
+	return nil
+}
+
+func (b *changeStreamUsageEventBroker) Subscribe(ctx context.Context, mdn, lastEventID string) (<-chan *model.UsageEvent, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType":    bson.M{"$in": bson.A{"insert", "update", "replace"}},
+			"fullDocument.mdn": mdn,
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if lastEventID != "" {
+		token, err := base64.RawURLEncoding.DecodeString(lastEventID)
+		if err == nil && bson.Raw(token).Validate() == nil {
+			opts.SetResumeAfter(bson.Raw(token))
+		}
+	}
+
+	stream, err := b.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch daily usage: %w", err)
+	}
+
+	events := make(chan *model.UsageEvent, subscriberBuffer)
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change struct {
+				FullDocument *model.DailyUsage `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil || change.FullDocument == nil {
+				continue
+			}
+			event := usageEventFrom(change.FullDocument)
+			event.ID = base64.RawURLEncoding.EncodeToString(stream.ResumeToken())
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// memoryUsageEventBroker only sees usage recorded through this process. It
+// keeps a short per-MDN history so clients can resume after a reconnect, but
+// only for lines that are watched or were within the resume window, so its
+// memory follows the subscribers rather than the fleet.
+type memoryUsageEventBroker struct {
+	mu          sync.Mutex
+	sequence    uint64
+	history     map[string][]*model.UsageEvent
+	subscribers map[string]map[chan *model.UsageEvent]struct{}
+	// left holds when the last subscriber of a line left
+	left    map[string]time.Time
+	sweptAt time.Time
+}
+
+func newMemoryUsageEventBroker() *memoryUsageEventBroker {
+	return &memoryUsageEventBroker{
+		history:     make(map[string][]*model.UsageEvent),
+		subscribers: make(map[string]map[chan *model.UsageEvent]struct{}),
+		left:        make(map[string]time.Time),
+	}
+}
+
+func (b *memoryUsageEventBroker) Publish(ctx context.Context, event *model.UsageEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.sweep(now)
+
+	b.sequence++
+	published := *event
+	published.ID = strconv.FormatUint(b.sequence, 10)
+
+	if b.watched(event.MDN, now) {
+		history := append(b.history[event.MDN], &published)
+		if len(history) > memoryHistoryPerMDN {
+			history = history[len(history)-memoryHistoryPerMDN:]
+		}
+		b.history[event.MDN] = history
+	}
+
+	for subscriber := range b.subscribers[event.MDN] {
+		select {
+		case subscriber <- &published:
+		default:
+			// Too slow to keep up: disconnect it rather than block publishers
+			b.unsubscribe(event.MDN, subscriber)
+		}
+	}
+
+	return nil
+}
+
+func (b *memoryUsageEventBroker) Subscribe(ctx context.Context, mdn, lastEventID string) (<-chan *model.UsageEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan *model.UsageEvent, subscriberBuffer)
+	if last, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		for _, event := range b.history[mdn] {
+			if id, _ := strconv.ParseUint(event.ID, 10, 64); id > last && len(events) < subscriberBuffer {
+				events <- event
+			}
+		}
+	}
+
+	if b.subscribers[mdn] == nil {
+		b.subscribers[mdn] = make(map[chan *model.UsageEvent]struct{})
+	}
+	b.subscribers[mdn][events] = struct{}{}
+	delete(b.left, mdn)
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.unsubscribe(mdn, events)
+	}()
+
+	return events, nil
+}
+
+// unsubscribe must be called with the lock held. It is a no-op for channels
+// that were already removed, so the channel is closed exactly once.
+func (b *memoryUsageEventBroker) unsubscribe(mdn string, events chan *model.UsageEvent) {
+	if _, ok := b.subscribers[mdn][events]; !ok {
+		return
+	}
+	delete(b.subscribers[mdn], events)
+	if len(b.subscribers[mdn]) == 0 {
+		delete(b.subscribers, mdn)
+		b.left[mdn] = time.Now()
+	}
+	close(events)
+}
+
+// watched reports whether a line has subscribers, or had within the resume
+// window. It must be called with the lock held.
+func (b *memoryUsageEventBroker) watched(mdn string, now time.Time) bool {
+	if len(b.subscribers[mdn]) > 0 {
+		return true
+	}
+	left, ok := b.left[mdn]
+	return ok && now.Sub(left) < memoryResumeWindow
+}
+
+// sweep drops the history of lines whose last subscriber left longer ago
+// than the resume window. It runs at most once per window and must be called
+// with the lock held.
+func (b *memoryUsageEventBroker) sweep(now time.Time) {
+	if now.Sub(b.sweptAt) < memoryResumeWindow {
+		return
+	}
+	b.sweptAt = now
+
+	for mdn, left := range b.left {
+		if now.Sub(left) >= memoryResumeWindow {
+			delete(b.history, mdn)
+			delete(b.left, mdn)
+		}
+	}
+}
+
+func usageEventFrom(usage *model.DailyUsage) *model.UsageEvent {
+	return &model.UsageEvent{
+		Type:       model.UsageEventDailyUsage,
+		MDN:        usage.MDN,
+		UserID:     usage.UserID,
+		UsageDate:  usage.UsageDate,
+		DailyUsage: usage.UsedInMB,
+	}
+}
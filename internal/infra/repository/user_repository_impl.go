@@ -2,37 +2,237 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
 	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
-	ErrUserNotFound      = errors.New("user not found")
+	ErrUserNotFound      = repository.ErrUserNotFound
 	ErrUserAlreadyExists = errors.New("user with this email already exists")
 )
 
 type mongoUserRepository struct {
 	collection *mongo.Collection
+	envelope   *secrets.Envelope
 }
 
-func SetupUserRepository(db *mongo.Database) repository.UserRepository {
+// SetupUserRepository stores names and email addresses encrypted with the
+// envelope, and a blind index of the email to look users up by it.
+// Documents written before encryption are still read, and encrypted by the
+// next write or Rekey run.
+func SetupUserRepository(db *mongo.Database, envelope *secrets.Envelope) repository.UserRepository {
 	return &mongoUserRepository{
 		collection: db.Collection("users"),
+		envelope:   envelope,
+	}
+}
+
+// userDocument is a user as stored. KeyID and DataKey hold the wrapped data
+// key the personal fields are encrypted with; they are empty on documents
+// written before encryption, whose fields are in plaintext. SearchTokens is
+// missing on documents written before search, or before its current form.
+type userDocument struct {
+	ID            string     `bson:"_id,omitempty"`
+	FirstName     string     `bson:"firstName"`
+	LastName      string     `bson:"lastName"`
+	Email         string     `bson:"email"`
+	EmailIndex    string     `bson:"emailIndex,omitempty"`
+	SearchTokens  []string   `bson:"searchTokens"`
+	EmailVerified bool       `bson:"emailVerified"`
+	PendingEmail  string     `bson:"pendingEmail,omitempty"`
+	Password      string     `bson:"password"`
+	Roles         []string   `bson:"roles,omitempty"`
+	MFA           *model.MFA `bson:"mfa,omitempty"`
+	KeyID         string     `bson:"keyId,omitempty"`
+	DataKey       string     `bson:"dataKey,omitempty"`
+	CreatedAt     time.Time  `bson:"createdAt"`
+	UpdatedAt     time.Time  `bson:"updatedAt"`
+	Version       int64      `bson:"version"`
+	DeletedAt     *time.Time `bson:"deletedAt,omitempty"`
+	AnonymizedAt  *time.Time `bson:"anonymizedAt,omitempty"`
+}
+
+// seal returns the document of a user with its personal fields encrypted
+// under a new data key.
+func (m *mongoUserRepository) seal(user *model.User) (*userDocument, error) {
+	dataKey, err := m.envelope.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &userDocument{
+		ID:            user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		EmailIndex:    m.envelope.BlindIndex(normalizeEmail(user.Email)),
+		SearchTokens:  m.searchTokens(user),
+		EmailVerified: user.EmailVerified,
+		PendingEmail:  user.PendingEmail,
+		Password:      user.Password,
+		Roles:         user.Roles,
+		MFA:           user.MFA,
+		KeyID:         dataKey.KeyID,
+		DataKey:       dataKey.Wrapped,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Version:       user.Version,
+		DeletedAt:     user.DeletedAt,
+		AnonymizedAt:  user.AnonymizedAt,
+	}
+	for field, value := range personalFields(doc) {
+		if *value, err = dataKey.Encrypt(field, *value); err != nil {
+			return nil, fmt.Errorf("failed to encrypt user: %w", err)
+		}
+	}
+
+	return doc, nil
+}
+
+// sealedFields are the fields of doc that seal encrypts or derives.
+func sealedFields(doc *userDocument) bson.M {
+	return bson.M{
+		"firstName":    doc.FirstName,
+		"lastName":     doc.LastName,
+		"email":        doc.Email,
+		"emailIndex":   doc.EmailIndex,
+		"searchTokens": doc.SearchTokens,
+		"pendingEmail": doc.PendingEmail,
+		"keyId":        doc.KeyID,
+		"dataKey":      doc.DataKey,
+	}
+}
+
+// Users are searched by prefixes of at least minSearchPrefix characters, of
+// which only the first maxSearchPrefix are indexed.
+const (
+	minSearchPrefix = 3
+	maxSearchPrefix = 8
+)
+
+// searchTokens holds blind indexes of the lowercased prefixes of the user's
+// names and email, so users can be searched by prefix while only the
+// ciphertexts are stored. Equal prefixes have equal tokens, which tells how
+// many users share a prefix, but not what it is. Indexing only prefixes of
+// minSearchPrefix to maxSearchPrefix characters leaves out the one and two
+// character prefixes that are easiest to guess by their frequency, and keeps
+// whole names and addresses out of the index.
+func (m *mongoUserRepository) searchTokens(user *model.User) []string {
+	tokens := []string{}
+	seen := make(map[string]bool)
+	add := func(kind, value string) {
+		runes := []rune(strings.ToLower(value))
+		for n := minSearchPrefix; n <= len(runes) && n <= maxSearchPrefix; n++ {
+			token := m.searchToken(kind, string(runes[:n]))
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	add("name", user.FirstName)
+	add("name", user.LastName)
+	add("email", normalizeEmail(user.Email))
+	return tokens
+}
+
+// normalizeEmail is the form of an email that is indexed, so an address is
+// found, and kept unique, whatever its case.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// searchToken is the token of a prefix of the given kind of field. Longer
+// prefixes are cut to maxSearchPrefix; shorter ones than minSearchPrefix have
+// tokens that no user has.
+func (m *mongoUserRepository) searchToken(kind, prefix string) string {
+	runes := []rune(strings.ToLower(prefix))
+	if len(runes) > maxSearchPrefix {
+		runes = runes[:maxSearchPrefix]
+	}
+	return m.envelope.SearchIndex(kind + ":" + string(runes))
+}
+
+// matchesSearch tells whether user has the whole prefixes that filter
+// searches for, of which the tokens only match the first maxSearchPrefix
+// characters.
+func matchesSearch(user *model.User, filter model.UserFilter) bool {
+	hasPrefix := func(value, prefix string) bool {
+		return strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix))
+	}
+	if filter.Name != "" && !hasPrefix(user.FirstName, filter.Name) && !hasPrefix(user.LastName, filter.Name) {
+		return false
+	}
+	return filter.Email == "" || hasPrefix(normalizeEmail(user.Email), normalizeEmail(filter.Email))
+}
+
+// open returns the user stored in doc, decrypting its personal fields in
+// place.
+func (m *mongoUserRepository) open(doc *userDocument) (*model.User, error) {
+	if doc.KeyID != "" {
+		dataKey, err := m.envelope.OpenDataKey(doc.KeyID, doc.DataKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt user %s: %w", doc.ID, err)
+		}
+		for field, value := range personalFields(doc) {
+			if *value, err = dataKey.Decrypt(field, *value); err != nil {
+				return nil, fmt.Errorf("failed to decrypt user %s: %w", doc.ID, err)
+			}
+		}
+	}
+
+	return &model.User{
+		ID:            doc.ID,
+		FirstName:     doc.FirstName,
+		LastName:      doc.LastName,
+		Email:         doc.Email,
+		EmailVerified: doc.EmailVerified,
+		PendingEmail:  doc.PendingEmail,
+		Password:      doc.Password,
+		Roles:         doc.Roles,
+		MFA:           doc.MFA,
+		CreatedAt:     doc.CreatedAt,
+		UpdatedAt:     doc.UpdatedAt,
+		Version:       doc.Version,
+		DeletedAt:     doc.DeletedAt,
+		AnonymizedAt:  doc.AnonymizedAt,
+	}, nil
+}
+
+// personalFields are the encrypted fields of doc by name. The name is bound
+// to the ciphertext, so values cannot be moved between fields.
+func personalFields(doc *userDocument) map[string]*string {
+	return map[string]*string{
+		"firstName":    &doc.FirstName,
+		"lastName":     &doc.LastName,
+		"email":        &doc.Email,
+		"pendingEmail": &doc.PendingEmail,
 	}
 }
 
 func (m *mongoUserRepository) Create(ctx context.Context, user *model.User) error {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	user.Version = 1
 
-	result, err := m.collection.InsertOne(ctx, user)
+	doc, err := m.seal(user)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.collection.InsertOne(ctx, doc)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return ErrUserAlreadyExists
@@ -63,9 +263,23 @@ func (m *mongoUserRepository) Delete(ctx context.Context, id string) error {
 
 	return nil}
 
+// GetByEmail looks the email up by its blind index, or in plaintext among
+// the documents not encrypted yet. Documents indexed before emails were
+// normalized are still found by the address as given.
 func (m *mongoUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	var user model.User
-	err := m.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"emailIndex": bson.M{"$in": bson.A{
+				m.envelope.BlindIndex(normalizeEmail(email)),
+				m.envelope.BlindIndex(email),
+			}}},
+			bson.M{"email": email, "keyId": bson.M{"$exists": false}},
+		},
+		"deletedAt": nil,
+	}
+
+	var doc userDocument
+	err := m.collection.FindOne(ctx, filter).Decode(&doc)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, ErrUserNotFound
@@ -73,7 +287,140 @@ func (m *mongoUserRepository) GetByEmail(ctx context.Context, email string) (*mo
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
-	return &user, nil
+	return m.open(&doc)
+}
+
+// userCursor is the position after the last user of a page, in the order
+// of the search that returned it.
+type userCursor struct {
+	Sort       string             `json:"s"`
+	Descending bool               `json:"d,omitempty"`
+	Value      time.Time          `json:"v"`
+	ID         primitive.ObjectID `json:"id"`
+}
+
+func (c userCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(encoded string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+	var cursor userCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, repository.ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Search pages through users by their timestamp and then their ID, so that
+// users sharing a timestamp are neither skipped nor repeated.
+func (m *mongoUserRepository) Search(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	sortField := filter.Sort
+	if sortField == "" {
+		sortField = model.UserSortCreatedAt
+	}
+	if sortField != model.UserSortCreatedAt && sortField != model.UserSortUpdatedAt {
+		return nil, "", fmt.Errorf("cannot sort users by %s", sortField)
+	}
+
+	query := bson.M{"deletedAt": nil}
+	tokens := bson.A{}
+	if filter.Name != "" {
+		tokens = append(tokens, m.searchToken("name", filter.Name))
+	}
+	if filter.Email != "" {
+		tokens = append(tokens, m.searchToken("email", normalizeEmail(filter.Email)))
+	}
+	if len(tokens) > 0 {
+		query["searchTokens"] = bson.M{"$all": tokens}
+	}
+	created := bson.M{}
+	if !filter.CreatedFrom.IsZero() {
+		created["$gte"] = filter.CreatedFrom
+	}
+	if !filter.CreatedTo.IsZero() {
+		created["$lte"] = filter.CreatedTo
+	}
+	if len(created) > 0 {
+		query["createdAt"] = created
+	}
+
+	direction, after := 1, "$gt"
+	if filter.Descending {
+		direction, after = -1, "$lt"
+	}
+	if filter.After != "" {
+		cursor, err := decodeUserCursor(filter.After)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.Sort != sortField || cursor.Descending != filter.Descending {
+			return nil, "", repository.ErrInvalidCursor
+		}
+		query["$or"] = bson.A{
+			bson.M{sortField: bson.M{after: cursor.Value}},
+			bson.M{sortField: cursor.Value, "_id": bson.M{after: cursor.ID}},
+		}
+	}
+
+	// The tokens only match the first maxSearchPrefix characters, so users
+	// are read until enough of them match the whole prefixes; one more than
+	// the page tells whether there is a next one
+	opts := options.Find().SetSort(bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}})
+	if filter.Limit > 0 {
+		opts.SetBatchSize(int32(filter.Limit) + 1)
+	}
+
+	cursor, err := m.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []*model.User{}
+	var last *userDocument
+	more := false
+	for cursor.Next(ctx) {
+		var doc userDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, "", fmt.Errorf("failed to decode users: %w", err)
+		}
+		user, err := m.open(&doc)
+		if err != nil {
+			return nil, "", err
+		}
+		if !matchesSearch(user, filter) {
+			continue
+		}
+		if filter.Limit > 0 && len(users) == filter.Limit {
+			more = true
+			break
+		}
+		users = append(users, user)
+		last = &doc
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to search users: %w", err)
+	}
+
+	next := ""
+	if more {
+		lastID, err := primitive.ObjectIDFromHex(last.ID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to page users: %w", err)
+		}
+		value := last.CreatedAt
+		if sortField == model.UserSortUpdatedAt {
+			value = last.UpdatedAt
+		}
+		next = userCursor{Sort: sortField, Descending: filter.Descending, Value: value, ID: lastID}.encode()
+	}
+
+	return users, next, nil
 }
 
 func (m *mongoUserRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
@@ -82,8 +429,8 @@ func (m *mongoUserRepository) GetByID(ctx context.Context, id string) (*model.Us
 		return nil, ErrUserNotFound
 	}
 
-	var user model.User
-	err = m.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	var doc userDocument
+	err = m.collection.FindOne(ctx, live(objectID)).Decode(&doc)
 	if err != nil{
 		if err == mongo.ErrNoDocuments {
 			return nil, ErrUserNotFound
@@ -91,7 +438,7 @@ func (m *mongoUserRepository) GetByID(ctx context.Context, id string) (*model.Us
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &user, nil
+	return m.open(&doc)
 }
 
 func (m *mongoUserRepository) Update(ctx context.Context, user *model.User) error {
@@ -102,16 +449,20 @@ func (m *mongoUserRepository) Update(ctx context.Context, user *model.User) erro
 
 	user.UpdatedAt = time.Now()
 
-	update := bson.M{
-		"$set": bson.M{
-			"firstName": user.FirstName,
-			"lastName":  user.LastName,
-			"email":     user.Email,
-			"updatedAt": user.UpdatedAt,
-		},
+	// Every write seals the fields under a new data key, which also
+	// encrypts documents written before encryption
+	doc, err := m.seal(user)
+	if err != nil {
+		return err
 	}
+	set := sealedFields(doc)
+	set["emailVerified"] = user.EmailVerified
+	set["password"] = user.Password
+	set["updatedAt"] = user.UpdatedAt
+	set["version"] = user.Version + 1
+	update := bson.M{"$set": set}
 
-	result, err := m.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	result, err := m.collection.UpdateOne(ctx, liveAt(objectID, user.Version), update)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return ErrUserAlreadyExists
@@ -119,9 +470,284 @@ func (m *mongoUserRepository) Update(ctx context.Context, user *model.User) erro
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	if result.MatchedCount == 0 {
+		exists, err := m.collection.CountDocuments(ctx, live(objectID))
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		if exists > 0 {
+			return repository.ErrConcurrentModification
+		}
+		return ErrUserNotFound
+	}
+
+	user.Version++
+	return nil
+}
+
+func (m *mongoUserRepository) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"roles":     roles,
+			"updatedAt": time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := m.collection.UpdateOne(ctx, live(objectID), update)
+	if err != nil {
+		return fmt.Errorf("failed to update user roles: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (m *mongoUserRepository) SetMFA(ctx context.Context, id string, mfa *model.MFA) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	update := bson.M{"$set": bson.M{"mfa": mfa, "updatedAt": time.Now()}, "$inc": bson.M{"version": 1}}
+	if mfa == nil {
+		update = bson.M{"$unset": bson.M{"mfa": ""}, "$set": bson.M{"updatedAt": time.Now()}, "$inc": bson.M{"version": 1}}
+	}
+
+	result, err := m.collection.UpdateOne(ctx, live(objectID), update)
+	if err != nil {
+		return fmt.Errorf("failed to update user mfa: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UseTOTPStep only matches while the stored step is older, so of two logins
+// racing with the same code only one succeeds.
+func (m *mongoUserRepository) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "mfa.enabled": true, "mfa.lastStep": bson.M{"$lt": step}}
+	result, err := m.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"mfa.lastStep": step}})
+	if err != nil {
+		return fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return repository.ErrMFAFactorUsed
+	}
+
+	return nil
+}
+
+func (m *mongoUserRepository) UseRecoveryCode(ctx context.Context, id string, hash string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	filter := bson.M{"_id": objectID, "mfa.enabled": true, "mfa.recoveryCodes": hash}
+	result, err := m.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"mfa.recoveryCodes": hash}})
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return repository.ErrMFAFactorUsed
+	}
+
+	return nil
+}
+
+func (m *mongoUserRepository) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	update := bson.M{"$set": bson.M{"deletedAt": at, "updatedAt": at}, "$inc": bson.M{"version": 1}}
+	result, err := m.collection.UpdateOne(ctx, live(objectID), update)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (m *mongoUserRepository) GetDeletedBefore(ctx context.Context, before time.Time) ([]*model.User, error) {
+	filter := bson.M{"deletedAt": bson.M{"$lt": before}, "anonymizedAt": bson.M{"$exists": false}}
+	cursor, err := m.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "deletedAt", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []*userDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode deleted users: %w", err)
+	}
+
+	users := make([]*model.User, 0, len(docs))
+	for _, doc := range docs {
+		user, err := m.open(doc)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// Anonymize keeps the ID, the timestamps and nothing else. Emails are
+// unique, so each anonymized user gets its own placeholder address under the
+// reserved .invalid domain.
+func (m *mongoUserRepository) Anonymize(ctx context.Context, id string, at time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	doc, err := m.seal(&model.User{Email: id + "@deleted.invalid"})
+	if err != nil {
+		return err
+	}
+	set := sealedFields(doc)
+	delete(set, "pendingEmail")
+	set["emailVerified"] = false
+	set["anonymizedAt"] = at
+	set["updatedAt"] = at
+
+	filter := bson.M{"_id": objectID, "deletedAt": bson.M{"$ne": nil}}
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+		"$unset": bson.M{
+			"pendingEmail": "",
+			"password":     "",
+			"roles":        "",
+			"mfa":          "",
+		},
+	}
+
+	result, err := m.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
+	}
+
 	if result.MatchedCount == 0 {
 		return ErrUserNotFound
 	}
 
 	return nil
 }
+
+// Rekey wraps every data key that is not wrapped with the current key again,
+// and encrypts and indexes the documents written before encryption or
+// search tokens; the search index of earlier versions is dropped. The fields of the others stay as they are. Each document is only updated while its data
+// key is still the one that was read, so a concurrent write wins.
+func (m *mongoUserRepository) Rekey(ctx context.Context) (int, error) {
+	current := m.envelope.CurrentKeyID()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"keyId": bson.M{"$ne": current}},
+			bson.M{"searchTokens": bson.M{"$exists": false}},
+		},
+	}
+	cursor, err := m.collection.Find(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users to rekey: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rekeyed := 0
+	for cursor.Next(ctx) {
+		var doc userDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return rekeyed, fmt.Errorf("failed to decode user: %w", err)
+		}
+
+		filter, set, err := m.rekey(&doc)
+		if err != nil {
+			return rekeyed, err
+		}
+
+		update := bson.M{"$set": set, "$unset": bson.M{"searchIndex": ""}}
+		result, err := m.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return rekeyed, fmt.Errorf("failed to rekey user %s: %w", doc.ID, err)
+		}
+		rekeyed += int(result.ModifiedCount)
+	}
+	if err := cursor.Err(); err != nil {
+		return rekeyed, fmt.Errorf("failed to find users to rekey: %w", err)
+	}
+
+	return rekeyed, nil
+}
+
+// rekey returns the update that brings doc to the current key, and the
+// filter that applies it only to the version of doc that was read.
+func (m *mongoUserRepository) rekey(doc *userDocument) (bson.M, bson.M, error) {
+	objectID, err := primitive.ObjectIDFromHex(doc.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rekey user %s: %w", doc.ID, err)
+	}
+
+	if doc.KeyID == "" || doc.SearchTokens == nil {
+		filter := bson.M{"_id": objectID, "keyId": bson.M{"$exists": false}}
+		if doc.KeyID != "" {
+			filter = bson.M{"_id": objectID, "dataKey": doc.DataKey}
+		}
+		user, err := m.open(doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		sealed, err := m.seal(user)
+		if err != nil {
+			return nil, nil, err
+		}
+		return filter, sealedFields(sealed), nil
+	}
+
+	dataKey, err := m.envelope.OpenDataKey(doc.KeyID, doc.DataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rekey user %s: %w", doc.ID, err)
+	}
+	rewrapped, err := m.envelope.Rewrap(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rekey user %s: %w", doc.ID, err)
+	}
+
+	filter := bson.M{"_id": objectID, "dataKey": doc.DataKey}
+	return filter, bson.M{"keyId": rewrapped.KeyID, "dataKey": rewrapped.Wrapped}, nil
+}
+
+// live matches the user with the given ID unless it has been deleted.
+func live(objectID primitive.ObjectID) bson.M {
+	return bson.M{"_id": objectID, "deletedAt": nil}
+}
+
+// liveAt is live for the user at the given version.
+func liveAt(objectID primitive.ObjectID, version int64) bson.M {
+	return atVersion(live(objectID), version)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoUserTokenRepository relies on a TTL index on expiresAt to remove
+// tokens, used or not, once they expire.
+type mongoUserTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func SetupUserTokenRepository(db *mongo.Database) repository.UserTokenRepository {
+	return &mongoUserTokenRepository{
+		collection: db.Collection("user_tokens"),
+	}
+}
+
+func (m *mongoUserTokenRepository) Create(ctx context.Context, token *model.UserToken) error {
+	token.CreatedAt = time.Now()
+
+	result, err := m.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		token.ID = oid.Hex()
+	}
+
+	return nil
+}
+
+func (m *mongoUserTokenRepository) Consume(ctx context.Context, purpose, hash string, at time.Time) (*model.UserToken, error) {
+	filter := bson.M{
+		"hash":      hash,
+		"purpose":   purpose,
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": at},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": at}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var token model.UserToken
+	err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, repository.ErrUserTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to consume user token: %w", err)
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// atVersion narrows filter to documents at the given version, for updates
+// that must not overwrite a write they have not seen. Documents stored
+// before versioning have none, which counts as version 0.
+func atVersion(filter bson.M, version int64) bson.M {
+	filter["version"] = version
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	return filter
+}
@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+var ErrCiphertextInvalid = errors.New("ciphertext is invalid or was not encrypted with this key")
+
+// aesCipher seals secrets with AES-256-GCM. Ciphertexts are the base64 of
+// the random nonce followed by the sealed data.
+type aesCipher struct {
+	aead cipher.AEAD
+}
+
+// SetupAESCipher takes a 32-byte key.
+func SetupAESCipher(key []byte) (repository.SecretCipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesCipher{aead: aead}, nil
+}
+
+func (c *aesCipher) Encrypt(plaintext []byte) (string, error) {
+	return seal(c.aead, plaintext, nil)
+}
+
+func (c *aesCipher) Decrypt(ciphertext string) ([]byte, error) {
+	return open(c.aead, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext bound to the additional data, which has to be
+// presented again to open it.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, additionalData)), nil
+}
+
+func open(aead cipher.AEAD, ciphertext string, additionalData []byte) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, ErrCiphertextInvalid
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrCiphertextInvalid
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+// Envelope encrypts documents field by field. Each document has a data key
+// of its own, stored with it wrapped by a key-encryption key from the
+// KeyProvider, so rotating keys only rewraps data keys and leaves the fields
+// as they are.
+type Envelope struct {
+	keys repository.KeyProvider
+}
+
+func SetupEnvelope(keys repository.KeyProvider) *Envelope {
+	return &Envelope{keys: keys}
+}
+
+// DataKey encrypts the fields of one document. KeyID and Wrapped are stored
+// with the document to open the key again.
+type DataKey struct {
+	KeyID   string
+	Wrapped string
+	key     []byte
+}
+
+// NewDataKey generates a data key wrapped with the current key.
+func (e *Envelope) NewDataKey() (*DataKey, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return e.wrap(key)
+}
+
+// OpenDataKey unwraps a stored data key.
+func (e *Envelope) OpenDataKey(keyID, wrapped string) (*DataKey, error) {
+	kek, err := e.kek(keyID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := open(kek, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return &DataKey{KeyID: keyID, Wrapped: wrapped, key: key}, nil
+}
+
+// Rewrap wraps the data key with the current key, unless it already is.
+func (e *Envelope) Rewrap(dataKey *DataKey) (*DataKey, error) {
+	if dataKey.KeyID == e.keys.CurrentKeyID() {
+		return dataKey, nil
+	}
+	return e.wrap(dataKey.key)
+}
+
+func (e *Envelope) CurrentKeyID() string {
+	return e.keys.CurrentKeyID()
+}
+
+// BlindIndex is a keyed hash of value that can be stored and searched for in
+// its place: equal values have equal indexes, and without the index key an
+// index cannot be tested against guesses.
+func (e *Envelope) BlindIndex(value string) string {
+	return blindIndex(e.keys.IndexKey(), value)
+}
+
+// SearchIndex is a blind index of a search prefix. Prefixes are short and
+// shared by many users, which leaves their indexes open to frequency
+// analysis, so they have a key of their own: guessing prefixes tells nothing
+// about the email indexes, and the search key can be replaced on its own.
+func (e *Envelope) SearchIndex(value string) string {
+	return blindIndex(e.keys.SearchKey(), value)
+}
+
+func blindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// wrap binds the wrapped key to the ID of the key that wraps it.
+func (e *Envelope) wrap(key []byte) (*DataKey, error) {
+	keyID := e.keys.CurrentKeyID()
+	kek, err := e.kek(keyID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(kek, key, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &DataKey{KeyID: keyID, Wrapped: wrapped, key: key}, nil
+}
+
+func (e *Envelope) kek(keyID string) (cipher.AEAD, error) {
+	key, err := e.keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return newGCM(key)
+}
+
+// Encrypt seals the value of a field. The field name is bound to the
+// ciphertext, so values cannot be swapped between fields. Empty values are
+// left empty.
+func (k *DataKey) Encrypt(field, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	aead, err := newGCM(k.key)
+	if err != nil {
+		return "", err
+	}
+	return seal(aead, []byte(value), []byte(field))
+}
+
+func (k *DataKey) Decrypt(field, ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	aead, err := newGCM(k.key)
+	if err != nil {
+		return "", err
+	}
+	value, err := open(aead, ciphertext, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", field, err)
+	}
+	return string(value), nil
+}
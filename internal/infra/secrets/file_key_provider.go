@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+)
+
+// keyFile is the format of the key file, with keys base64-encoded:
+//
+//	{
+//	  "currentKeyId": "2024-05",
+//	  "keys": {"2024-01": "...", "2024-05": "..."},
+//	  "indexKey": "...",
+//	  "searchKey": "..."
+//	}
+type keyFile struct {
+	CurrentKeyID string            `json:"currentKeyId"`
+	Keys         map[string]string `json:"keys"`
+	IndexKey     string            `json:"indexKey"`
+	SearchKey    string            `json:"searchKey"`
+}
+
+type fileKeyProvider struct {
+	current   string
+	keys      map[string][]byte
+	indexKey  []byte
+	searchKey []byte
+}
+
+// SetupFileKeyProvider reads the keys from a local JSON file once. To rotate,
+// add a key, make it current and restart; the older key has to stay in the
+// file until the rekey-users run has rewrapped every data key.
+func SetupFileKeyProvider(path string) (repository.KeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	provider := &fileKeyProvider{
+		current: file.CurrentKeyID,
+		keys:    make(map[string][]byte, len(file.Keys)),
+	}
+	for id, encoded := range file.Keys {
+		if provider.keys[id], err = decodeKey(encoded); err != nil {
+			return nil, fmt.Errorf("key %q in key file: %w", id, err)
+		}
+	}
+	if _, ok := provider.keys[provider.current]; !ok {
+		return nil, fmt.Errorf("key file has no key for currentKeyId %q", provider.current)
+	}
+	if provider.indexKey, err = decodeKey(file.IndexKey); err != nil {
+		return nil, fmt.Errorf("indexKey in key file: %w", err)
+	}
+	if provider.searchKey, err = decodeKey(file.SearchKey); err != nil {
+		return nil, fmt.Errorf("searchKey in key file: %w", err)
+	}
+
+	return provider, nil
+}
+
+func (p *fileKeyProvider) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *fileKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", repository.ErrEncryptionKeyNotFound, id)
+	}
+	return key, nil
+}
+
+func (p *fileKeyProvider) IndexKey() []byte {
+	return p.indexKey
+}
+
+func (p *fileKeyProvider) SearchKey() []byte {
+	return p.searchKey
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("must be the base64 of a 32-byte key")
+	}
+	return key, nil
+}
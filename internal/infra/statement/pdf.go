@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pageWidth  = 612.0 // US Letter in points
+	pageHeight = 792.0
+)
+
+// pdfDocument is a deliberately small PDF 1.4 writer: text in the standard
+// Helvetica fonts, filled rectangles and lines. The standard fonts are built
+// into every PDF reader, so nothing needs to be embedded.
+type pdfDocument struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+}
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{}
+	doc.AddPage()
+	return doc
+}
+
+func (d *pdfDocument) AddPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+}
+
+func (d *pdfDocument) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.current, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(text))
+}
+
+// TextRight draws text ending at x. Widths are estimated from Helvetica's
+// digit width, which is accurate enough for right-aligned numeric columns.
+func (d *pdfDocument) TextRight(x, y, size float64, bold bool, text string) {
+	d.Text(x-float64(len(text))*size*0.556, y, size, bold, text)
+}
+
+func (d *pdfDocument) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.current, "%.3f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, y, w, h)
+}
+
+func (d *pdfDocument) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.current, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		return len(offsets)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object
+	pageIDs := make([]string, len(d.pages))
+	for i := range d.pages {
+		pageIDs[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageIDs, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.WriteTo(w)
+}
+
+// pdfString escapes a literal string and maps it onto WinAnsi, replacing
+// characters the standard fonts cannot show.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
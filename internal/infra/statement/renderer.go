@@ -0,0 +1,202 @@
+package statement
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+)
+
+const (
+	htmlTemplateName = "statement.html.tmpl"
+	pdfTemplateName  = "statement.pdf.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Renderer turns a statement into HTML or PDF. The HTML template and the PDF
+// header template can each be overridden by placing a file with the same name
+// in the configured template directory.
+type Renderer struct {
+	html *htmltemplate.Template
+	pdf  *texttemplate.Template
+}
+
+func SetupRenderer(templateDir string) (*Renderer, error) {
+	htmlSource, err := loadTemplate(templateDir, htmlTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	pdfSource, err := loadTemplate(templateDir, pdfTemplateName)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := htmltemplate.New(htmlTemplateName).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(htmlSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", htmlTemplateName, err)
+	}
+	pdf, err := texttemplate.New(pdfTemplateName).Funcs(texttemplate.FuncMap(templateFuncs)).Parse(pdfSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pdfTemplateName, err)
+	}
+
+	return &Renderer{html: html, pdf: pdf}, nil
+}
+
+func loadTemplate(templateDir, name string) (string, error) {
+	if templateDir != "" {
+		source, err := os.ReadFile(filepath.Join(templateDir, name))
+		if err == nil {
+			return string(source), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	source, err := defaultTemplates.ReadFile("templates/" + name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read default %s: %w", name, err)
+	}
+	return string(source), nil
+}
+
+func (r *Renderer) RenderHTML(w io.Writer, statement *model.Statement) error {
+	return r.html.Execute(w, statement)
+}
+
+// RenderPDF lays out the header produced by the PDF template, followed by the
+// usage chart and the daily usage table, starting new pages as needed.
+func (r *Renderer) RenderPDF(w io.Writer, statement *model.Statement) error {
+	var header bytes.Buffer
+	if err := r.pdf.Execute(&header, statement); err != nil {
+		return fmt.Errorf("failed to render statement header: %w", err)
+	}
+
+	const margin = 50.0
+	doc := newPDFDocument()
+	y := pageHeight - margin
+
+	for i, line := range strings.Split(strings.TrimRight(header.String(), "\n"), "\n") {
+		if i == 0 {
+			doc.Text(margin, y, 18, true, line)
+			y -= 28
+			continue
+		}
+		doc.Text(margin, y, 11, false, line)
+		y -= 16
+	}
+
+	if c := chart(statement); c != nil {
+		y -= 10
+		top := y
+		for _, bar := range c.Bars {
+			// Chart coordinates grow downwards like SVG; PDF grows upwards
+			doc.FillRect(margin+bar.X, top-bar.Y-bar.Height, bar.Width, bar.Height, 0.45)
+		}
+		y = top - c.Height
+		doc.Line(margin, y, margin+c.Width, y, 0.5)
+		y -= 24
+	}
+
+	const rowHeight = 16.0
+	usageColumn := margin + 220
+	tableHeader := func() {
+		doc.Text(margin, y, 11, true, "Date")
+		doc.TextRight(usageColumn, y, 11, true, "Usage")
+		y -= 6
+		doc.Line(margin, y, usageColumn, y, 0.5)
+		y -= rowHeight
+	}
+	tableHeader()
+
+	if len(statement.Usage) == 0 {
+		doc.Text(margin, y, 10, false, "No usage recorded in this cycle.")
+	}
+	for _, usage := range statement.Usage {
+		if y < margin {
+			doc.AddPage()
+			y = pageHeight - margin
+			tableHeader()
+		}
+		doc.Text(margin, y, 10, false, formatDate(usage.Date))
+		doc.TextRight(usageColumn, y, 10, false, formatMB(usage.Usage))
+		y -= rowHeight
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+type chartBar struct {
+	X, Y, Width, Height float64
+	Date                time.Time
+	Usage               float64
+}
+
+type usageChart struct {
+	Width, Height float64
+	Bars          []chartBar
+}
+
+// chart scales one bar per day of usage into a fixed 500x150 box shared by
+// the HTML (SVG) and PDF output.
+func chart(statement *model.Statement) *usageChart {
+	if len(statement.Usage) == 0 {
+		return nil
+	}
+
+	c := &usageChart{Width: 500, Height: 150}
+	slot := c.Width / float64(len(statement.Usage))
+	for i, usage := range statement.Usage {
+		height := 0.0
+		if statement.PeakUsage > 0 {
+			height = usage.Usage / statement.PeakUsage * c.Height
+		}
+		c.Bars = append(c.Bars, chartBar{
+			X:      float64(i)*slot + slot*0.1,
+			Y:      c.Height - height,
+			Width:  slot * 0.8,
+			Height: height,
+			Date:   usage.Date,
+			Usage:  usage.Usage,
+		})
+	}
+	return c
+}
+
+var templateFuncs = map[string]any{
+	"date":     formatDate,
+	"datetime": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 MST") },
+	"mb":       formatMB,
+	"chart":    chart,
+}
+
+func formatDate(value any) string {
+	switch t := value.(type) {
+	case time.Time:
+		return t.UTC().Format(time.DateOnly)
+	case *time.Time:
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.DateOnly)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func formatMB(mb float64) string {
+	return fmt.Sprintf("%.2f MB", mb)
+}
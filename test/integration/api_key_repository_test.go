@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAPIKeyRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupAPIKeyRepository(client.Database("test_db"))
+
+	key := &model.APIKey{Name: "mediation", Prefix: "pus_aaaaaaaa", Hash: "hash1", Scopes: []string{model.ScopeUsageWrite}}
+	require.NoError(t, repo.Create(ctx, key))
+	assert.NotEmpty(t, key.ID)
+
+	found, err := repo.GetByHash(ctx, "hash1")
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Nil(t, found.LastUsedAt)
+
+	usedAt := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, repo.TouchLastUsed(ctx, key.ID, usedAt))
+	// An older timestamp from a slower request does not move it back
+	require.NoError(t, repo.TouchLastUsed(ctx, key.ID, usedAt.Add(-time.Minute)))
+
+	rotated, err := repo.Rotate(ctx, key.ID, "pus_bbbbbbbb", "hash2", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "pus_bbbbbbbb", rotated.Prefix)
+	assert.Equal(t, usedAt, rotated.LastUsedAt.UTC())
+
+	_, err = repo.GetByHash(ctx, "hash1")
+	assert.ErrorIs(t, err, domainrepo.ErrAPIKeyNotFound)
+
+	revoked, err := repo.Revoke(ctx, key.ID, time.Now())
+	require.NoError(t, err)
+	assert.True(t, revoked.Revoked())
+
+	_, err = repo.Rotate(ctx, key.ID, "pus_cccccccc", "hash3", time.Now())
+	assert.ErrorIs(t, err, domainrepo.ErrAPIKeyNotFound, "revoked keys cannot be rotated")
+
+	keys, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
@@ -0,0 +1,70 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAuditRepository_Find(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupAuditRepository(client.Database("test_db"))
+
+	start := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*model.AuditEntry{
+		{At: start, Actor: "user:u3", Action: model.AuditActionUserUpdate, Target: "user:u9",
+			Before: map[string]any{"email": "john@example.com"}, After: map[string]any{"email": "jane@example.com"}},
+		{At: start.Add(time.Hour), Actor: "user:u2", Action: model.AuditActionSupportAccess, Target: "GET /api/v1/lines/5551234567/usage"},
+		{At: start.Add(2 * time.Hour), Actor: "user:u3", Action: model.AuditActionUserRoles, Target: "user:u9"},
+	}
+	for _, entry := range entries {
+		require.NoError(t, repo.Append(ctx, entry))
+		assert.NotEmpty(t, entry.ID)
+	}
+
+	found, err := repo.Find(ctx, model.AuditFilter{Target: "user:u9"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, model.AuditActionUserRoles, found[0].Action)
+	assert.Equal(t, "jane@example.com", found[1].After["email"])
+
+	found, err = repo.Find(ctx, model.AuditFilter{Actor: "user:u3", To: start.Add(time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, model.AuditActionUserUpdate, found[0].Action)
+
+	found, err = repo.Find(ctx, model.AuditFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, model.AuditActionUserRoles, found[0].Action)
+
+	// Redaction only touches the fields given, in entries about the target
+	require.NoError(t, repo.RedactFields(ctx, "user:u9", []string{"email", "firstName"}))
+	found, err = repo.Find(ctx, model.AuditFilter{Target: "user:u9"})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, model.RedactedValue, found[1].Before["email"])
+	assert.Equal(t, model.RedactedValue, found[1].After["email"])
+	assert.NotContains(t, found[1].Before, "firstName")
+	assert.Nil(t, found[0].Before)
+}
@@ -50,6 +50,7 @@ func TestCycleRepository_Create(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cycle.MDN, retrieved.MDN)
 	assert.Equal(t, cycle.UserID, retrieved.UserID)
+	assert.Equal(t, int64(1), retrieved.Version)
 }
 
 func TestCycleRepository_GetByMDN(t *testing.T) {
@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCycleSummaryRepository_ApplyUsage(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	cycleRepo := repository.SetupCycleRepository(db)
+	repo := repository.SetupCycleSummaryRepository(db)
+
+	cycle := &model.Cycle{
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+	require.NoError(t, cycleRepo.Create(ctx, cycle))
+
+	day1 := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	_, err = repo.ApplyUsage(ctx, cycle, day1, 250.5, 0, true)
+	require.NoError(t, err)
+	_, err = repo.ApplyUsage(ctx, cycle, day2, 100, 0, true)
+	require.NoError(t, err)
+	summary, err := repo.ApplyUsage(ctx, cycle, day2, 300, 100, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, cycle.ID, summary.CycleID)
+	assert.Equal(t, 550.5, summary.TotalMB)
+	assert.Equal(t, 2, summary.DayCount)
+	assert.Equal(t, 300.0, summary.PeakMB)
+	assert.Equal(t, day2, summary.PeakDate.UTC())
+
+	retrieved, err := repo.GetByCycleID(ctx, cycle.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, summary.TotalMB, retrieved.TotalMB)
+}
+
+func TestCycleSummaryRepository_Rebuild(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	cycleRepo := repository.SetupCycleRepository(db)
+	usageRepo := repository.SetupDailyUsageRepository(db)
+	repo := repository.SetupCycleSummaryRepository(db)
+
+	cycle := &model.Cycle{
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+	require.NoError(t, cycleRepo.Create(ctx, cycle))
+
+	usageRecords := []*model.DailyUsage{
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 250.5},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 320.7},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 999}, // Outside the cycle
+	}
+	for _, record := range usageRecords {
+		require.NoError(t, usageRepo.Create(ctx, record))
+	}
+
+	// Start from a drifted summary to make sure it is replaced, not added to
+	_, err = repo.ApplyUsage(ctx, cycle, time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC), 5000, 0, true)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.RebuildAll(ctx))
+
+	summary, err := repo.GetByCycleID(ctx, cycle.ID)
+	assert.NoError(t, err)
+	assert.InDelta(t, 571.2, summary.TotalMB, 0.0001)
+	assert.Equal(t, 2, summary.DayCount)
+	assert.Equal(t, 320.7, summary.PeakMB)
+	assert.Equal(t, time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), summary.PeakDate.UTC())
+}
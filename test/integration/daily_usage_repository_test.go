@@ -6,10 +6,13 @@ import (
 	"time"
 
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
 	"github.com/bowe99/phone-usage-service/internal/infra/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -30,6 +33,11 @@ func TestDailyUsageRepository_Create(t *testing.T) {
 
 	db := client.Database("test_db")
 	repo := repository.SetupDailyUsageRepository(db)
+	_, err = db.Collection("daily_usage").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "mdn", Value: 1}, {Key: "usageDate", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	require.NoError(t, err)
 
 	usage := &model.DailyUsage{
 		MDN:       "5551234567",
@@ -41,6 +49,10 @@ func TestDailyUsageRepository_Create(t *testing.T) {
 	err = repo.Create(ctx, usage)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, usage.ID)
+
+	// A second first write of the same day lost the race
+	duplicate := &model.DailyUsage{MDN: usage.MDN, UserID: usage.UserID, UsageDate: usage.UsageDate, UsedInMB: 300}
+	assert.ErrorIs(t, repo.Create(ctx, duplicate), domainrepo.ErrConcurrentModification)
 }
 
 func TestDailyUsageRepository_GetByDateRange(t *testing.T) {
@@ -134,10 +146,24 @@ func TestDailyUsageRepository_Update(t *testing.T) {
 
 	err = repo.Create(ctx, usage)
 	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Version)
 
+	stale := *usage
 	usage.UsedInMB = 275.8
 	err = repo.Update(ctx, usage)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), usage.Version)
+
+	// A write based on the version before the last one loses
+	stale.UsedInMB = 300
+	assert.ErrorIs(t, repo.Update(ctx, &stale), domainrepo.ErrConcurrentModification)
+
+	// Records stored before versioning count as version 0
+	legacy, err := db.Collection("daily_usage").InsertOne(ctx, bson.M{
+		"mdn": "5551234567", "userId": "user123", "usageDate": time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), "usedInMb": 12.5,
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Update(ctx, &model.DailyUsage{ID: legacy.InsertedID.(primitive.ObjectID).Hex(), UsedInMB: 13}))
 
 	results, err := repo.GetByDateRange(
 		ctx,
@@ -150,3 +176,154 @@ func TestDailyUsageRepository_Update(t *testing.T) {
 	assert.Len(t, results, 1)
 	assert.Equal(t, 275.8, results[0].UsedInMB)
 }
+
+func TestDailyUsageRepository_GetCycleTotals(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	repo := repository.SetupDailyUsageRepository(db)
+
+	cycles := []*model.Cycle{
+		{
+			ID:        "november",
+			MDN:       "5551234567",
+			UserID:    "user456",
+			StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			ID:        "october",
+			MDN:       "5551234567",
+			UserID:    "user123",
+			StartDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, 10, 31, 23, 59, 59, 0, time.UTC),
+		},
+	}
+
+	usageRecords := []*model.DailyUsage{
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 100},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 50},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 10, 20, 0, 0, 0, 0, time.UTC), UsedInMB: 200},
+		{MDN: "5551234567", UserID: "user456", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 300},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 999}, // Previous owner, not in November's cycle
+		{MDN: "5559999999", UserID: "user456", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 999}, // Other MDN
+	}
+	for _, record := range usageRecords {
+		require.NoError(t, repo.Create(ctx, record))
+	}
+
+	totals, err := repo.GetCycleTotals(ctx, "5551234567", cycles, 2)
+	assert.NoError(t, err)
+	assert.Len(t, totals, 2)
+
+	byCycle := map[string]*model.CycleUsageTotal{}
+	for _, total := range totals {
+		byCycle[total.CycleID] = total
+	}
+	assert.Equal(t, 350.0, byCycle["october"].TotalMB)
+	assert.Equal(t, 150.0, byCycle["october"].AlignedMB)
+	assert.Equal(t, 3, byCycle["october"].DaysWithUsage)
+	assert.Equal(t, 300.0, byCycle["november"].TotalMB)
+}
+
+func TestDailyUsageRepository_GetByCycles(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	repo := repository.SetupDailyUsageRepository(db)
+
+	cycles := []*model.Cycle{
+		{ID: "november", MDN: "5551234567", UserID: "user456", StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC)},
+		{ID: "other-line", MDN: "5559999999", UserID: "user456", StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC)},
+	}
+
+	usageRecords := []*model.DailyUsage{
+		{MDN: "5551234567", UserID: "user456", UsageDate: time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 300},
+		{MDN: "5559999999", UserID: "user456", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 20},
+		{MDN: "5551234567", UserID: "user123", UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 999}, // Previous owner
+		{MDN: "5551234567", UserID: "user456", UsageDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 999}, // Next cycle
+	}
+	for _, record := range usageRecords {
+		require.NoError(t, repo.Create(ctx, record))
+	}
+
+	records, err := repo.GetByCycles(ctx, cycles)
+	assert.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 20.0, records[0].UsedInMB) // Oldest first
+	assert.Equal(t, 300.0, records[1].UsedInMB)
+
+	records, err = repo.GetByCycles(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestDailyUsageRepository_RemoveDuplicates(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	repo := repository.SetupDailyUsageRepository(db)
+
+	// Written by a version without the unique index: two racing first writes
+	// of November 1st, and a day of another line
+	day := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	written := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	_, err = db.Collection("daily_usage").InsertMany(ctx, []interface{}{
+		bson.M{"userId": "user123", "mdn": "5551234567", "usageDate": day, "usedInMb": 300.0, "updatedAt": written.Add(time.Second), "version": 1},
+		bson.M{"userId": "user123", "mdn": "5551234567", "usageDate": day, "usedInMb": 120.0, "updatedAt": written, "version": 1},
+		bson.M{"userId": "user456", "mdn": "5559876543", "usageDate": day, "usedInMb": 80.0, "updatedAt": written, "version": 1},
+	})
+	require.NoError(t, err)
+
+	mdns, err := repo.RemoveDuplicates(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5551234567"}, mdns)
+
+	// The last write is kept, and the unique index can now be built
+	records, err := repo.GetByDateRange(ctx, "user123", "5551234567", day, day.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 300.0, records[0].UsedInMB)
+	_, err = db.Collection("daily_usage").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "mdn", Value: 1}, {Key: "usageDate", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	require.NoError(t, err)
+
+	mdns, err = repo.RemoveDuplicates(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mdns)
+}
@@ -0,0 +1,71 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestDataExportRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupDataExportRepository(client.Database("test_db"))
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	export := &model.DataExport{UserID: "u1", Status: model.DataExportRunning, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, export))
+	require.NotEmpty(t, export.ID)
+
+	// Nothing can be downloaded before the export is ready
+	_, err = repo.GetArchive(ctx, export.ID)
+	assert.ErrorIs(t, err, domainrepo.ErrDataExportNotFound)
+
+	archive := []byte("PK\x03\x04 archive")
+	require.NoError(t, repo.Complete(ctx, export.ID, archive, now, now.Add(2*time.Hour)))
+	// An export finishes once
+	assert.ErrorIs(t, repo.Fail(ctx, export.ID, "too late", now), domainrepo.ErrDataExportNotFound)
+
+	stored, err := repo.GetByID(ctx, export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.DataExportReady, stored.Status)
+	assert.Equal(t, len(archive), stored.Size)
+	assert.True(t, now.Add(2*time.Hour).Equal(stored.ExpiresAt))
+
+	downloaded, err := repo.GetArchive(ctx, export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, archive, downloaded)
+
+	failed := &model.DataExport{UserID: "u1", Status: model.DataExportRunning, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, failed))
+	require.NoError(t, repo.Fail(ctx, failed.ID, "failed to get cycles", now))
+	stored, err = repo.GetByID(ctx, failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.DataExportFailed, stored.Status)
+	assert.Equal(t, "failed to get cycles", stored.Error)
+
+	// Expired exports are gone before the TTL monitor removes them
+	expired := &model.DataExport{UserID: "u1", Status: model.DataExportRunning, CreatedAt: now, ExpiresAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, expired))
+	_, err = repo.GetByID(ctx, expired.ID)
+	assert.ErrorIs(t, err, domainrepo.ErrDataExportNotFound)
+}
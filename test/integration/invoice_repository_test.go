@@ -0,0 +1,58 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/database"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func TestInvoiceRepository_CreateIsUniquePerCycle(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	// Connect through the database package so the unique cycleId index exists
+	db, err := database.Connect(connStr, "test_db", 10*time.Second)
+	require.NoError(t, err)
+	defer db.Disconnect(ctx)
+
+	repo := repository.SetupInvoiceRepository(db.Database)
+
+	invoice := &model.Invoice{
+		CycleID:     "cycle1",
+		UserID:      "user123",
+		MDN:         "5551234567",
+		PlanID:      "basic",
+		PeriodStart: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2024, 10, 31, 23, 59, 59, 0, time.UTC),
+		Currency:    "USD",
+		Subtotal:    model.NewMoney(2500, "USD"),
+		Total:       model.NewMoney(2706, "USD"),
+	}
+	require.NoError(t, repo.Create(ctx, invoice))
+	assert.NotEmpty(t, invoice.ID)
+
+	duplicate := *invoice
+	duplicate.ID = ""
+	err = repo.Create(ctx, &duplicate)
+	assert.ErrorIs(t, err, domainrepo.ErrInvoiceAlreadyExists)
+
+	invoices, err := repo.GetByUserID(ctx, "user123")
+	assert.NoError(t, err)
+	assert.Len(t, invoices, 1)
+	assert.Equal(t, int64(2706), invoices[0].Total.Amount)
+	assert.Equal(t, "USD", invoices[0].Total.Currency)
+}
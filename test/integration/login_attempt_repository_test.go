@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestLoginAttemptRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupLoginAttemptRepository(client.Database("test_db"))
+	key := "account:john@example.com"
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	attempts, err := repo.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Zero(t, attempts.Failures)
+
+	for i := 1; i <= 3; i++ {
+		attempts, err = repo.RecordFailure(ctx, key, now, 15*time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, i, attempts.Failures)
+	}
+	assert.True(t, now.Equal(attempts.LastFailureAt))
+	assert.False(t, attempts.Locked(now))
+
+	until := now.Add(time.Hour)
+	require.NoError(t, repo.Lock(ctx, key, until))
+	attempts, err = repo.Get(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, attempts.Locked(now))
+	// The lock is kept for as long as it lasts
+	assert.True(t, until.Equal(attempts.ExpiresAt))
+
+	require.NoError(t, repo.Reset(ctx, key))
+	attempts, err = repo.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Zero(t, attempts.Failures)
+	assert.False(t, attempts.Locked(now))
+}
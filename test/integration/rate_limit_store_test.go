@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoRateLimitStore_Take(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	limit := model.RateLimit{Rate: 0.01, Burst: 3}
+
+	// Two stores on the same database behave like two replicas
+	replicaA := repository.SetupMongoRateLimitStore(db)
+	replicaB := repository.SetupMongoRateLimitStore(db)
+
+	result, err := replicaA.Take(ctx, "users:ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+
+	result, err = replicaB.Take(ctx, "users:ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, err = replicaA.Take(ctx, "users:ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = replicaB.Take(ctx, "users:ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+
+	result, err = replicaA.Take(ctx, "users:ip:10.0.0.2", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+}
@@ -0,0 +1,51 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSessionRepository_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupSessionRepository(client.Database("test_db"))
+
+	session := &model.Session{UserID: "u1", Hash: "hash1", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, session))
+	assert.NotEmpty(t, session.ID)
+
+	found, err := repo.GetByHash(ctx, "hash1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", found.UserID)
+
+	// Expired sessions are ignored even before the TTL index removes them
+	expired := &model.Session{UserID: "u1", Hash: "hash2", ExpiresAt: time.Now().Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, expired))
+	_, err = repo.GetByHash(ctx, "hash2")
+	assert.ErrorIs(t, err, domainrepo.ErrSessionNotFound)
+
+	require.NoError(t, repo.Delete(ctx, session.ID))
+	_, err = repo.GetByHash(ctx, "hash1")
+	assert.ErrorIs(t, err, domainrepo.ErrSessionNotFound)
+}
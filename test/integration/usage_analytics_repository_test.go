@@ -0,0 +1,136 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUsageAnalyticsRepository(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	usageRepo := repository.SetupDailyUsageRepository(db)
+	cycleRepo := repository.SetupCycleRepository(db)
+	repo := repository.SetupUsageAnalyticsRepository(db)
+
+	// Line i uses i*10MB on each of two days of its November cycle, so cycle
+	// totals are 20, 40, ..., 2000
+	for i := 1; i <= 100; i++ {
+		require.NoError(t, cycleRepo.Create(ctx, &model.Cycle{
+			MDN:       fmt.Sprintf("555%07d", i),
+			UserID:    "user123",
+			StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		}))
+		for day := 1; day <= 2; day++ {
+			require.NoError(t, usageRepo.Create(ctx, &model.DailyUsage{
+				MDN:       fmt.Sprintf("555%07d", i),
+				UserID:    "user123",
+				UsageDate: time.Date(2024, 11, day, 0, 0, 0, 0, time.UTC),
+				UsedInMB:  float64(i * 10),
+			}))
+		}
+	}
+	// Outside the range
+	require.NoError(t, usageRepo.Create(ctx, &model.DailyUsage{
+		MDN:       "5550000001",
+		UserID:    "user123",
+		UsageDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		UsedInMB:  100000,
+	}))
+
+	// A cycle ending in November counts in full, October days included
+	require.NoError(t, cycleRepo.Create(ctx, &model.Cycle{
+		MDN:       "5559999999",
+		UserID:    "user456",
+		StartDate: time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 14, 23, 59, 59, 0, time.UTC),
+	}))
+	require.NoError(t, usageRepo.Create(ctx, &model.DailyUsage{
+		MDN:       "5559999999",
+		UserID:    "user456",
+		UsageDate: time.Date(2024, 10, 20, 0, 0, 0, 0, time.UTC),
+		UsedInMB:  5000,
+	}))
+
+	startDate := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC)
+
+	top, err := repo.GetTopConsumers(ctx, startDate, endDate, 3)
+	assert.NoError(t, err)
+	assert.Len(t, top, 3)
+	assert.Equal(t, "5550000100", top[0].MDN)
+	assert.Equal(t, 2000.0, top[0].TotalMB)
+	assert.Equal(t, 2, top[0].DaysWithUsage)
+
+	// The p-th percentile of the 101 totals is the ceil(p*101)-th smallest
+	percentiles, err := repo.GetUsagePercentiles(ctx, startDate, endDate)
+	assert.NoError(t, err)
+	assert.Equal(t, 101, percentiles.CycleCount)
+	assert.Equal(t, 20.0, percentiles.Min)
+	assert.Equal(t, 5000.0, percentiles.Max)
+	assert.Equal(t, 1020.0, percentiles.P50)
+	assert.Equal(t, 1820.0, percentiles.P90)
+	assert.Equal(t, 2000.0, percentiles.P99)
+
+	histogram, err := repo.GetUsageHistogram(ctx, startDate, endDate, 4)
+	assert.NoError(t, err)
+	assert.Len(t, histogram, 4)
+	cycles := 0
+	for _, bucket := range histogram {
+		cycles += bucket.CycleCount
+	}
+	assert.Equal(t, 101, cycles)
+
+	// Cycles without usage total zero; the 90th percentile of three totals is
+	// the third
+	december := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+	for i, used := range []float64{0, 20, 30} {
+		mdn := fmt.Sprintf("556%07d", i)
+		require.NoError(t, cycleRepo.Create(ctx, &model.Cycle{
+			MDN:       mdn,
+			UserID:    "user789",
+			StartDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   december,
+		}))
+		if used > 0 {
+			require.NoError(t, usageRepo.Create(ctx, &model.DailyUsage{
+				MDN:       mdn,
+				UserID:    "user789",
+				UsageDate: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC),
+				UsedInMB:  used,
+			}))
+		}
+	}
+	percentiles, err = repo.GetUsagePercentiles(ctx, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), december)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, percentiles.CycleCount)
+	assert.Equal(t, 0.0, percentiles.Min)
+	assert.Equal(t, 20.0, percentiles.P50)
+	assert.Equal(t, 30.0, percentiles.P90)
+
+	empty, err := repo.GetUsagePercentiles(ctx, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.CycleCount)
+}
@@ -2,17 +2,51 @@ package integration
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
 	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// piiEnvelope encrypts with the key named current, from a key file that also
+// holds the other keys named. Keys are derived from their names, so envelopes
+// set up with the same names share them.
+func piiEnvelope(t *testing.T, current string, names ...string) *secrets.Envelope {
+	t.Helper()
+	derive := func(name string) string {
+		key := sha256.Sum256([]byte(name))
+		return base64.StdEncoding.EncodeToString(key[:])
+	}
+
+	keys := make(map[string]string, len(names))
+	for _, name := range names {
+		keys[name] = derive(name)
+	}
+	data, err := json.Marshal(map[string]any{"currentKeyId": current, "keys": keys, "indexKey": derive("index"), "searchKey": derive("search")})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	provider, err := secrets.SetupFileKeyProvider(path)
+	require.NoError(t, err)
+	return secrets.SetupEnvelope(provider)
+}
+
 func TestUserRepository_Create(t *testing.T) {
 	ctx := context.Background()
 
@@ -32,7 +66,7 @@ func TestUserRepository_Create(t *testing.T) {
 	defer client.Disconnect(ctx)
 
 	db := client.Database("test_db")
-	repo := repository.SetupUserRepository(db)
+	repo := repository.SetupUserRepository(db, piiEnvelope(t, "k1", "k1"))
 
 	// Test Create
 	user := &model.User{
@@ -68,7 +102,7 @@ func TestUserRepository_Update(t *testing.T) {
 	defer client.Disconnect(ctx)
 
 	db := client.Database("test_db")
-	repo := repository.SetupUserRepository(db)
+	repo := repository.SetupUserRepository(db, piiEnvelope(t, "k1", "k1"))
 
 	// Create user first
 	user := &model.User{
@@ -91,4 +125,328 @@ func TestUserRepository_Update(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Jane", updated.FirstName)
 	assert.Equal(t, "jane.doe@example.com", updated.Email)
+	assert.Equal(t, int64(2), updated.Version)
+
+	// A write based on the version before the last one loses
+	user.FirstName = "Joan"
+	user.Version = 1
+	assert.ErrorIs(t, repo.Update(ctx, user), domainrepo.ErrConcurrentModification)
+	require.NoError(t, repo.UpdateRoles(ctx, user.ID, []string{model.RoleSupport}))
+	updated.LastName = "Roe"
+	assert.ErrorIs(t, repo.Update(ctx, updated), domainrepo.ErrConcurrentModification)
+
+	current, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", current.FirstName)
+	assert.Equal(t, int64(3), current.Version)
+	require.NoError(t, repo.Update(ctx, current))
+	assert.Equal(t, int64(4), current.Version)
+}
+
+func TestUserRepository_MFAFactorsWorkOnce(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupUserRepository(client.Database("test_db"), piiEnvelope(t, "k1", "k1"))
+
+	user := &model.User{FirstName: "Ada", Email: "ada@example.com", Password: "hashedpassword"}
+	require.NoError(t, repo.Create(ctx, user))
+
+	// Nothing is accepted before MFA is enabled
+	assert.ErrorIs(t, repo.UseTOTPStep(ctx, user.ID, 100), domainrepo.ErrMFAFactorUsed)
+
+	require.NoError(t, repo.SetMFA(ctx, user.ID, &model.MFA{
+		Secret:        "sealed",
+		Enabled:       true,
+		RecoveryCodes: []string{"hash-a", "hash-b"},
+		LastStep:      100,
+	}))
+
+	assert.ErrorIs(t, repo.UseTOTPStep(ctx, user.ID, 100), domainrepo.ErrMFAFactorUsed)
+	assert.NoError(t, repo.UseTOTPStep(ctx, user.ID, 101))
+	assert.ErrorIs(t, repo.UseTOTPStep(ctx, user.ID, 101), domainrepo.ErrMFAFactorUsed)
+
+	assert.NoError(t, repo.UseRecoveryCode(ctx, user.ID, "hash-a"))
+	assert.ErrorIs(t, repo.UseRecoveryCode(ctx, user.ID, "hash-a"), domainrepo.ErrMFAFactorUsed)
+
+	stored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(101), stored.MFA.LastStep)
+	assert.Equal(t, []string{"hash-b"}, stored.MFA.RecoveryCodes)
+
+	require.NoError(t, repo.SetMFA(ctx, user.ID, nil))
+	stored, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.MFA)
+}
+
+func TestUserRepository_SoftDeleteAndAnonymize(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	repo := repository.SetupUserRepository(db, piiEnvelope(t, "k1", "k1"))
+	// Anonymized users share nothing that the unique email index would reject
+	_, err = db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "emailIndex", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	require.NoError(t, err)
+
+	deletedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for _, email := range []string{"ada@example.com", "bob@example.com"} {
+		user := &model.User{FirstName: "Ada", LastName: "Lovelace", Email: email, Password: "hashedpassword", Roles: []string{model.RoleCustomer}}
+		require.NoError(t, repo.Create(ctx, user))
+		require.NoError(t, repo.SoftDelete(ctx, user.ID, deletedAt))
+		ids = append(ids, user.ID)
+	}
+	kept := &model.User{FirstName: "Cy", Email: "cy@example.com", Password: "hashedpassword"}
+	require.NoError(t, repo.Create(ctx, kept))
+
+	// Deleted users are gone for every lookup, and cannot be deleted twice
+	_, err = repo.GetByID(ctx, ids[0])
+	assert.ErrorIs(t, err, domainrepo.ErrUserNotFound)
+	_, err = repo.GetByEmail(ctx, "ada@example.com")
+	assert.ErrorIs(t, err, domainrepo.ErrUserNotFound)
+	assert.ErrorIs(t, repo.SoftDelete(ctx, ids[0], deletedAt), domainrepo.ErrUserNotFound)
+	assert.ErrorIs(t, repo.Update(ctx, &model.User{ID: ids[0], Email: "ada@example.com"}), domainrepo.ErrUserNotFound)
+
+	due, err := repo.GetDeletedBefore(ctx, deletedAt)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = repo.GetDeletedBefore(ctx, deletedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, due, 2)
+
+	// Only deleted users can be anonymized
+	assert.ErrorIs(t, repo.Anonymize(ctx, kept.ID, deletedAt), domainrepo.ErrUserNotFound)
+	for _, id := range ids {
+		require.NoError(t, repo.Anonymize(ctx, id, deletedAt.Add(time.Hour)))
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(ids[0])
+	require.NoError(t, err)
+	var stored bson.M
+	require.NoError(t, db.Collection("users").FindOne(ctx, bson.M{"_id": objectID}).Decode(&stored))
+	assert.Equal(t, "", stored["firstName"])
+	assert.Equal(t, "", stored["lastName"])
+	assert.NotContains(t, stored, "password")
+	assert.NotContains(t, stored, "roles")
+	assert.Contains(t, stored, "anonymizedAt")
+
+	due, err = repo.GetDeletedBefore(ctx, deletedAt.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestUserRepository_EncryptsPersonalData(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	users := db.Collection("users")
+	_, err = users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "emailIndex", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	require.NoError(t, err)
+	repo := repository.SetupUserRepository(db, piiEnvelope(t, "k1", "k1"))
+
+	user := &model.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "hashedpassword"}
+	require.NoError(t, repo.Create(ctx, user))
+	objectID, err := primitive.ObjectIDFromHex(user.ID)
+	require.NoError(t, err)
+
+	var stored bson.M
+	require.NoError(t, users.FindOne(ctx, bson.M{"_id": objectID}).Decode(&stored))
+	assert.Equal(t, "k1", stored["keyId"])
+	assert.NotContains(t, stored, "pendingEmail")
+	for field, plaintext := range map[string]string{"firstName": "Ada", "lastName": "Lovelace", "email": "ada@example.com"} {
+		assert.NotEmpty(t, stored[field])
+		assert.NotContains(t, stored[field], plaintext)
+	}
+
+	found, err := repo.GetByEmail(ctx, "ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Lovelace", found.LastName)
+	assert.ErrorIs(t, repo.Create(ctx, &model.User{Email: "ada@example.com"}), repository.ErrUserAlreadyExists)
+	// Emails are indexed whatever their case
+	found, err = repo.GetByEmail(ctx, " Ada@Example.COM")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.ErrorIs(t, repo.Create(ctx, &model.User{Email: "ADA@example.com"}), repository.ErrUserAlreadyExists)
+
+	// Documents stored before encryption are still found
+	legacyID := primitive.NewObjectID()
+	_, err = users.InsertOne(ctx, bson.M{"_id": legacyID, "firstName": "Bob", "lastName": "Byron", "email": "bob@example.com", "password": "hashedpassword"})
+	require.NoError(t, err)
+	legacy, err := repo.GetByEmail(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, legacyID.Hex(), legacy.ID)
+
+	// After rotating, the old key is only needed until the rekey run
+	rotated := repository.SetupUserRepository(db, piiEnvelope(t, "k2", "k1", "k2"))
+	rekeyed, err := rotated.Rekey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rekeyed)
+	rekeyed, err = rotated.Rekey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, rekeyed)
+
+	require.NoError(t, users.FindOne(ctx, bson.M{"_id": legacyID}).Decode(&stored))
+	assert.Equal(t, "k2", stored["keyId"])
+	assert.NotContains(t, stored["email"], "bob@example.com")
+
+	current := repository.SetupUserRepository(db, piiEnvelope(t, "k2", "k2"))
+	for email, firstName := range map[string]string{"ada@example.com": "Ada", "bob@example.com": "Bob"} {
+		found, err := current.GetByEmail(ctx, email)
+		require.NoError(t, err)
+		assert.Equal(t, firstName, found.FirstName)
+	}
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	db := client.Database("test_db")
+	repo := repository.SetupUserRepository(db, piiEnvelope(t, "k1", "k1"))
+
+	for _, user := range []*model.User{
+		{FirstName: "Ada", LastName: "Smith", Email: "ada@example.com"},
+		{FirstName: "Bob", LastName: "Smithers", Email: "bob@example.org"},
+		{FirstName: "Smitty", LastName: "Jones", Email: "smitty@example.com"},
+		{FirstName: "Cy", LastName: "Young", Email: "cy@smith.example"},
+	} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	deleted := &model.User{FirstName: "Dee", LastName: "Smith", Email: "dee@example.com"}
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.SoftDelete(ctx, deleted.ID, time.Now()))
+
+	firstNames := func(users []*model.User) []string {
+		names := []string{}
+		for _, user := range users {
+			names = append(names, user.FirstName)
+		}
+		return names
+	}
+
+	// Prefixes of either name match regardless of case; deleted users do not
+	users, next, err := repo.Search(ctx, model.UserFilter{Name: "SMIT"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ada", "Bob", "Smitty"}, firstNames(users))
+	assert.Empty(t, next)
+
+	users, _, err = repo.Search(ctx, model.UserFilter{Name: "smith", Email: "ada"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ada"}, firstNames(users))
+
+	// Prefixes longer than the indexed ones still match in full
+	users, _, err = repo.Search(ctx, model.UserFilter{Name: "smithers"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bob"}, firstNames(users))
+	users, _, err = repo.Search(ctx, model.UserFilter{Name: "smithersen"})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Prefixes shorter than three characters are not indexed
+	users, _, err = repo.Search(ctx, model.UserFilter{Name: "sm"})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Prefixes of the domain are not prefixes of the email
+	users, _, err = repo.Search(ctx, model.UserFilter{Email: "smith"})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Pages follow each other without gaps, newest first
+	var pages [][]string
+	filter := model.UserFilter{Descending: true, Limit: 3}
+	for {
+		users, next, err := repo.Search(ctx, filter)
+		require.NoError(t, err)
+		pages = append(pages, firstNames(users))
+		if next == "" {
+			break
+		}
+		filter.After = next
+	}
+	assert.Equal(t, [][]string{{"Cy", "Smitty", "Bob"}, {"Ada"}}, pages)
+
+	// A cursor only continues the search order it came from
+	_, next, err = repo.Search(ctx, model.UserFilter{Descending: true, Limit: 1})
+	require.NoError(t, err)
+	_, _, err = repo.Search(ctx, model.UserFilter{After: next, Limit: 1})
+	assert.ErrorIs(t, err, domainrepo.ErrInvalidCursor)
+	_, _, err = repo.Search(ctx, model.UserFilter{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, domainrepo.ErrInvalidCursor)
+
+	users, _, err = repo.Search(ctx, model.UserFilter{CreatedFrom: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	// Users whose tokens match but whose names do not are read past, so
+	// pages are full and end where the last user returned does
+	for _, user := range []*model.User{
+		{FirstName: "Eve", LastName: "Smithersby", Email: "eve@example.com"},
+		{FirstName: "Fay", LastName: "Smithersen", Email: "fay@example.com"},
+		{FirstName: "Gus", LastName: "Smithersby", Email: "gus@example.com"},
+		{FirstName: "Hal", LastName: "Smithersen", Email: "hal@example.com"},
+	} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	pages = nil
+	filter = model.UserFilter{Name: "smithersen", Limit: 1}
+	for {
+		users, next, err := repo.Search(ctx, filter)
+		require.NoError(t, err)
+		pages = append(pages, firstNames(users))
+		if next == "" {
+			break
+		}
+		filter.After = next
+	}
+	assert.Equal(t, [][]string{{"Fay"}, {"Hal"}}, pages)
 }
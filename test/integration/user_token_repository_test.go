@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	domainrepo "github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestUserTokenRepository_Consume(t *testing.T) {
+	ctx := context.Background()
+
+	mongoContainer, err := mongodb.Run(ctx, "mongo:6")
+	require.NoError(t, err)
+	defer mongoContainer.Terminate(ctx)
+
+	connStr, err := mongoContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+
+	repo := repository.SetupUserTokenRepository(client.Database("test_db"))
+	now := time.Now()
+
+	token := &model.UserToken{UserID: "u1", Purpose: model.UserTokenResetPassword, Email: "john@example.com", Hash: "hash1", ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, token))
+	assert.NotEmpty(t, token.ID)
+
+	// A token only works for the purpose it was issued for
+	_, err = repo.Consume(ctx, model.UserTokenVerifyEmail, "hash1", now)
+	assert.ErrorIs(t, err, domainrepo.ErrUserTokenNotFound)
+
+	consumed, err := repo.Consume(ctx, model.UserTokenResetPassword, "hash1", now)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", consumed.UserID)
+	require.NotNil(t, consumed.UsedAt)
+
+	_, err = repo.Consume(ctx, model.UserTokenResetPassword, "hash1", now)
+	assert.ErrorIs(t, err, domainrepo.ErrUserTokenNotFound)
+
+	expired := &model.UserToken{UserID: "u1", Purpose: model.UserTokenVerifyEmail, Email: "john@example.com", Hash: "hash2", ExpiresAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, expired))
+	_, err = repo.Consume(ctx, model.UserTokenVerifyEmail, "hash2", now)
+	assert.ErrorIs(t, err, domainrepo.ErrUserTokenNotFound)
+}
@@ -0,0 +1,305 @@
+package unit
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockUserTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserTokenRepository) Create(ctx context.Context, token *model.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockUserTokenRepository) Consume(ctx context.Context, purpose, hash string, at time.Time) (*model.UserToken, error) {
+	args := m.Called(ctx, purpose, hash, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserToken), args.Error(1)
+}
+
+type MockMailSender struct {
+	mock.Mock
+}
+
+func (m *MockMailSender) Send(ctx context.Context, mail *model.Mail) error {
+	args := m.Called(ctx, mail)
+	return args.Error(0)
+}
+
+type accountFixture struct {
+	userRepo    *MockUserRepository
+	tokenRepo   *MockUserTokenRepository
+	sessionRepo *MockSessionRepository
+	mailer      *MockMailSender
+	service     *service.AccountService
+	sent        []*model.Mail
+	issued      []*model.UserToken
+}
+
+func setupAccounts() *accountFixture {
+	f := &accountFixture{
+		userRepo:    new(MockUserRepository),
+		sessionRepo: new(MockSessionRepository),
+		mailer:      new(MockMailSender),
+	}
+	f.tokenRepo = setupSingleUseTokens(&f.issued)
+	f.service = service.SetupAccountService(f.userRepo, f.tokenRepo, f.sessionRepo, f.mailer, service.AccountOptions{
+		VerificationTTL: 48 * time.Hour,
+		ResetTTL:        time.Hour,
+		LinkBaseURL:     "https://app.example.com/",
+	}, nil)
+
+	f.mailer.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+		Run(func(args mock.Arguments) { f.sent = append(f.sent, args.Get(1).(*model.Mail)) }).
+		Return(nil)
+	return f
+}
+
+var mailedToken = regexp.MustCompile(`https://app\.example\.com/[a-z-]+\?token=(\S+)`)
+
+// token returns the secret mailed in the last message.
+func (f *accountFixture) token(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, f.sent)
+	match := mailedToken.FindStringSubmatch(f.sent[len(f.sent)-1].Body)
+	require.NotNil(t, match)
+	secret, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+
+	issued := f.issued[len(f.issued)-1]
+	assert.Equal(t, sha256Hex(secret), issued.Hash)
+	return secret
+}
+
+func TestUserService_CreateUser_SendsVerification(t *testing.T) {
+	f := setupAccounts()
+	users := service.SetupUserService(f.userRepo, nil, f.service)
+
+	f.userRepo.On("GetByEmail", mock.Anything, "john@example.com").Return(nil, nil)
+	f.userRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.User")).
+		Run(func(args mock.Arguments) { args.Get(1).(*model.User).ID = "u1" }).
+		Return(nil)
+
+	created, err := users.CreateUser(context.Background(), dto.CreateUserRequest{
+		FirstName: "John", LastName: "Doe", Email: "john@example.com", Password: "password123",
+	})
+	require.NoError(t, err)
+	assert.False(t, created.EmailVerified)
+
+	require.Len(t, f.sent, 1)
+	assert.Equal(t, "john@example.com", f.sent[0].To)
+	assert.Equal(t, model.UserTokenVerifyEmail, f.issued[0].Purpose)
+	assert.Equal(t, "u1", f.issued[0].UserID)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), f.issued[0].ExpiresAt, time.Minute)
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	t.Run("tokens work once", func(t *testing.T) {
+		f := setupAccounts()
+		user := &model.User{ID: "u1", Email: "john@example.com"}
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+		f.userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool { return u.EmailVerified })).Return(nil)
+
+		require.NoError(t, f.service.SendVerification(context.Background(), user))
+		secret := f.token(t)
+
+		verified, err := f.service.VerifyEmail(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		require.NoError(t, err)
+		assert.True(t, verified.EmailVerified)
+
+		_, err = f.service.VerifyEmail(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		assert.ErrorIs(t, err, service.ErrInvalidToken)
+	})
+
+	t.Run("tokens for a previous address are rejected", func(t *testing.T) {
+		f := setupAccounts()
+		user := &model.User{ID: "u1", Email: "john@example.com"}
+		require.NoError(t, f.service.SendVerification(context.Background(), user))
+		secret := f.token(t)
+
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", Email: "jane@example.com"}, nil)
+
+		_, err := f.service.VerifyEmail(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		assert.ErrorIs(t, err, service.ErrInvalidToken)
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent changes are kept", func(t *testing.T) {
+		f := setupAccounts()
+		user := &model.User{ID: "u1", FirstName: "John", Email: "john@example.com", Version: 1}
+		require.NoError(t, f.service.SendVerification(context.Background(), user))
+		secret := f.token(t)
+
+		// The token is used up, so losing the race reads the user again
+		// rather than failing
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", FirstName: "John", Email: "john@example.com", Version: 1}, nil).Once()
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", FirstName: "Johnny", Email: "john@example.com", Version: 2}, nil)
+		f.userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool { return u.Version == 1 })).Return(repository.ErrConcurrentModification)
+		f.userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *model.User) bool { return u.Version == 2 })).Return(nil)
+
+		verified, err := f.service.VerifyEmail(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		require.NoError(t, err)
+		assert.True(t, verified.EmailVerified)
+		assert.Equal(t, "Johnny", verified.FirstName)
+		f.userRepo.AssertNumberOfCalls(t, "Update", 2)
+	})
+}
+
+func TestAccountService_ForgotPassword_IgnoresUnknownAddresses(t *testing.T) {
+	f := setupAccounts()
+	f.userRepo.On("GetByEmail", mock.Anything, "nobody@example.com").Return(nil, repository.ErrUserNotFound)
+
+	err := f.service.ForgotPassword(context.Background(), dto.EmailRequest{Email: "nobody@example.com"})
+
+	require.NoError(t, err)
+	f.service.Wait()
+	assert.Empty(t, f.sent)
+	f.tokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_SendsMailAfterReturning(t *testing.T) {
+	f := setupAccounts()
+	user := &model.User{ID: "u1", FirstName: "John", Email: "john@example.com"}
+	f.userRepo.On("GetByEmail", mock.Anything, "john@example.com").Return(user, nil)
+
+	// Known addresses return as soon as unknown ones do, however slow the
+	// token store is
+	release := make(chan struct{})
+	f.tokenRepo.ExpectedCalls = nil
+	f.tokenRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.UserToken")).
+		Run(func(args mock.Arguments) {
+			<-release
+			singleUse(f.tokenRepo, &f.issued, args.Get(1).(*model.UserToken))
+		}).
+		Return(nil)
+
+	for _, request := range []func(context.Context, dto.EmailRequest) error{f.service.ForgotPassword, f.service.ResendVerification} {
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, request(ctx, dto.EmailRequest{Email: "john@example.com"}))
+		assert.Empty(t, f.sent)
+
+		// The mail outlives the request
+		cancel()
+		release <- struct{}{}
+		f.service.Wait()
+		require.Len(t, f.sent, 1)
+		f.sent = nil
+	}
+	assert.Equal(t, model.UserTokenResetPassword, f.issued[0].Purpose)
+	assert.Equal(t, model.UserTokenVerifyEmail, f.issued[1].Purpose)
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	f := setupAccounts()
+	user := &model.User{ID: "u1", FirstName: "John", Email: "john@example.com", Password: "old-hash"}
+	f.userRepo.On("GetByEmail", mock.Anything, "john@example.com").Return(user, nil)
+	f.userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+
+	var updated *model.User
+	f.userRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(*model.User) }).
+		Return(nil)
+	f.sessionRepo.On("DeleteByUserID", mock.Anything, "u1").Return(nil)
+
+	require.NoError(t, f.service.ForgotPassword(context.Background(), dto.EmailRequest{Email: "john@example.com"}))
+	f.service.Wait()
+	assert.Equal(t, model.UserTokenResetPassword, f.issued[0].Purpose)
+	secret := f.token(t)
+
+	err := f.service.ResetPassword(context.Background(), dto.ResetPasswordRequest{Token: secret, Password: "new-password"})
+	require.NoError(t, err)
+
+	require.NotNil(t, updated)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password")))
+	assert.True(t, updated.EmailVerified)
+	f.sessionRepo.AssertCalled(t, "DeleteByUserID", mock.Anything, "u1")
+
+	err = f.service.ResetPassword(context.Background(), dto.ResetPasswordRequest{Token: secret, Password: "another-password"})
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestUserService_UpdateUserProfile_ChangesEmailSafely(t *testing.T) {
+	hash := hashPassword(t, "password123", bcrypt.MinCost)
+
+	setup := func() (*accountFixture, *service.UserService, *model.User) {
+		f := setupAccounts()
+		user := &model.User{ID: "u1", FirstName: "John", Email: "john@example.com", EmailVerified: true, Password: hash}
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+		f.userRepo.On("GetByEmail", mock.Anything, "jane@example.com").Return(nil, repository.ErrUserNotFound)
+		f.userRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+		return f, service.SetupUserService(f.userRepo, nil, f.service), user
+	}
+
+	t.Run("owners must present their current password", func(t *testing.T) {
+		f, users, _ := setup()
+		ctx := asUser("u1", model.RoleCustomer)
+
+		_, err := users.UpdateUserProfile(ctx, "u1", dto.UpdateUserRequest{Email: "jane@example.com"})
+		assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+
+		_, err = users.UpdateUserProfile(ctx, "u1", dto.UpdateUserRequest{Password: "new-password", CurrentPassword: "wrong"})
+		assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		assert.Empty(t, f.sent)
+	})
+
+	t.Run("the new address takes over once confirmed", func(t *testing.T) {
+		f, users, user := setup()
+		ctx := asUser("u1", model.RoleCustomer)
+
+		updated, err := users.UpdateUserProfile(ctx, "u1", dto.UpdateUserRequest{Email: "jane@example.com", CurrentPassword: "password123"})
+		require.NoError(t, err)
+		assert.Equal(t, "john@example.com", updated.Email)
+		assert.Equal(t, "jane@example.com", updated.PendingEmail)
+
+		require.Len(t, f.sent, 2)
+		assert.Equal(t, "jane@example.com", f.sent[0].To)
+		assert.Equal(t, "john@example.com", f.sent[1].To)
+		assert.Contains(t, f.sent[1].Body, "jane@example.com")
+		assert.Equal(t, model.UserTokenChangeEmail, f.issued[0].Purpose)
+		assert.Equal(t, "jane@example.com", f.issued[0].Email)
+
+		// The notice to the old address carries no link, so take the token from
+		// the confirmation mail
+		f.sent = f.sent[:1]
+		secret := f.token(t)
+
+		confirmed, err := f.service.ConfirmEmailChange(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		require.NoError(t, err)
+		assert.Equal(t, "jane@example.com", confirmed.Email)
+		assert.Empty(t, confirmed.PendingEmail)
+		assert.True(t, user.EmailVerified)
+
+		_, err = f.service.ConfirmEmailChange(context.Background(), dto.VerifyEmailRequest{Token: secret})
+		assert.ErrorIs(t, err, service.ErrInvalidToken)
+	})
+
+	t.Run("addresses in use are rejected", func(t *testing.T) {
+		f := setupAccounts()
+		users := service.SetupUserService(f.userRepo, nil, f.service)
+		f.userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", Email: "john@example.com", Password: string(hash)}, nil)
+		f.userRepo.On("GetByEmail", mock.Anything, "jane@example.com").Return(&model.User{ID: "u2", Email: "jane@example.com"}, nil)
+
+		_, err := users.UpdateUserProfile(asUser("u1", model.RoleCustomer), "u1", dto.UpdateUserRequest{Email: "jane@example.com", CurrentPassword: "password123"})
+		assert.ErrorIs(t, err, service.ErrEmailAlreadyExists)
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
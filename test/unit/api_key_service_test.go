@@ -0,0 +1,233 @@
+package unit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetByID(ctx context.Context, id string) (*model.APIKey, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) List(ctx context.Context) ([]*model.APIKey, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Rotate(ctx context.Context, id, prefix, hash string, at time.Time) (*model.APIKey, error) {
+	args := m.Called(ctx, id, prefix, hash, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (*model.APIKey, error) {
+	args := m.Called(ctx, id, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// setupTestAPIKey registers secret as an active key with the given scopes and
+// returns an auth service that authenticates it.
+func setupTestAPIKey(secret string, scopes ...string) *service.AuthService {
+	repo := new(MockAPIKeyRepository)
+	repo.On("GetByHash", mock.Anything, sha256Hex(secret)).Return(&model.APIKey{ID: "key1", Scopes: scopes}, nil)
+	repo.On("GetByHash", mock.Anything, mock.Anything).Return(nil, repository.ErrAPIKeyNotFound)
+	repo.On("TouchLastUsed", mock.Anything, "key1", mock.Anything).Return(nil)
+	return service.SetupAuthService(nil, nil, service.SetupAPIKeyService(repo, nil), nil, nil, time.Hour)
+}
+
+func authorized(req *http.Request, secret string) *http.Request {
+	req.Header.Set("X-API-Key", secret)
+	return req
+}
+
+func TestAPIKeyService_CreateKey_StoresOnlyTheHash(t *testing.T) {
+	repo := new(MockAPIKeyRepository)
+	svc := service.SetupAPIKeyService(repo, nil)
+
+	var stored *model.APIKey
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*model.APIKey")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*model.APIKey) }).
+		Return(nil)
+
+	issued, err := svc.CreateKey(context.Background(), dto.CreateAPIKeyRequest{Name: "mediation", Scopes: []string{model.ScopeUsageWrite}})
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Key, "pus_"))
+	assert.Equal(t, sha256Hex(issued.Key), stored.Hash)
+	assert.Equal(t, issued.Key[:12], stored.Prefix)
+	assert.NotContains(t, stored.Hash, issued.Key)
+	assert.Equal(t, []string{model.ScopeUsageWrite}, stored.Scopes)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	revokedAt := time.Now()
+	recent := time.Now().Add(-10 * time.Second)
+
+	tests := []struct {
+		name      string
+		key       *model.APIKey
+		repoErr   error
+		wantErr   error
+		wantTouch bool
+	}{
+		{name: "unknown key", repoErr: repository.ErrAPIKeyNotFound, wantErr: service.ErrInvalidAPIKey},
+		{name: "revoked key", key: &model.APIKey{ID: "key1", RevokedAt: &revokedAt}, wantErr: service.ErrInvalidAPIKey},
+		{name: "first use records last use", key: &model.APIKey{ID: "key1"}, wantTouch: true},
+		{name: "recent use is not rewritten", key: &model.APIKey{ID: "key1", LastUsedAt: &recent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockAPIKeyRepository)
+			svc := service.SetupAPIKeyService(repo, nil)
+
+			if tt.key != nil {
+				repo.On("GetByHash", mock.Anything, sha256Hex("pus_secret")).Return(tt.key, nil)
+			} else {
+				repo.On("GetByHash", mock.Anything, sha256Hex("pus_secret")).Return(nil, tt.repoErr)
+			}
+			if tt.wantTouch {
+				repo.On("TouchLastUsed", mock.Anything, "key1", mock.AnythingOfType("time.Time")).Return(nil)
+			}
+
+			key, err := svc.Authenticate(context.Background(), "pus_secret")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, key)
+			} else {
+				require.NoError(t, err)
+				assert.NotNil(t, key.LastUsedAt)
+			}
+			repo.AssertExpectations(t)
+			if !tt.wantTouch {
+				repo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_RotateKey(t *testing.T) {
+	t.Run("replaces the secret", func(t *testing.T) {
+		repo := new(MockAPIKeyRepository)
+		svc := service.SetupAPIKeyService(repo, nil)
+
+		repo.On("GetByID", mock.Anything, "key1").Return(&model.APIKey{ID: "key1", Scopes: []string{model.ScopeUsageRead}}, nil)
+		var newHash string
+		repo.On("Rotate", mock.Anything, "key1", mock.Anything, mock.Anything, mock.AnythingOfType("time.Time")).
+			Run(func(args mock.Arguments) { newHash = args.String(3) }).
+			Return(&model.APIKey{ID: "key1", Scopes: []string{model.ScopeUsageRead}}, nil)
+
+		issued, err := svc.RotateKey(context.Background(), "key1")
+
+		require.NoError(t, err)
+		assert.Equal(t, sha256Hex(issued.Key), newHash)
+		assert.Equal(t, "key1", issued.ID)
+	})
+
+	t.Run("revoked keys cannot be rotated", func(t *testing.T) {
+		repo := new(MockAPIKeyRepository)
+		svc := service.SetupAPIKeyService(repo, nil)
+		revokedAt := time.Now()
+
+		repo.On("GetByID", mock.Anything, "key1").Return(&model.APIKey{ID: "key1", RevokedAt: &revokedAt}, nil)
+
+		_, err := svc.RotateKey(context.Background(), "key1")
+
+		assert.ErrorIs(t, err, service.ErrAPIKeyRevoked)
+		repo.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRouter_EnforcesAPIKeyScopes(t *testing.T) {
+	auth := setupTestAPIKey("pus_reader", model.ScopeUsageRead)
+	r := router.SetupRouter(nil, router.Config{GinMode: gin.TestMode, Auth: auth}, router.Handlers{
+		Cycle:      handler.SetupCycleHandler(service.SetupCycleService(new(MockCycleRepository))),
+		DailyUsage: handler.SetupDailyUsageHandler(service.SetupDailyUsageService(nil, nil, nil, nil, nil, nil)),
+	})
+
+	post := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	missing := post("/api/v1/cycles/history", nil)
+	assert.Equal(t, http.StatusUnauthorized, missing.Code)
+	assert.Contains(t, missing.Header().Get("WWW-Authenticate"), "Bearer")
+
+	invalid := post("/api/v1/cycles/history", http.Header{"X-Api-Key": {"pus_wrong"}})
+	assert.Equal(t, http.StatusUnauthorized, invalid.Code)
+	assert.JSONEq(t, `{"error":"invalid api key"}`, invalid.Body.String())
+
+	// An invalid body is rejected only once the key and scope check out
+	allowed := post("/api/v1/cycles/history", http.Header{"Authorization": {"Bearer pus_reader"}})
+	assert.Equal(t, http.StatusBadRequest, allowed.Code)
+
+	legacy := post("/api/cycle/history", http.Header{"X-Api-Key": {"pus_reader"}})
+	assert.Equal(t, http.StatusBadRequest, legacy.Code)
+
+	forbidden := post("/api/v1/usage", http.Header{"X-Api-Key": {"pus_reader"}})
+	assert.Equal(t, http.StatusForbidden, forbidden.Code)
+	assert.Contains(t, forbidden.Body.String(), model.ScopeUsageWrite)
+}
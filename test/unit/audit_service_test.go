@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Append(ctx context.Context, entry *model.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) Find(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditRepository) RedactFields(ctx context.Context, target string, fields []string) error {
+	args := m.Called(ctx, target, fields)
+	return args.Error(0)
+}
+
+func TestUserService_UpdateUserProfile_RecordsRedactedDiff(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	auditRepo := new(MockAuditRepository)
+	svc := service.SetupUserService(userRepo, service.SetupAuditService(auditRepo), nil)
+
+	userRepo.On("GetByID", mock.Anything, "u9").Return(&model.User{
+		ID:        "u9",
+		FirstName: "John",
+		Email:     "john@example.com",
+		Password:  "old-hash",
+	}, nil)
+	userRepo.On("GetByEmail", mock.Anything, "jane@example.com").Return(nil, repository.ErrUserNotFound)
+	userRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+
+	var entry *model.AuditEntry
+	auditRepo.On("Append", mock.Anything, mock.AnythingOfType("*model.AuditEntry")).
+		Run(func(args mock.Arguments) { entry = args.Get(1).(*model.AuditEntry) }).
+		Return(nil)
+
+	ctx := service.WithRequestInfo(asUser("u3", model.RoleAdmin), "req-1", "203.0.113.7")
+	_, err := svc.UpdateUserProfile(ctx, "u9", dto.UpdateUserRequest{
+		FirstName: "John",
+		Email:     "jane@example.com",
+		Password:  "new-password",
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, entry)
+	assert.Equal(t, "user:u3", entry.Actor)
+	assert.Equal(t, model.AuditActionUserUpdate, entry.Action)
+	assert.Equal(t, "user:u9", entry.Target)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "203.0.113.7", entry.SourceIP)
+
+	assert.Equal(t, map[string]any{"password": model.RedactedValue}, entry.Before)
+	assert.Equal(t, map[string]any{"pendingEmail": model.RedactedValue, "password": model.RedactedValue}, entry.After)
+}
+
+func TestAuditService_Record_RedactsPersonalData(t *testing.T) {
+	auditRepo := new(MockAuditRepository)
+	audit := service.SetupAuditService(auditRepo)
+
+	var entry *model.AuditEntry
+	auditRepo.On("Append", mock.Anything, mock.AnythingOfType("*model.AuditEntry")).
+		Run(func(args mock.Arguments) { entry = args.Get(1).(*model.AuditEntry) }).
+		Return(nil)
+
+	before := &model.User{ID: "u1", FirstName: "John", LastName: "Doe", Email: "john@example.com", PendingEmail: "jd@example.com"}
+	after := &model.User{ID: "u1", FirstName: "Jane", LastName: "Roe", Email: "jd@example.com", EmailVerified: true}
+	require.NoError(t, audit.Record(context.Background(), model.AuditActionEmailChange, "user:u1", before, after))
+
+	// Names and addresses are stored encrypted, so none reach the audit log
+	// in plaintext; the entry still shows which of them changed
+	data, err := json.Marshal(entry)
+	require.NoError(t, err)
+	for _, value := range []string{"John", "Doe", "john@example.com", "jd@example.com", "Jane", "Roe"} {
+		assert.NotContains(t, string(data), value)
+	}
+	assert.Equal(t, model.RedactedValue, entry.Before["pendingEmail"])
+	assert.Equal(t, map[string]any{
+		"firstName": model.RedactedValue, "lastName": model.RedactedValue, "email": model.RedactedValue, "emailVerified": true,
+	}, entry.After)
+}
+
+func TestAuditService_ListEntries(t *testing.T) {
+	auditRepo := new(MockAuditRepository)
+	svc := service.SetupAuditService(auditRepo)
+
+	_, err := svc.ListEntries(asUser("u2", model.RoleSupport), dto.ListAuditEntriesRequest{})
+	assert.ErrorIs(t, err, service.ErrPermissionDenied)
+
+	filter := model.AuditFilter{Target: "user:u9", Limit: 100}
+	auditRepo.On("Find", mock.Anything, filter).Return([]*model.AuditEntry{{Action: model.AuditActionUserUpdate}}, nil)
+
+	entries, err := svc.ListEntries(asUser("u3", model.RoleAdmin), dto.ListAuditEntriesRequest{Target: "user:u9"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestRouter_AuditsAdminReads(t *testing.T) {
+	auditRepo := new(MockAuditRepository)
+	audit := service.SetupAuditService(auditRepo)
+
+	r := router.SetupRouter(nil, router.Config{
+		GinMode: gin.TestMode,
+		Auth:    setupTestAPIKey("pus_auditor", model.ScopeAuditRead),
+		Audit:   audit,
+	}, router.Handlers{
+		Audit: handler.SetupAuditHandler(audit),
+	})
+
+	auditRepo.On("Append", mock.Anything, mock.Anything).Return(nil)
+	auditRepo.On("Find", mock.Anything, mock.Anything).Return([]*model.AuditEntry{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit?action=user.update", nil)
+	req.Header.Set("X-API-Key", "pus_auditor")
+	req.Header.Set("X-Request-ID", "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-42", w.Header().Get("X-Request-ID"))
+	auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Actor == "apikey:key1" &&
+			entry.Action == model.AuditActionAdminRead &&
+			entry.Target == "GET /api/v1/admin/audit" &&
+			entry.RequestID == "req-42"
+	}))
+	auditRepo.AssertCalled(t, "Find", mock.Anything, model.AuditFilter{Action: model.AuditActionUserUpdate, Limit: 100})
+}
@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetByHash(ctx context.Context, hash string) (*model.Session, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func asUser(id string, roles ...string) context.Context {
+	return service.WithPrincipal(context.Background(), model.UserPrincipal(&model.User{ID: id, Roles: roles}))
+}
+
+func TestPrincipal_Can(t *testing.T) {
+	customer := model.UserPrincipal(&model.User{ID: "u1", Roles: []string{model.RoleCustomer}})
+	support := model.UserPrincipal(&model.User{ID: "u2", Roles: []string{model.RoleSupport}})
+	admin := model.UserPrincipal(&model.User{ID: "u3", Roles: []string{model.RoleAdmin}})
+	reader := model.APIKeyPrincipal(&model.APIKey{ID: "key1", Scopes: []string{model.ScopeUsageRead}})
+
+	assert.True(t, customer.Can(model.PermissionUsageRead, "u1"))
+	assert.True(t, customer.Can(model.PermissionUsersWrite, "u1"))
+	assert.False(t, customer.Can(model.PermissionUsageRead, "u9"))
+	assert.False(t, customer.Can(model.PermissionUsageWrite, "u1"))
+
+	assert.True(t, support.Can(model.PermissionUsageRead, "u9"))
+	assert.True(t, support.Can(model.PermissionUsersRead, "u9"))
+	assert.False(t, support.Can(model.PermissionUsersWrite, "u9"))
+	assert.False(t, support.Can(model.PermissionRolesAdmin, ""))
+
+	assert.True(t, admin.Can(model.PermissionRolesAdmin, ""))
+	assert.True(t, admin.Can(model.PermissionUsersWrite, "u9"))
+
+	assert.True(t, reader.Can(model.PermissionUsageRead, "u9"))
+	assert.False(t, reader.Can(model.PermissionUsersRead, "u9"))
+
+	// Keys never assign roles, whatever their scopes
+	everything := model.APIKeyPrincipal(&model.APIKey{ID: "key2", Scopes: model.Scopes})
+	assert.True(t, everything.Can(model.PermissionUsersWrite, "u9"))
+	assert.False(t, everything.Can(model.PermissionRolesAdmin, ""))
+}
+
+func TestRouter_KeysCannotSetRoles(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	r := router.SetupRouter(nil, router.Config{GinMode: gin.TestMode, Auth: setupTestAPIKey("pus_users", model.ScopeUsersAdmin)}, router.Handlers{
+		User: handler.SetupUserHandler(service.SetupUserService(userRepo, nil, nil), nil),
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/u9/roles", strings.NewReader(`{"roles":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, authorized(req, "pus_users"))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	userRepo.AssertNotCalled(t, "UpdateRoles", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Authorization(t *testing.T) {
+	t.Run("customers cannot update other users", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		svc := service.SetupUserService(repo, nil, nil)
+
+		_, err := svc.UpdateUserProfile(asUser("u1", model.RoleCustomer), "u9", dto.UpdateUserRequest{FirstName: "Eve"})
+
+		assert.ErrorIs(t, err, service.ErrPermissionDenied)
+		repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("support agents read but do not write", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		svc := service.SetupUserService(repo, nil, nil)
+		ctx := asUser("u2", model.RoleSupport)
+
+		repo.On("GetByID", mock.Anything, "u9").Return(&model.User{ID: "u9"}, nil)
+
+		_, err := svc.GetUser(ctx, "u9")
+		require.NoError(t, err)
+
+		_, err = svc.UpdateUserProfile(ctx, "u9", dto.UpdateUserRequest{FirstName: "Eve"})
+		assert.ErrorIs(t, err, service.ErrPermissionDenied)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("only admins set roles", func(t *testing.T) {
+		repo := new(MockUserRepository)
+		svc := service.SetupUserService(repo, nil, nil)
+		req := dto.SetRolesRequest{Roles: []string{model.RoleSupport}}
+
+		_, err := svc.SetRoles(asUser("u2", model.RoleSupport), "u9", req)
+		assert.ErrorIs(t, err, service.ErrPermissionDenied)
+
+		repo.On("UpdateRoles", mock.Anything, "u9", req.Roles).Return(nil)
+		repo.On("GetByID", mock.Anything, "u9").Return(&model.User{ID: "u9", Roles: req.Roles}, nil)
+
+		user, err := svc.SetRoles(asUser("u3", model.RoleAdmin), "u9", req)
+		require.NoError(t, err)
+		assert.Equal(t, req.Roles, user.Roles)
+	})
+}
+
+func TestAuthService_Login(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &model.User{ID: "u1", Email: "john@example.com", Password: string(hash), Roles: []string{model.RoleCustomer}}
+
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	svc := service.SetupAuthService(userRepo, sessionRepo, nil, nil, nil, time.Hour)
+
+	userRepo.On("GetByEmail", mock.Anything, "john@example.com").Return(user, nil)
+	userRepo.On("GetByEmail", mock.Anything, mock.Anything).Return(nil, repository.ErrUserNotFound)
+	userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+
+	var stored *model.Session
+	sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Session")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*model.Session) }).
+		Return(nil)
+
+	_, err = svc.Login(context.Background(), dto.LoginRequest{Email: "john@example.com", Password: "wrong"})
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	_, err = svc.Login(context.Background(), dto.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	issued, err := svc.Login(context.Background(), dto.LoginRequest{Email: "john@example.com", Password: "password123"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(issued.Token, "pss_"))
+	assert.Equal(t, sha256Hex(issued.Token), stored.Hash)
+	assert.Equal(t, "u1", stored.UserID)
+
+	sessionRepo.On("GetByHash", mock.Anything, stored.Hash).Return(stored, nil)
+	sessionRepo.On("GetByHash", mock.Anything, mock.Anything).Return(nil, repository.ErrSessionNotFound)
+
+	principal, err := svc.Authenticate(context.Background(), issued.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", principal.UserID)
+	assert.True(t, principal.HasRole(model.RoleCustomer))
+
+	_, err = svc.Authenticate(context.Background(), "pss_expired")
+	assert.ErrorIs(t, err, service.ErrInvalidSession)
+}
+
+func TestRouter_AuditsSupportAccess(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	auditRepo := new(MockAuditRepository)
+
+	sessions := map[string]*model.User{
+		"pss_support":  {ID: "u2", Roles: []string{model.RoleSupport}},
+		"pss_customer": {ID: "u1", Roles: []string{model.RoleCustomer}},
+	}
+	for token, user := range sessions {
+		sessionRepo.On("GetByHash", mock.Anything, sha256Hex(token)).Return(&model.Session{ID: "s-" + user.ID, UserID: user.ID}, nil)
+		userRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+	}
+
+	r := router.SetupRouter(nil, router.Config{
+		GinMode: gin.TestMode,
+		Auth:    service.SetupAuthService(userRepo, sessionRepo, nil, nil, nil, time.Hour),
+		Audit:   service.SetupAuditService(auditRepo),
+	}, router.Handlers{
+		Cycle: handler.SetupCycleHandler(service.SetupCycleService(new(MockCycleRepository))),
+	})
+
+	post := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cycles/history", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	auditRepo.On("Append", mock.Anything, mock.Anything).Return(nil).Once()
+
+	// The body is invalid, so reaching validation means the request was served
+	assert.Equal(t, http.StatusBadRequest, post("pss_support").Code)
+	auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Actor == "user:u2" &&
+			entry.Action == model.AuditActionSupportAccess &&
+			entry.Target == "POST /api/v1/cycles/history"
+	}))
+
+	assert.Equal(t, http.StatusBadRequest, post("pss_customer").Code)
+	auditRepo.AssertNumberOfCalls(t, "Append", 1)
+
+	auditRepo.On("Append", mock.Anything, mock.Anything).Return(errors.New("mongo down"))
+	assert.Equal(t, http.StatusInternalServerError, post("pss_support").Code)
+}
@@ -37,6 +37,34 @@ func (m *MockCycleRepository) GetByMDN(ctx context.Context, mdn string) ([]*mode
 	return args.Get(0).([]*model.Cycle), args.Error(1)
 }
 
+func (m *MockCycleRepository) StreamByMDN(ctx context.Context, mdn string, fn func(*model.Cycle) error) error {
+	args := m.Called(ctx, mdn, fn)
+	if cycles, ok := args.Get(0).([]*model.Cycle); ok {
+		for _, cycle := range cycles {
+			if err := fn(cycle); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockCycleRepository) GetByMDNs(ctx context.Context, mdns []string) ([]*model.Cycle, error) {
+	args := m.Called(ctx, mdns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Cycle), args.Error(1)
+}
+
+func (m *MockCycleRepository) GetByUserIDs(ctx context.Context, userIDs []string) ([]*model.Cycle, error) {
+	args := m.Called(ctx, userIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Cycle), args.Error(1)
+}
+
 func (m *MockCycleRepository) GetByUserID(ctx context.Context, userID string) ([]*model.Cycle, error) {
 	args := m.Called(ctx, userID)
 	if args.Get(0) == nil {
@@ -53,6 +81,14 @@ func (m *MockCycleRepository) GetCurrentCycle(ctx context.Context, userID, mdn s
 	return args.Get(0).(*model.Cycle), args.Error(1)
 }
 
+func (m *MockCycleRepository) GetEndedBetween(ctx context.Context, from, to time.Time) ([]*model.Cycle, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Cycle), args.Error(1)
+}
+
 func TestCycleService_GetCycleHistory(t *testing.T) {
 	mockRepo := new(MockCycleRepository)
 	cycleService := service.SetupCycleService(mockRepo)
@@ -106,3 +142,29 @@ func TestCycleService_GetCycleHistory_InvalidInput(t *testing.T) {
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "userId is required")
 }
+
+func TestCycleService_StreamCycleHistory(t *testing.T) {
+	mockRepo := new(MockCycleRepository)
+	cycleService := service.SetupCycleService(mockRepo)
+
+	req := dto.GetCycleHistoryRequest{
+		UserID: "user123",
+		MDN:    "5551234567",
+	}
+
+	cycles := []*model.Cycle{
+		{ID: "cycle1", MDN: "5551234567", UserID: "user123"},
+		{ID: "cycle2", MDN: "5551234567", UserID: "user123"},
+	}
+	mockRepo.On("StreamByMDN", mock.Anything, req.MDN, mock.Anything).Return(cycles, nil)
+
+	var streamed []string
+	err := cycleService.StreamCycleHistory(context.Background(), req, func(cycle *model.CycleResponse) error {
+		streamed = append(streamed, cycle.CycleID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cycle1", "cycle2"}, streamed)
+	mockRepo.AssertExpectations(t)
+}
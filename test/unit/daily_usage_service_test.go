@@ -2,14 +2,17 @@ package unit
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/bowe99/phone-usage-service/internal/application/dtos"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockDailyUsageRepository struct {
@@ -29,16 +32,96 @@ func (m *MockDailyUsageRepository) GetByDateRange(ctx context.Context, userID, m
 	return args.Get(0).([]*model.DailyUsage), args.Error(1)
 }
 
+func (m *MockDailyUsageRepository) StreamByDateRange(ctx context.Context, userID, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error {
+	args := m.Called(ctx, userID, mdn, startDate, endDate, fn)
+	return streamUsage(args, fn)
+}
+
+func (m *MockDailyUsageRepository) StreamByMDN(ctx context.Context, mdn string, startDate, endDate time.Time, fn func(*model.DailyUsage) error) error {
+	args := m.Called(ctx, mdn, startDate, endDate, fn)
+	return streamUsage(args, fn)
+}
+
+func streamUsage(args mock.Arguments, fn func(*model.DailyUsage) error) error {
+	if records, ok := args.Get(0).([]*model.DailyUsage); ok {
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockDailyUsageRepository) GetByCycles(ctx context.Context, cycles []*model.Cycle) ([]*model.DailyUsage, error) {
+	args := m.Called(ctx, cycles)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DailyUsage), args.Error(1)
+}
+
 func (m *MockDailyUsageRepository) Update(ctx context.Context, usage *model.DailyUsage) error {
 	args := m.Called(ctx, usage)
 	return args.Error(0)
 }
 
+func (m *MockDailyUsageRepository) GetCycleTotals(ctx context.Context, mdn string, cycles []*model.Cycle, alignDays int) ([]*model.CycleUsageTotal, error) {
+	args := m.Called(ctx, mdn, cycles, alignDays)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.CycleUsageTotal), args.Error(1)
+}
+
+func (m *MockDailyUsageRepository) RemoveDuplicates(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type MockCycleSummaryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCycleSummaryRepository) GetByCycleID(ctx context.Context, cycleID string) (*model.CycleSummary, error) {
+	args := m.Called(ctx, cycleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CycleSummary), args.Error(1)
+}
+
+func (m *MockCycleSummaryRepository) ApplyUsage(ctx context.Context, cycle *model.Cycle, usageDate time.Time, usedMB, previousMB float64, isNewDay bool) (*model.CycleSummary, error) {
+	args := m.Called(ctx, cycle, usageDate, usedMB, previousMB, isNewDay)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CycleSummary), args.Error(1)
+}
+
+func (m *MockCycleSummaryRepository) Rebuild(ctx context.Context, cycleID string) error {
+	args := m.Called(ctx, cycleID)
+	return args.Error(0)
+}
+
+func (m *MockCycleSummaryRepository) RebuildByMDN(ctx context.Context, mdn string) error {
+	args := m.Called(ctx, mdn)
+	return args.Error(0)
+}
+
+func (m *MockCycleSummaryRepository) RebuildAll(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func TestDailyUsageService_GetCurrentCycleUsage(t *testing.T) {
 	// Arrange
 	mockUsageRepo := new(MockDailyUsageRepository)
 	mockCycleRepo := new(MockCycleRepository)
-	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
 
 	req := dto.GetCurrentCycleUsageRequest{
 		UserID: "user123",
@@ -95,7 +178,7 @@ func TestDailyUsageService_GetCurrentCycleUsage_NoCycleFound(t *testing.T) {
 	// Arrange
 	mockUsageRepo := new(MockDailyUsageRepository)
 	mockCycleRepo := new(MockCycleRepository)
-	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
 
 	req := dto.GetCurrentCycleUsageRequest{
 		UserID: "user123",
@@ -119,7 +202,7 @@ func TestDailyUsageService_GetCurrentCycleUsage_NoCycleFound(t *testing.T) {
 func TestDailyUsageService_GetCurrentCycleUsage_InvalidInput(t *testing.T) {
 	mockUsageRepo := new(MockDailyUsageRepository)
 	mockCycleRepo := new(MockCycleRepository)
-	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
 
 	// Test missing userId
 	req := dto.GetCurrentCycleUsageRequest{
@@ -133,3 +216,348 @@ func TestDailyUsageService_GetCurrentCycleUsage_InvalidInput(t *testing.T) {
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "userId is required")
 }
+
+func TestDailyUsageService_GetUsageTrends(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
+
+	// Current cycle started 4 days ago, so 5 days (including today) have elapsed
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	currentStart := today.AddDate(0, 0, -4)
+	cycles := []*model.Cycle{
+		{ID: "upcoming", MDN: "5551234567", UserID: "user123", StartDate: today.AddDate(0, 1, 0), EndDate: today.AddDate(0, 2, 0)},
+		{ID: "current", MDN: "5551234567", UserID: "user123", StartDate: currentStart, EndDate: currentStart.AddDate(0, 0, 30).Add(-time.Second)},
+		{ID: "previous", MDN: "5551234567", UserID: "user123", StartDate: currentStart.AddDate(0, 0, -30), EndDate: currentStart.Add(-time.Second)},
+		{ID: "oldest", MDN: "5551234567", UserID: "user456", StartDate: currentStart.AddDate(0, 0, -60), EndDate: currentStart.AddDate(0, 0, -30).Add(-time.Second)},
+	}
+
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return(cycles, nil)
+	mockUsageRepo.On("GetCycleTotals", mock.Anything, "5551234567", cycles[1:3], 5).
+		Return([]*model.CycleUsageTotal{
+			{CycleID: "current", TotalMB: 600, DaysWithUsage: 5, AlignedMB: 600},
+			{CycleID: "previous", TotalMB: 3000, DaysWithUsage: 30, AlignedMB: 500},
+		}, nil)
+
+	result, err := usageService.GetUsageTrends(context.Background(), dto.GetUsageTrendsRequest{MDN: "5551234567", Cycles: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, result.AlignedDays)
+	assert.Len(t, result.Cycles, 2)
+
+	current := result.Cycles[0]
+	assert.Equal(t, "current", current.CycleID)
+	assert.True(t, current.Partial)
+	assert.Equal(t, 120.0, current.AverageDailyUsage)
+	// Compared against the first 5 days of the previous cycle, not its full total
+	assert.Equal(t, 100.0, *current.Delta)
+	assert.Equal(t, 20.0, *current.PercentChange)
+
+	previous := result.Cycles[1]
+	assert.False(t, previous.Partial)
+	assert.Equal(t, 100.0, previous.AverageDailyUsage)
+	assert.Nil(t, previous.Delta)
+	mockCycleRepo.AssertExpectations(t)
+	mockUsageRepo.AssertExpectations(t)
+}
+
+func TestDailyUsageService_GetUsageTrends_NoCycles(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
+
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{}, nil)
+
+	result, err := usageService.GetUsageTrends(context.Background(), dto.GetUsageTrendsRequest{MDN: "5551234567"})
+
+	assert.ErrorIs(t, err, service.ErrNoCyclesFound)
+	assert.Nil(t, result)
+	mockUsageRepo.AssertNotCalled(t, "GetCycleTotals")
+}
+
+func TestDailyUsageService_RecordUsage_NewDay(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockSummaryRepo := new(MockCycleSummaryRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockBroker := new(MockUsageEventBroker)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, mockSummaryRepo, mockInvoiceRepo, mockBroker, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, mock.Anything).Return(nil, repository.ErrInvoiceNotFound)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 180.3
+	req := dto.RecordUsageRequest{
+		UserID:    "user123",
+		MDN:       "5551234567",
+		UsageDate: usageDate.Add(15 * time.Hour), // Normalized to the start of the day
+		UsedInMB:  &usedInMB,
+	}
+
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{}, nil)
+	mockUsageRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.DailyUsage")).Return(nil)
+	mockSummaryRepo.On("ApplyUsage", mock.Anything, cycle, usageDate, 180.3, 0.0, true).
+		Return(&model.CycleSummary{CycleID: "cycle1", TotalMB: 180.3, PeakDate: usageDate, PeakMB: 180.3, DayCount: 1}, nil)
+	mockBroker.On("Publish", mock.Anything, mock.MatchedBy(func(event *model.UsageEvent) bool {
+		return event.Type == model.UsageEventDailyUsage && event.UsageDate.Equal(usageDate) && event.DailyUsage == 180.3
+	})).Return(nil)
+
+	result, err := usageService.RecordUsage(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, usageDate, result.Date)
+	assert.Equal(t, 180.3, result.Usage)
+	mockUsageRepo.AssertExpectations(t)
+	mockSummaryRepo.AssertExpectations(t)
+	mockSummaryRepo.AssertNotCalled(t, "Rebuild", mock.Anything, mock.Anything)
+	mockBroker.AssertExpectations(t)
+}
+
+func TestDailyUsageService_RecordUsage_LoweringPeakRebuildsSummary(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockSummaryRepo := new(MockCycleSummaryRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockBroker := new(MockUsageEventBroker)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, mockSummaryRepo, mockInvoiceRepo, mockBroker, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, mock.Anything).Return(nil, repository.ErrInvoiceNotFound)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 10.0
+	req := dto.RecordUsageRequest{
+		UserID:    "user123",
+		MDN:       "5551234567",
+		UsageDate: usageDate,
+		UsedInMB:  &usedInMB,
+	}
+
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123"}
+	existing := &model.DailyUsage{ID: "usage1", MDN: "5551234567", UserID: "user123", UsageDate: usageDate, UsedInMB: 500}
+
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{existing}, nil)
+	mockUsageRepo.On("Update", mock.Anything, existing).Return(nil)
+	mockSummaryRepo.On("ApplyUsage", mock.Anything, cycle, usageDate, 10.0, 500.0, false).
+		Return(&model.CycleSummary{CycleID: "cycle1", PeakDate: usageDate, PeakMB: 500}, nil)
+	mockSummaryRepo.On("Rebuild", mock.Anything, "cycle1").Return(nil)
+	mockBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := usageService.RecordUsage(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, existing.UsedInMB)
+	mockUsageRepo.AssertExpectations(t)
+	mockSummaryRepo.AssertExpectations(t)
+}
+
+func TestDailyUsageService_RecordUsage_ReplayAfterFailedSummaryUpdate(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockSummaryRepo := new(MockCycleSummaryRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockBroker := new(MockUsageEventBroker)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, mockSummaryRepo, mockInvoiceRepo, mockBroker, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, mock.Anything).Return(nil, repository.ErrInvoiceNotFound)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 180.3
+	req := dto.RecordUsageRequest{
+		UserID:    "user123",
+		MDN:       "5551234567",
+		UsageDate: usageDate,
+		UsedInMB:  &usedInMB,
+	}
+
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123"}
+	var stored *model.DailyUsage
+
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{}, nil).Once()
+	mockUsageRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.DailyUsage")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*model.DailyUsage) }).
+		Return(nil)
+	mockSummaryRepo.On("ApplyUsage", mock.Anything, cycle, usageDate, 180.3, 0.0, true).
+		Return(nil, errors.New("connection reset"))
+	mockSummaryRepo.On("Rebuild", mock.Anything, "cycle1").Return(errors.New("connection reset")).Once()
+
+	// The usage is stored, but neither summary write gets through
+	_, err := usageService.RecordUsage(context.Background(), req)
+	assert.Error(t, err)
+	require.NotNil(t, stored)
+
+	// The client sends the same request again, which finds its own record
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{stored}, nil)
+	mockUsageRepo.On("Update", mock.Anything, stored).Return(nil)
+	mockSummaryRepo.On("Rebuild", mock.Anything, "cycle1").Return(nil).Once()
+	mockBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := usageService.RecordUsage(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, 180.3, result.Usage)
+	// The replay rebuilds the summary instead of applying a zero change
+	mockSummaryRepo.AssertNumberOfCalls(t, "ApplyUsage", 1)
+	mockSummaryRepo.AssertNumberOfCalls(t, "Rebuild", 2)
+}
+
+func TestDailyUsageService_RecordUsage_RetriesConcurrentCorrections(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockSummaryRepo := new(MockCycleSummaryRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockBroker := new(MockUsageEventBroker)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, mockSummaryRepo, mockInvoiceRepo, mockBroker, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, mock.Anything).Return(nil, repository.ErrInvoiceNotFound)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 300.0
+	req := dto.RecordUsageRequest{
+		UserID:    "user123",
+		MDN:       "5551234567",
+		UsageDate: usageDate,
+		UsedInMB:  &usedInMB,
+	}
+
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123"}
+	stale := &model.DailyUsage{ID: "usage1", MDN: "5551234567", UserID: "user123", UsageDate: usageDate, UsedInMB: 100, Version: 1}
+	current := &model.DailyUsage{ID: "usage1", MDN: "5551234567", UserID: "user123", UsageDate: usageDate, UsedInMB: 250, Version: 2}
+
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{stale}, nil).Once()
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{current}, nil)
+	mockUsageRepo.On("Update", mock.Anything, stale).Return(repository.ErrConcurrentModification)
+	mockUsageRepo.On("Update", mock.Anything, current).Return(nil)
+	// The summary moves by the difference to the value that was replaced,
+	// not the one first read
+	mockSummaryRepo.On("ApplyUsage", mock.Anything, cycle, usageDate, 300.0, 250.0, false).
+		Return(&model.CycleSummary{CycleID: "cycle1", PeakDate: usageDate, PeakMB: 300}, nil)
+	mockBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	result, err := usageService.RecordUsage(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.Equal(t, 300.0, result.Usage)
+	mockUsageRepo.AssertNumberOfCalls(t, "Update", 2)
+	mockSummaryRepo.AssertExpectations(t)
+
+	// Writers that keep losing get the conflict
+	mockUsageRepo.ExpectedCalls = nil
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{stale}, nil)
+	mockUsageRepo.On("Update", mock.Anything, stale).Return(repository.ErrConcurrentModification)
+
+	_, err = usageService.RecordUsage(context.Background(), req)
+
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+	mockSummaryRepo.AssertNumberOfCalls(t, "ApplyUsage", 1)
+}
+
+func TestDailyUsageService_RecordUsage_ConcurrentFirstWrites(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockSummaryRepo := new(MockCycleSummaryRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockBroker := new(MockUsageEventBroker)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, mockSummaryRepo, mockInvoiceRepo, mockBroker, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, mock.Anything).Return(nil, repository.ErrInvoiceNotFound)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 300.0
+	req := dto.RecordUsageRequest{UserID: "user123", MDN: "5551234567", UsageDate: usageDate, UsedInMB: &usedInMB}
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123"}
+	// Another writer recorded the day between the read and the insert
+	other := &model.DailyUsage{ID: "usage1", MDN: "5551234567", UserID: "user123", UsageDate: usageDate, UsedInMB: 120, Version: 1}
+
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{}, nil).Once()
+	mockUsageRepo.On("GetByDateRange", mock.Anything, req.UserID, req.MDN, usageDate, mock.AnythingOfType("time.Time")).
+		Return([]*model.DailyUsage{other}, nil)
+	mockUsageRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.DailyUsage")).Return(repository.ErrConcurrentModification)
+	mockUsageRepo.On("Update", mock.Anything, other).Return(nil)
+	// The day is counted once, as a correction of the other write
+	mockSummaryRepo.On("ApplyUsage", mock.Anything, cycle, usageDate, 300.0, 120.0, false).
+		Return(&model.CycleSummary{CycleID: "cycle1", PeakDate: usageDate, PeakMB: 300}, nil)
+	mockBroker.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	_, err := usageService.RecordUsage(context.Background(), req)
+
+	require.NoError(t, err)
+	mockUsageRepo.AssertExpectations(t)
+	mockSummaryRepo.AssertExpectations(t)
+}
+
+func TestDailyUsageService_RecordUsage_InvoicedCycle(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), mockInvoiceRepo, new(MockUsageEventBroker), nil)
+
+	usageDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usedInMB := 90.0
+	req := dto.RecordUsageRequest{
+		UserID:    "user123",
+		MDN:       "5551234567",
+		UsageDate: usageDate,
+		UsedInMB:  &usedInMB,
+	}
+
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+	mockCycleRepo.On("GetCurrentCycle", mock.Anything, req.UserID, req.MDN, usageDate).Return(cycle, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, "cycle1").Return(&model.Invoice{ID: "inv1", CycleID: "cycle1"}, nil)
+
+	_, err := usageService.RecordUsage(context.Background(), req)
+
+	assert.ErrorIs(t, err, service.ErrCycleInvoiced)
+	mockUsageRepo.AssertNotCalled(t, "GetByDateRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockUsageRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockUsageRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestDailyUsageService_RecycledNumbersHidePreviousOwners(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	usageService := service.SetupDailyUsageService(mockUsageRepo, mockCycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil)
+
+	now := time.Now().UTC().Truncate(24 * time.Hour)
+	mine := &model.Cycle{ID: "c2", MDN: "5551234567", UserID: "user123", StartDate: now.AddDate(0, 0, -5), EndDate: now.AddDate(0, 0, 25)}
+	previous := &model.Cycle{ID: "c1", MDN: "5551234567", UserID: "user000", StartDate: now.AddDate(0, -1, -5), EndDate: now.AddDate(0, 0, -6)}
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{mine, previous}, nil)
+	ctx := asUser("user123", model.RoleCustomer)
+
+	// Exports only cover the caller's own usage of the line
+	mockUsageRepo.On("StreamByDateRange", mock.Anything, "user123", "5551234567", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	require.NoError(t, usageService.StreamUsage(ctx, "5551234567", dto.UsageDateRange{}, func(*model.DailyUsage) error { return nil }))
+	mockUsageRepo.AssertNotCalled(t, "StreamByMDN", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Trends only compare the caller's own cycles
+	mockUsageRepo.On("GetCycleTotals", mock.Anything, "5551234567", []*model.Cycle{mine}, mock.Anything).Return([]*model.CycleUsageTotal{}, nil)
+	trends, err := usageService.GetUsageTrends(ctx, dto.GetUsageTrendsRequest{MDN: "5551234567"})
+	require.NoError(t, err)
+	require.Len(t, trends.Cycles, 1)
+	assert.Equal(t, "c2", trends.Cycles[0].CycleID)
+
+	// Callers who never held the line are denied
+	err = usageService.StreamUsage(asUser("user999", model.RoleCustomer), "5551234567", dto.UsageDateRange{}, func(*model.DailyUsage) error { return nil })
+	assert.ErrorIs(t, err, service.ErrPermissionDenied)
+}
@@ -0,0 +1,223 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDataExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockDataExportRepository) Create(ctx context.Context, export *model.DataExport) error {
+	args := m.Called(ctx, export)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetByID(ctx context.Context, id string) (*model.DataExport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DataExport), args.Error(1)
+}
+
+func (m *MockDataExportRepository) Complete(ctx context.Context, id string, archive []byte, at, expiresAt time.Time) error {
+	args := m.Called(ctx, id, archive, at, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) Fail(ctx context.Context, id, reason string, at time.Time) error {
+	args := m.Called(ctx, id, reason, at)
+	return args.Error(0)
+}
+
+func (m *MockDataExportRepository) GetArchive(ctx context.Context, id string) ([]byte, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+type dataExportFixture struct {
+	exportRepo  *MockDataExportRepository
+	userRepo    *MockUserRepository
+	cycleRepo   *MockCycleRepository
+	usageRepo   *MockDailyUsageRepository
+	invoiceRepo *MockInvoiceRepository
+	auditRepo   *MockAuditRepository
+	clock       *fakeClock
+	service     *service.DataExportService
+}
+
+func setupDataExport() *dataExportFixture {
+	audit, auditRepo := setupAcceptingAudit()
+	f := &dataExportFixture{
+		exportRepo:  new(MockDataExportRepository),
+		userRepo:    new(MockUserRepository),
+		cycleRepo:   new(MockCycleRepository),
+		usageRepo:   new(MockDailyUsageRepository),
+		invoiceRepo: new(MockInvoiceRepository),
+		auditRepo:   auditRepo,
+		clock:       newFakeClock(),
+	}
+	f.service = service.SetupDataExportService(f.exportRepo, f.userRepo, f.cycleRepo, f.usageRepo, f.invoiceRepo, f.auditRepo,
+		export.SetupZIPArchiver(), service.DataExportOptions{
+			TTL:     72 * time.Hour,
+			Timeout: 10 * time.Minute,
+			Clock:   f.clock,
+		}, audit)
+
+	f.userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{
+		ID: "u1", FirstName: "John", LastName: "Doe", Email: "john@example.com", Roles: []string{model.RoleCustomer},
+	}, nil)
+	return f
+}
+
+// readArchive returns the entries of a ZIP archive by name.
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	entries := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		entries[file.Name] = string(data)
+	}
+	return entries
+}
+
+func TestDataExportService_RequestExport(t *testing.T) {
+	f := setupDataExport()
+	cycles := []*model.Cycle{
+		{ID: "c1", MDN: "5551234567", UserID: "u1", StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{ID: "c2", MDN: "5559876543", UserID: "u1", StartDate: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	f.cycleRepo.On("GetByUserID", mock.Anything, "u1").Return(cycles, nil)
+	f.usageRepo.On("GetByCycles", mock.Anything, cycles).Return([]*model.DailyUsage{
+		{MDN: "5551234567", UserID: "u1", UsageDate: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 120.5},
+		{MDN: "5559876543", UserID: "u1", UsageDate: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 80},
+	}, nil)
+	f.invoiceRepo.On("GetByUserID", mock.Anything, "u1").Return([]*model.Invoice{{
+		ID: "i1", CycleID: "c1", UserID: "u1", MDN: "5551234567", Currency: "USD",
+		Total:     model.NewMoney(2500, "USD"),
+		LineItems: []model.InvoiceLineItem{{Type: model.LineItemBaseFee, Description: "Base fee", Quantity: 1, Amount: model.NewMoney(2500, "USD")}},
+	}}, nil)
+	// The user's own change shows up in both queries but is exported once
+	update := &model.AuditEntry{ID: "a1", Actor: "user:u1", Action: model.AuditActionUserUpdate, Target: "user:u1",
+		After: map[string]any{"firstName": "John"}, At: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}
+	access := &model.AuditEntry{ID: "a2", Actor: "user:u2", Action: model.AuditActionSupportAccess, Target: "user:u1",
+		At: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)}
+	f.auditRepo.On("Find", mock.Anything, model.AuditFilter{Target: "user:u1"}).Return([]*model.AuditEntry{access, update}, nil)
+	f.auditRepo.On("Find", mock.Anything, model.AuditFilter{Actor: "user:u1"}).Return([]*model.AuditEntry{update}, nil)
+
+	f.exportRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.DataExport")).
+		Run(func(args mock.Arguments) { args.Get(1).(*model.DataExport).ID = "e1" }).
+		Return(nil)
+	var archive []byte
+	f.exportRepo.On("Complete", mock.Anything, "e1", mock.Anything, f.clock.now, f.clock.now.Add(72*time.Hour)).
+		Run(func(args mock.Arguments) { archive = args.Get(2).([]byte) }).
+		Return(nil)
+
+	_, err := f.service.RequestExport(asUser("u2", model.RoleCustomer), "u1")
+	assert.ErrorIs(t, err, service.ErrPermissionDenied)
+
+	dataExport, err := f.service.RequestExport(asUser("u1", model.RoleCustomer), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "e1", dataExport.ID)
+	assert.Equal(t, model.DataExportRunning, dataExport.Status)
+	f.service.Wait()
+
+	f.exportRepo.AssertExpectations(t)
+	f.exportRepo.AssertNotCalled(t, "Fail", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	f.auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionDataExport && entry.Actor == "user:u1" && entry.Target == "user:u1"
+	}))
+
+	entries := readArchive(t, archive)
+	for _, name := range []string{"profile", "cycles", "daily_usage", "invoices", "invoice_line_items", "audit_log"} {
+		assert.Contains(t, entries, name+".json")
+		assert.Contains(t, entries, name+".csv")
+	}
+	assert.Contains(t, entries["profile.csv"], "u1,John,Doe,john@example.com,false,,customer,false,")
+
+	var usage []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(entries["daily_usage.json"]), &usage))
+	require.Len(t, usage, 2)
+	assert.Equal(t, "5559876543", usage[1]["mdn"])
+	assert.Equal(t, 80.0, usage[1]["usedInMb"])
+
+	assert.Contains(t, entries["invoice_line_items.csv"], "i1,base_fee,Base fee,1,0,2500,USD")
+
+	var audit []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(entries["audit_log.json"]), &audit))
+	require.Len(t, audit, 2)
+	assert.Equal(t, "a2", audit[0]["id"])
+	assert.Equal(t, `{"firstName":"John"}`, audit[1]["after"])
+}
+
+func TestDataExportService_FailedExport(t *testing.T) {
+	f := setupDataExport()
+	f.cycleRepo.On("GetByUserID", mock.Anything, "u1").Return(nil, errors.New("connection reset"))
+	f.exportRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.DataExport")).
+		Run(func(args mock.Arguments) { args.Get(1).(*model.DataExport).ID = "e1" }).
+		Return(nil)
+	f.exportRepo.On("Fail", mock.Anything, "e1", "failed to get cycles: connection reset", f.clock.now).Return(nil)
+
+	_, err := f.service.RequestExport(asUser("u3", model.RoleAdmin), "u1")
+	require.NoError(t, err)
+	f.service.Wait()
+
+	f.exportRepo.AssertExpectations(t)
+	f.exportRepo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDataExportService_DownloadExport(t *testing.T) {
+	f := setupDataExport()
+	running := &model.DataExport{ID: "e1", UserID: "u1", Status: model.DataExportRunning, CreatedAt: f.clock.now}
+	ready := &model.DataExport{ID: "e2", UserID: "u1", Status: model.DataExportReady, CreatedAt: f.clock.now}
+	f.exportRepo.On("GetByID", mock.Anything, "e1").Return(running, nil)
+	f.exportRepo.On("GetByID", mock.Anything, "e2").Return(ready, nil)
+	f.exportRepo.On("GetByID", mock.Anything, mock.Anything).Return(nil, repository.ErrDataExportNotFound)
+	f.exportRepo.On("GetArchive", mock.Anything, "e2").Return([]byte("PK"), nil)
+	ctx := asUser("u1", model.RoleCustomer)
+
+	_, _, err := f.service.DownloadExport(ctx, "u1", "e1")
+	assert.ErrorIs(t, err, service.ErrDataExportNotReady)
+
+	// Exports are only found under the user they belong to
+	_, err = f.service.GetExport(asUser("u9", model.RoleCustomer), "u9", "e2")
+	assert.ErrorIs(t, err, repository.ErrDataExportNotFound)
+
+	_, archive, err := f.service.DownloadExport(ctx, "u1", "e2")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("PK"), archive)
+	f.auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionDataExportDownload && entry.Target == "user:u1"
+	}))
+
+	// An export that outlived its timeout stopped running with its instance
+	f.clock.Advance(11 * time.Minute)
+	stale, err := f.service.GetExport(ctx, "u1", "e1")
+	require.NoError(t, err)
+	assert.Equal(t, model.DataExportFailed, stale.Status)
+}
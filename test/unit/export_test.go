@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/infra/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportRows = [][]any{
+	{time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), "5551234567", 250.5},
+	{time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), `say "hi", <ok>`, 180.0},
+}
+
+func writeExport(t *testing.T, format export.Format) []byte {
+	var out bytes.Buffer
+	writer, err := export.NewRowWriter(format, &out, []string{"date", "mdn", "dailyUsage"})
+	require.NoError(t, err)
+	for _, row := range exportRows {
+		require.NoError(t, writer.Write(row))
+	}
+	require.NoError(t, writer.Close())
+	return out.Bytes()
+}
+
+func TestExport_CSV(t *testing.T) {
+	out := writeExport(t, export.FormatCSV)
+
+	assert.Equal(t, "date,mdn,dailyUsage\n"+
+		"2024-11-01T00:00:00Z,5551234567,250.5\n"+
+		`2024-11-02T00:00:00Z,"say ""hi"", <ok>",180`+"\n", string(out))
+}
+
+func TestExport_NDJSONAndJSON(t *testing.T) {
+	ndjson := writeExport(t, export.FormatNDJSON)
+	lines := strings.Split(string(ndjson), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, `{"date":"2024-11-01T00:00:00Z","mdn":"5551234567","dailyUsage":250.5}`, lines[0])
+	assert.JSONEq(t, `{"date":"2024-11-02T00:00:00Z","mdn":"say \"hi\", <ok>","dailyUsage":180}`, lines[1])
+	assert.Empty(t, lines[2])
+
+	json := writeExport(t, export.FormatJSON)
+	assert.JSONEq(t, `[
+		{"date":"2024-11-01T00:00:00Z","mdn":"5551234567","dailyUsage":250.5},
+		{"date":"2024-11-02T00:00:00Z","mdn":"say \"hi\", <ok>","dailyUsage":180}
+	]`, string(json))
+
+	var empty bytes.Buffer
+	writer, err := export.NewRowWriter(export.FormatJSON, &empty, []string{"date"})
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	assert.Equal(t, "[]", empty.String())
+}
+
+func TestExport_XLSX(t *testing.T) {
+	out := writeExport(t, export.FormatXLSX)
+
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+
+	entries := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		entries[file.Name] = string(body)
+	}
+
+	assert.Contains(t, entries, "[Content_Types].xml")
+	assert.Contains(t, entries, "xl/workbook.xml")
+	sheet := entries["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">date</t></is></c>`)
+	// 2024-11-01 is day 45597 in Excel's calendar
+	assert.Contains(t, sheet, `<c r="A2" s="1"><v>45597</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2"><v>250.5</v></c>`)
+	assert.Contains(t, sheet, `say &#34;hi&#34;, &lt;ok&gt;`)
+}
+
+func TestExport_Negotiation(t *testing.T) {
+	assert.Equal(t, export.FormatCSV, export.FromAccept("text/csv"))
+	assert.Equal(t, export.FormatNDJSON, export.FromAccept("application/x-ndjson; charset=utf-8"))
+	assert.Equal(t, export.FormatXLSX, export.FromAccept("text/html, application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"))
+	assert.Equal(t, export.FormatJSON, export.FromAccept("*/*"))
+	assert.Equal(t, export.FormatJSON, export.FromAccept(""))
+
+	format, ok := export.ParseFormat("XLSX")
+	assert.True(t, ok)
+	assert.Equal(t, export.FormatXLSX, format)
+	_, ok = export.ParseFormat("xml")
+	assert.False(t, ok)
+}
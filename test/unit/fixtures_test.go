@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newFakeClock starts every service fixture at the same moment.
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+// setupAcceptingAudit returns an audit service whose entries are all
+// appended, so tests can assert on them through the repository.
+func setupAcceptingAudit() (*service.AuditService, *MockAuditRepository) {
+	auditRepo := new(MockAuditRepository)
+	auditRepo.On("Append", mock.Anything, mock.Anything).Return(nil)
+	return service.SetupAuditService(auditRepo), auditRepo
+}
+
+func hashPassword(t *testing.T, password string, cost int) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+// setupSingleUseTokens backs a token repository with tokens that can be
+// consumed once each, collecting them in issued as they are created.
+func setupSingleUseTokens(issued *[]*model.UserToken) *MockUserTokenRepository {
+	tokenRepo := new(MockUserTokenRepository)
+	tokenRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.UserToken")).
+		Run(func(args mock.Arguments) { singleUse(tokenRepo, issued, args.Get(1).(*model.UserToken)) }).
+		Return(nil)
+	return tokenRepo
+}
+
+func singleUse(tokenRepo *MockUserTokenRepository, issued *[]*model.UserToken, token *model.UserToken) {
+	*issued = append(*issued, token)
+	tokenRepo.On("Consume", mock.Anything, token.Purpose, token.Hash, mock.Anything).Return(token, nil).Once()
+	tokenRepo.On("Consume", mock.Anything, token.Purpose, token.Hash, mock.Anything).Return(nil, repository.ErrUserTokenNotFound)
+}
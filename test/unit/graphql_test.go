@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/api/gql"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGraphQL(t *testing.T, maxComplexity int) (*gql.Executor, *MockUserRepository, *MockCycleRepository, *MockDailyUsageRepository) {
+	t.Helper()
+	userRepo := new(MockUserRepository)
+	cycleRepo := new(MockCycleRepository)
+	usageRepo := new(MockDailyUsageRepository)
+
+	executor, err := gql.SetupExecutor(
+		service.SetupUserService(userRepo, nil, nil),
+		service.SetupCycleService(cycleRepo),
+		service.SetupDailyUsageService(usageRepo, cycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil),
+		maxComplexity,
+	)
+	require.NoError(t, err)
+	return executor, userRepo, cycleRepo, usageRepo
+}
+
+func TestGraphQL_NestedQueryBatchesLoads(t *testing.T) {
+	executor, userRepo, cycleRepo, usageRepo := setupGraphQL(t, 5000)
+
+	october := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	november := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	lineA := []*model.Cycle{
+		{ID: "a2", MDN: "5551111111", UserID: "user123", StartDate: november, EndDate: november.AddDate(0, 1, 0).Add(-time.Second)},
+		{ID: "a1", MDN: "5551111111", UserID: "user123", StartDate: october, EndDate: november.Add(-time.Second)},
+	}
+	lineB := []*model.Cycle{
+		{ID: "b1", MDN: "5552222222", UserID: "user123", StartDate: october, EndDate: november.Add(-time.Second)},
+	}
+
+	userRepo.On("GetByID", mock.Anything, "user123").Return(&model.User{ID: "user123", FirstName: "John", LastName: "Doe", Email: "john@example.com"}, nil)
+	cycleRepo.On("GetByUserIDs", mock.Anything, []string{"user123"}).Return([]*model.Cycle{lineA[0], lineA[1], lineB[0]}, nil).Once()
+	cycleRepo.On("GetByMDNs", mock.Anything, []string{"5551111111", "5552222222"}).Return([]*model.Cycle{lineA[0], lineA[1], lineB[0]}, nil).Once()
+	usageRepo.On("GetByCycles", mock.Anything, mock.Anything).Return([]*model.DailyUsage{
+		{MDN: "5551111111", UserID: "user123", UsageDate: november, UsedInMB: 100},
+		{MDN: "5551111111", UserID: "user123", UsageDate: november.AddDate(0, 0, 1), UsedInMB: 50},
+		{MDN: "5551111111", UserID: "user123", UsageDate: october, UsedInMB: 70},
+		{MDN: "5552222222", UserID: "user123", UsageDate: october, UsedInMB: 30},
+	}, nil).Once()
+
+	result, executed := executor.Execute(context.Background(), dto.GraphQLRequest{
+		Query: `query Dashboard($id: ID!) {
+			user(id: $id) {
+				firstName
+				lines {
+					mdn
+					cycles(last: 2) { id totalUsage usage { date usedMb } }
+				}
+			}
+		}`,
+		Variables: map[string]interface{}{"id": "user123"},
+	})
+
+	require.True(t, executed)
+	require.Empty(t, result.Errors)
+
+	var data struct {
+		User struct {
+			FirstName string
+			Lines     []struct {
+				MDN    string `json:"mdn"`
+				Cycles []struct {
+					ID         string
+					TotalUsage float64
+					Usage      []struct{ UsedMb float64 }
+				}
+			}
+		}
+	}
+	raw, _ := json.Marshal(result.Data)
+	require.NoError(t, json.Unmarshal(raw, &data))
+
+	assert.Equal(t, "John", data.User.FirstName)
+	require.Len(t, data.User.Lines, 2)
+	assert.Equal(t, "5551111111", data.User.Lines[0].MDN)
+	require.Len(t, data.User.Lines[0].Cycles, 2)
+	assert.Equal(t, "a2", data.User.Lines[0].Cycles[0].ID)
+	assert.Equal(t, 150.0, data.User.Lines[0].Cycles[0].TotalUsage)
+	assert.Len(t, data.User.Lines[0].Cycles[0].Usage, 2)
+	assert.Equal(t, 30.0, data.User.Lines[1].Cycles[0].TotalUsage)
+
+	// One call per level, however many lines and cycles were resolved
+	cycleRepo.AssertExpectations(t)
+	usageRepo.AssertExpectations(t)
+	usageRepo.AssertNumberOfCalls(t, "GetByCycles", 1)
+}
+
+func TestGraphQL_CustomersOnlySeeTheirOwnCycles(t *testing.T) {
+	executor, userRepo, cycleRepo, usageRepo := setupGraphQL(t, 5000)
+
+	october := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	november := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	// The number was recycled: its October cycle belongs to a previous subscriber
+	mine := &model.Cycle{ID: "a2", MDN: "5551111111", UserID: "user123", StartDate: november, EndDate: november.AddDate(0, 1, 0).Add(-time.Second)}
+	previous := &model.Cycle{ID: "a1", MDN: "5551111111", UserID: "user000", StartDate: october, EndDate: november.Add(-time.Second)}
+
+	userRepo.On("GetByID", mock.Anything, "user123").Return(&model.User{ID: "user123", FirstName: "John"}, nil)
+	cycleRepo.On("GetByUserIDs", mock.Anything, []string{"user123"}).Return([]*model.Cycle{mine}, nil).Once()
+	cycleRepo.On("GetByMDNs", mock.Anything, []string{"5551111111"}).Return([]*model.Cycle{mine, previous}, nil).Once()
+	usageRepo.On("GetByCycles", mock.Anything, []*model.Cycle{mine}).Return([]*model.DailyUsage{
+		{MDN: "5551111111", UserID: "user123", UsageDate: november, UsedInMB: 100},
+	}, nil).Once()
+
+	result, executed := executor.Execute(asUser("user123", model.RoleCustomer), dto.GraphQLRequest{
+		Query: `{ user(id: "user123") { lines { cycles { id usage { usedMb } } } } }`,
+	})
+
+	require.True(t, executed)
+	require.Empty(t, result.Errors)
+	raw, _ := json.Marshal(result.Data)
+	assert.JSONEq(t, `{"user":{"lines":[{"cycles":[{"id":"a2","usage":[{"usedMb":100}]}]}]}}`, string(raw))
+
+	// The batch is authorized as a whole, without a lookup per line
+	cycleRepo.AssertExpectations(t)
+	cycleRepo.AssertNotCalled(t, "GetByMDN", mock.Anything, mock.Anything)
+	usageRepo.AssertExpectations(t)
+}
+
+func TestGraphQL_RejectsQueriesOverComplexityLimit(t *testing.T) {
+	executor, _, cycleRepo, _ := setupGraphQL(t, 100)
+
+	result, executed := executor.Execute(context.Background(), dto.GraphQLRequest{
+		Query:     `query ($n: Int) { line(mdn: "5551111111") { cycles(last: $n) { id usage { date usedMb } } } }`,
+		Variables: map[string]interface{}{"n": 24},
+	})
+
+	assert.False(t, executed)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, "exceeds the limit of 100")
+	cycleRepo.AssertNotCalled(t, "GetByMDNs")
+
+	// The same shape with a small window fits
+	cycleRepo.On("GetByMDNs", mock.Anything, []string{"5551111111"}).Return([]*model.Cycle{}, nil)
+	result, executed = executor.Execute(context.Background(), dto.GraphQLRequest{
+		Query:     `query ($n: Int) { line(mdn: "5551111111") { cycles(last: $n) { id usage { date usedMb } } } }`,
+		Variables: map[string]interface{}{"n": 1},
+	})
+
+	assert.True(t, executed)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"line": nil}, result.Data)
+}
+
+func TestGraphQL_RejectsInvalidQueries(t *testing.T) {
+	executor, _, _, _ := setupGraphQL(t, 5000)
+
+	result, executed := executor.Execute(context.Background(), dto.GraphQLRequest{Query: `{ user(id: "1") { password } }`})
+
+	assert.False(t, executed)
+	assert.NotEmpty(t, result.Errors)
+}
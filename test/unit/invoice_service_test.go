@@ -0,0 +1,209 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInvoiceRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvoiceRepository) Create(ctx context.Context, invoice *model.Invoice) error {
+	args := m.Called(ctx, invoice)
+	return args.Error(0)
+}
+
+func (m *MockInvoiceRepository) GetByID(ctx context.Context, id string) (*model.Invoice, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) GetByCycleID(ctx context.Context, cycleID string) (*model.Invoice, error) {
+	args := m.Called(ctx, cycleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Invoice), args.Error(1)
+}
+
+func (m *MockInvoiceRepository) GetByUserID(ctx context.Context, userID string) ([]*model.Invoice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Invoice), args.Error(1)
+}
+
+type MockPlanRepository struct {
+	mock.Mock
+}
+
+func (m *MockPlanRepository) Create(ctx context.Context, plan *model.Plan) error {
+	args := m.Called(ctx, plan)
+	return args.Error(0)
+}
+
+func (m *MockPlanRepository) GetByID(ctx context.Context, id string) (*model.Plan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Plan), args.Error(1)
+}
+
+type MockCreditRepository struct {
+	mock.Mock
+}
+
+func (m *MockCreditRepository) Create(ctx context.Context, credit *model.Credit) error {
+	args := m.Called(ctx, credit)
+	return args.Error(0)
+}
+
+func (m *MockCreditRepository) GetByCycleID(ctx context.Context, cycleID string) ([]*model.Credit, error) {
+	args := m.Called(ctx, cycleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Credit), args.Error(1)
+}
+
+func TestInvoiceService_GenerateInvoice(t *testing.T) {
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockPlanRepo := new(MockPlanRepository)
+	mockCreditRepo := new(MockCreditRepository)
+	invoiceService := service.SetupInvoiceService(mockInvoiceRepo, mockCycleRepo, mockUsageRepo, mockPlanRepo, mockCreditRepo, "basic", nil)
+
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 10, 31, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+	plan := &model.Plan{
+		ID:                 "basic",
+		Name:               "Basic 5GB",
+		Currency:           "USD",
+		BaseFee:            2500,
+		IncludedMB:         5120,
+		OverageUnitMB:      1024,
+		OverageRate:        1000,
+		TaxRateBasisPoints: 825,
+	}
+
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, "cycle1").Return(nil, repository.ErrInvoiceNotFound)
+	mockCycleRepo.On("GetByID", mock.Anything, "cycle1").Return(cycle, nil)
+	mockPlanRepo.On("GetByID", mock.Anything, "basic").Return(plan, nil)
+	// 1.5GB over the allowance is billed as two started blocks
+	mockUsageRepo.On("GetCycleTotals", mock.Anything, "5551234567", []*model.Cycle{cycle}, 0).
+		Return([]*model.CycleUsageTotal{{CycleID: "cycle1", TotalMB: 6656}}, nil)
+	mockCreditRepo.On("GetByCycleID", mock.Anything, "cycle1").
+		Return([]*model.Credit{{ID: "credit1", Amount: model.NewMoney(500, "USD"), Reason: "Outage"}}, nil)
+	mockInvoiceRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Invoice")).Return(nil)
+
+	invoice, err := invoiceService.GenerateInvoice(context.Background(), "cycle1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", invoice.Currency)
+	assert.Equal(t, int64(4500), invoice.Subtotal.Amount)
+	assert.Equal(t, int64(500), invoice.Credits.Amount)
+	// 8.25% of 40.00 is 3.30
+	assert.Equal(t, int64(330), invoice.Tax.Amount)
+	assert.Equal(t, int64(4330), invoice.Total.Amount)
+	assert.Len(t, invoice.LineItems, 4)
+	assert.Equal(t, model.LineItemOverage, invoice.LineItems[1].Type)
+	assert.Equal(t, 2.0, invoice.LineItems[1].Quantity)
+	assert.Equal(t, int64(-500), invoice.LineItems[2].Amount.Amount)
+	mockInvoiceRepo.AssertExpectations(t)
+}
+
+func TestInvoiceService_GenerateInvoice_AlreadyInvoiced(t *testing.T) {
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	invoiceService := service.SetupInvoiceService(mockInvoiceRepo, mockCycleRepo, new(MockDailyUsageRepository), new(MockPlanRepository), new(MockCreditRepository), "basic", nil)
+
+	existing := &model.Invoice{ID: "invoice1", CycleID: "cycle1", Total: model.NewMoney(2500, "USD")}
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, "cycle1").Return(existing, nil)
+
+	invoice, err := invoiceService.GenerateInvoice(context.Background(), "cycle1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing, invoice)
+	mockCycleRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	mockInvoiceRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestInvoiceService_GenerateInvoice_OpenCycle(t *testing.T) {
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	invoiceService := service.SetupInvoiceService(mockInvoiceRepo, mockCycleRepo, new(MockDailyUsageRepository), new(MockPlanRepository), new(MockCreditRepository), "basic", nil)
+
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		StartDate: time.Now().AddDate(0, 0, -5),
+		EndDate:   time.Now().AddDate(0, 0, 25),
+	}
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, "cycle1").Return(nil, repository.ErrInvoiceNotFound)
+	mockCycleRepo.On("GetByID", mock.Anything, "cycle1").Return(cycle, nil)
+
+	invoice, err := invoiceService.GenerateInvoice(context.Background(), "cycle1")
+
+	assert.ErrorIs(t, err, service.ErrCycleNotClosed)
+	assert.Nil(t, invoice)
+}
+
+func TestMoney_CurrencyMismatch(t *testing.T) {
+	sum, err := model.NewMoney(2500, "USD").Add(model.NewMoney(500, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, model.NewMoney(3000, "USD"), sum)
+
+	// Amounts in different currencies are an error, not a panic
+	_, err = model.NewMoney(2500, "USD").Add(model.NewMoney(500, "EUR"))
+	assert.ErrorIs(t, err, model.ErrCurrencyMismatch)
+	_, err = model.NewMoney(2500, "USD").Sub(model.NewMoney(500, "EUR"))
+	assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+}
+
+func TestInvoiceService_GenerateInvoicesForEndedCycles_CurrencyMismatch(t *testing.T) {
+	mockInvoiceRepo := new(MockInvoiceRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockPlanRepo := new(MockPlanRepository)
+	mockCreditRepo := new(MockCreditRepository)
+	invoiceService := service.SetupInvoiceService(mockInvoiceRepo, mockCycleRepo, mockUsageRepo, mockPlanRepo, mockCreditRepo, "basic", nil)
+
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", StartDate: from, EndDate: to.Add(-time.Second), UserID: "user123"}
+
+	mockCycleRepo.On("GetEndedBetween", mock.Anything, from, to).Return([]*model.Cycle{cycle}, nil)
+	mockInvoiceRepo.On("GetByCycleID", mock.Anything, "cycle1").Return(nil, repository.ErrInvoiceNotFound)
+	mockCycleRepo.On("GetByID", mock.Anything, "cycle1").Return(cycle, nil)
+	mockPlanRepo.On("GetByID", mock.Anything, "basic").Return(&model.Plan{ID: "basic", Currency: "USD", BaseFee: 2500}, nil)
+	mockUsageRepo.On("GetCycleTotals", mock.Anything, "5551234567", []*model.Cycle{cycle}, 0).
+		Return([]*model.CycleUsageTotal{}, nil)
+	mockCreditRepo.On("GetByCycleID", mock.Anything, "cycle1").
+		Return([]*model.Credit{{ID: "credit1", Amount: model.NewMoney(500, "EUR")}}, nil)
+
+	// The cycle is reported as failed and the run carries on
+	invoiced, failures, err := invoiceService.GenerateInvoicesForEndedCycles(context.Background(), from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, invoiced)
+	assert.ErrorIs(t, failures["cycle1"], service.ErrCurrencyMismatch)
+	mockInvoiceRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
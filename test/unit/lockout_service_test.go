@@ -0,0 +1,215 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginAttempts), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, retention time.Duration) (*model.LoginAttempts, error) {
+	args := m.Called(ctx, key, at, retention)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginAttempts), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+type lockoutFixture struct {
+	clock     *fakeClock
+	userRepo  *MockUserRepository
+	auditRepo *MockAuditRepository
+	lockout   *service.LockoutService
+	auth      *service.AuthService
+	ctx       context.Context
+}
+
+// setupLockout backs the attempt repository with records that change the
+// way the stored ones would.
+func setupLockout(t *testing.T, cost int, keys ...string) *lockoutFixture {
+	audit, auditRepo := setupAcceptingAudit()
+	f := &lockoutFixture{
+		clock:     newFakeClock(),
+		userRepo:  new(MockUserRepository),
+		auditRepo: auditRepo,
+		ctx:       service.WithRequestInfo(context.Background(), "req-1", "203.0.113.9"),
+	}
+	attemptRepo := new(MockLoginAttemptRepository)
+	for _, key := range keys {
+		attempts := &model.LoginAttempts{Key: key}
+		attemptRepo.On("Get", mock.Anything, key).Return(attempts, nil)
+		attemptRepo.On("RecordFailure", mock.Anything, key, mock.Anything, 15*time.Minute).
+			Run(func(args mock.Arguments) {
+				attempts.Failures++
+				attempts.LastFailureAt = args.Get(2).(time.Time)
+			}).
+			Return(attempts, nil)
+		attemptRepo.On("Lock", mock.Anything, key, mock.Anything).
+			Run(func(args mock.Arguments) {
+				until := args.Get(2).(time.Time)
+				attempts.LockedUntil = &until
+			}).
+			Return(nil)
+		attemptRepo.On("Reset", mock.Anything, key).
+			Run(func(mock.Arguments) { *attempts = model.LoginAttempts{Key: key} }).
+			Return(nil)
+	}
+
+	f.lockout = service.SetupLockoutService(attemptRepo, f.userRepo, service.LockoutOptions{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      5,
+		Window:             15 * time.Minute,
+		LockDuration:       15 * time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           4 * time.Second,
+		Clock:              f.clock,
+	}, audit)
+	sessionRepo := new(MockSessionRepository)
+	sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Session")).Return(nil)
+	f.auth = service.SetupAuthService(f.userRepo, sessionRepo, nil, nil, f.lockout, time.Hour)
+
+	user := &model.User{ID: "u1", Email: "john@example.com", Password: hashPassword(t, "password123", cost)}
+	f.userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+	f.userRepo.On("GetByEmail", mock.Anything, "john@example.com").Return(user, nil)
+	f.userRepo.On("GetByEmail", mock.Anything, mock.Anything).Return(nil, repository.ErrUserNotFound)
+	return f
+}
+
+func (f *lockoutFixture) login(email, password string) error {
+	_, err := f.auth.Login(f.ctx, dto.LoginRequest{Email: email, Password: password})
+	return err
+}
+
+func assertThrottled(t *testing.T, err error, retryAfter time.Duration) {
+	t.Helper()
+	var throttled *service.LoginThrottledError
+	require.ErrorAs(t, err, &throttled)
+	assert.ErrorIs(t, err, service.ErrLoginThrottled)
+	assert.Equal(t, retryAfter, throttled.RetryAfter)
+}
+
+func TestAuthService_Login_LocksOutAccounts(t *testing.T) {
+	f := setupLockout(t, bcrypt.MinCost, "account:john@example.com", "ip:203.0.113.9")
+
+	assert.ErrorIs(t, f.login("john@example.com", "wrong"), service.ErrInvalidCredentials)
+	// Each failure doubles the wait before the next attempt
+	assertThrottled(t, f.login("john@example.com", "wrong"), time.Second)
+	f.clock.Advance(time.Second)
+	assert.ErrorIs(t, f.login("John@Example.com", "wrong"), service.ErrInvalidCredentials)
+	assertThrottled(t, f.login("john@example.com", "password123"), 2*time.Second)
+	f.clock.Advance(2 * time.Second)
+	assert.ErrorIs(t, f.login("john@example.com", "wrong"), service.ErrInvalidCredentials)
+
+	// The third failure locks the account, even against the right password
+	assertThrottled(t, f.login("john@example.com", "password123"), 15*time.Minute)
+	f.auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionLoginLockout &&
+			entry.Target == "user:u1" &&
+			entry.SourceIP == "203.0.113.9" &&
+			entry.After["failures"] == int32(3)
+	}))
+
+	assert.ErrorIs(t, f.lockout.Unlock(asUser("u2", model.RoleSupport), "u1"), service.ErrPermissionDenied)
+	require.NoError(t, f.lockout.Unlock(asUser("u3", model.RoleAdmin), "u1"))
+	f.auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionLoginUnlock && entry.Actor == "user:u3" && entry.Target == "user:u1"
+	}))
+
+	assert.NoError(t, f.login("john@example.com", "password123"))
+}
+
+func TestAuthService_Login_LocksOutAddresses(t *testing.T) {
+	keys := []string{"ip:203.0.113.9"}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		keys = append(keys, "account:"+name+"@example.com")
+	}
+	f := setupLockout(t, bcrypt.MinCost, keys...)
+
+	// Spraying one password across accounts never trips an account limit
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		assert.ErrorIs(t, f.login(name+"@example.com", "password123"), service.ErrInvalidCredentials)
+	}
+
+	assertThrottled(t, f.login("f@example.com", "password123"), 15*time.Minute)
+	f.auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionLoginLockout && entry.Target == "ip:203.0.113.9"
+	}))
+}
+
+func TestAuthService_Login_UnknownEmailsTakeAsLong(t *testing.T) {
+	f := setupLockout(t, bcrypt.DefaultCost, "account:john@example.com", "account:nobody@example.com", "ip:203.0.113.9")
+
+	timed := func(email string) time.Duration {
+		start := time.Now()
+		assert.ErrorIs(t, f.login(email, "wrong"), service.ErrInvalidCredentials)
+		return time.Since(start)
+	}
+
+	// Warm up, then compare; both compare a password against a bcrypt hash of
+	// the same cost
+	timed("nobody@example.com")
+	f.clock.Advance(time.Minute)
+	known := timed("john@example.com")
+	f.clock.Advance(time.Minute)
+	unknown := timed("nobody@example.com")
+
+	assert.Greater(t, unknown, known/3)
+}
+
+func TestRouter_LoginThrottledSetsRetryAfter(t *testing.T) {
+	f := setupLockout(t, bcrypt.MinCost, "account:john@example.com", "ip:192.0.2.1")
+
+	r := router.SetupRouter(nil, router.Config{GinMode: gin.TestMode}, router.Handlers{
+		Auth: handler.SetupAuthHandler(f.auth, nil),
+	})
+
+	login := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"john@example.com","password":"wrong-password"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login().Code)
+
+	w := login()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,280 @@
+package unit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// totpAt computes a code independently of the service, from the base32
+// secret shown at enrollment.
+func totpAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1_000_000)
+}
+
+type mfaFixture struct {
+	user        *model.User
+	clock       *fakeClock
+	userRepo    *MockUserRepository
+	tokenRepo   *MockUserTokenRepository
+	sessionRepo *MockSessionRepository
+	mfa         *service.MFAService
+	auth        *service.AuthService
+	sessions    []*model.Session
+	issued      []*model.UserToken
+}
+
+// setupMFA backs the mocks with a single admin user whose MFA state changes
+// the way the repository would change it.
+func setupMFA(t *testing.T) *mfaFixture {
+	cipher, err := secrets.SetupAESCipher([]byte(strings.Repeat("k", 32)))
+	require.NoError(t, err)
+
+	f := &mfaFixture{
+		user:        &model.User{ID: "u3", Email: "admin@example.com", Password: hashPassword(t, "password123", bcrypt.MinCost), Roles: []string{model.RoleAdmin}},
+		clock:       newFakeClock(),
+		userRepo:    new(MockUserRepository),
+		sessionRepo: new(MockSessionRepository),
+	}
+	f.tokenRepo = setupSingleUseTokens(&f.issued)
+	f.mfa = service.SetupMFAService(f.userRepo, f.tokenRepo, cipher, service.MFAOptions{
+		Issuer:        "Phone Usage",
+		RequiredRoles: []string{model.RoleSupport, model.RoleAdmin},
+		ChallengeTTL:  5 * time.Minute,
+		Clock:         f.clock,
+	}, nil)
+	f.auth = service.SetupAuthService(f.userRepo, f.sessionRepo, nil, f.mfa, nil, time.Hour)
+
+	f.userRepo.On("GetByID", mock.Anything, "u3").Return(f.user, nil)
+	f.userRepo.On("GetByEmail", mock.Anything, "admin@example.com").Return(f.user, nil)
+	f.userRepo.On("SetMFA", mock.Anything, "u3", mock.Anything).
+		Run(func(args mock.Arguments) { f.user.MFA, _ = args.Get(2).(*model.MFA) }).
+		Return(nil)
+	f.userRepo.On("UseTOTPStep", mock.Anything, "u3", mock.MatchedBy(func(step int64) bool { return step > f.user.MFA.LastStep })).
+		Run(func(args mock.Arguments) { f.user.MFA.LastStep = args.Get(2).(int64) }).
+		Return(nil)
+	f.userRepo.On("UseTOTPStep", mock.Anything, "u3", mock.Anything).Return(repository.ErrMFAFactorUsed)
+	f.userRepo.On("UseRecoveryCode", mock.Anything, "u3", mock.MatchedBy(func(hash string) bool { return slices.Contains(f.user.MFA.RecoveryCodes, hash) })).
+		Run(func(args mock.Arguments) {
+			f.user.MFA.RecoveryCodes = slices.DeleteFunc(f.user.MFA.RecoveryCodes, func(h string) bool { return h == args.Get(2) })
+		}).
+		Return(nil)
+	f.userRepo.On("UseRecoveryCode", mock.Anything, "u3", mock.Anything).Return(repository.ErrMFAFactorUsed)
+
+	f.sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.Session")).
+		Run(func(args mock.Arguments) {
+			session := args.Get(1).(*model.Session)
+			f.sessions = append(f.sessions, session)
+			f.sessionRepo.On("GetByHash", mock.Anything, session.Hash).Return(session, nil)
+		}).
+		Return(nil)
+	return f
+}
+
+// enable enrolls and activates MFA as the signed-in user and returns the
+// secret and recovery codes.
+func (f *mfaFixture) enable(t *testing.T) (string, []string) {
+	t.Helper()
+	ctx := asUser("u3")
+
+	enrollment, err := f.mfa.Enroll(ctx)
+	require.NoError(t, err)
+	codes, err := f.mfa.Activate(ctx, dto.MFACodeRequest{Code: totpAt(t, enrollment.Secret, f.clock.Now())})
+	require.NoError(t, err)
+	return enrollment.Secret, codes.Codes
+}
+
+func (f *mfaFixture) login(t *testing.T) string {
+	t.Helper()
+	issued, err := f.auth.Login(context.Background(), dto.LoginRequest{Email: "admin@example.com", Password: "password123"})
+	require.NoError(t, err)
+	require.True(t, issued.MFARequired)
+	assert.Empty(t, issued.Token)
+	return issued.Challenge
+}
+
+func TestTOTP_ReferenceVector(t *testing.T) {
+	// RFC 6238, appendix B, truncated to six digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	assert.Equal(t, "287082", totpAt(t, secret, time.Unix(59, 0)))
+	assert.Equal(t, "081804", totpAt(t, secret, time.Unix(1111111109, 0)))
+}
+
+func TestMFAService_Enrollment(t *testing.T) {
+	f := setupMFA(t)
+	ctx := asUser("u3")
+
+	enrollment, err := f.mfa.Enroll(ctx)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Phone%20Usage:admin@example.com?"))
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.NotContains(t, f.user.MFA.Secret, enrollment.Secret)
+	assert.False(t, f.user.MFA.Enabled)
+
+	_, err = f.mfa.Activate(ctx, dto.MFACodeRequest{Code: "000000"})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	// A code from the previous step is still accepted
+	code := totpAt(t, enrollment.Secret, f.clock.Now())
+	f.clock.Advance(30 * time.Second)
+	codes, err := f.mfa.Activate(ctx, dto.MFACodeRequest{Code: code})
+	require.NoError(t, err)
+	assert.True(t, f.user.MFA.Enabled)
+	require.Len(t, codes.Codes, 10)
+	assert.Len(t, f.user.MFA.RecoveryCodes, 10)
+	assert.NotContains(t, f.user.MFA.RecoveryCodes, codes.Codes[0])
+
+	_, err = f.mfa.Enroll(ctx)
+	assert.ErrorIs(t, err, service.ErrMFAAlreadyEnabled)
+}
+
+func TestAuthService_LoginWithMFA(t *testing.T) {
+	f := setupMFA(t)
+	secret, _ := f.enable(t)
+
+	// The activation code cannot be replayed
+	_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: f.login(t), Code: totpAt(t, secret, f.clock.Now())})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	assert.Empty(t, f.sessions)
+
+	f.clock.Advance(30 * time.Second)
+	challenge := f.login(t)
+	code := totpAt(t, secret, f.clock.Now())
+	issued, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: challenge, Code: code})
+	require.NoError(t, err)
+	require.Len(t, f.sessions, 1)
+	assert.True(t, f.sessions[0].MFA)
+
+	principal, err := f.auth.Authenticate(context.Background(), issued.Token)
+	require.NoError(t, err)
+	assert.True(t, principal.HasRole(model.RoleAdmin))
+
+	// Challenges are single use
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: challenge, Code: code})
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestAuthService_VerifyMFA_RefusesLockedAccounts(t *testing.T) {
+	f := setupMFA(t)
+	secret, _ := f.enable(t)
+
+	attempts := &model.LoginAttempts{Key: "account:admin@example.com"}
+	attemptRepo := new(MockLoginAttemptRepository)
+	attemptRepo.On("Get", mock.Anything, attempts.Key).Return(attempts, nil)
+	attemptRepo.On("Reset", mock.Anything, attempts.Key).Return(nil)
+	lockout := service.SetupLockoutService(attemptRepo, f.userRepo, service.LockoutOptions{
+		MaxAccountFailures: 3,
+		Window:             15 * time.Minute,
+		LockDuration:       15 * time.Minute,
+		Clock:              f.clock,
+	}, nil)
+	f.auth = service.SetupAuthService(f.userRepo, f.sessionRepo, nil, f.mfa, lockout, time.Hour)
+
+	f.clock.Advance(30 * time.Second)
+	challenge := f.login(t)
+	code := totpAt(t, secret, f.clock.Now())
+
+	// The account was locked after the challenge was issued
+	lockedUntil := f.clock.Now().Add(15 * time.Minute)
+	attempts.LockedUntil = &lockedUntil
+	_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: challenge, Code: code})
+	assert.ErrorIs(t, err, service.ErrLoginThrottled)
+	assert.Empty(t, f.sessions)
+
+	// The code was not spent on the refused attempt
+	attempts.LockedUntil = nil
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: f.login(t), Code: code})
+	require.NoError(t, err)
+	assert.Len(t, f.sessions, 1)
+}
+
+func TestAuthService_LoginWithRecoveryCode(t *testing.T) {
+	f := setupMFA(t)
+	_, codes := f.enable(t)
+
+	_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: f.login(t), Code: strings.ToUpper(codes[3])})
+	require.NoError(t, err)
+	assert.Len(t, f.user.MFA.RecoveryCodes, 9)
+
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{Challenge: f.login(t), Code: codes[3]})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+}
+
+func TestAuthService_RequiredRolesNeedMFA(t *testing.T) {
+	f := setupMFA(t)
+
+	// Without an enrollment the password alone opens a session, but without
+	// the admin role
+	issued, err := f.auth.Login(context.Background(), dto.LoginRequest{Email: "admin@example.com", Password: "password123"})
+	require.NoError(t, err)
+	require.NotEmpty(t, issued.Token)
+
+	principal, err := f.auth.Authenticate(context.Background(), issued.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "u3", principal.UserID)
+	assert.False(t, principal.HasRole(model.RoleAdmin))
+	assert.False(t, principal.Can(model.PermissionUsersRead, "u9"))
+	assert.Equal(t, []string{model.RoleAdmin}, f.user.Roles)
+}
+
+func TestMFAService_Disable(t *testing.T) {
+	f := setupMFA(t)
+	secret, _ := f.enable(t)
+	ctx := asUser("u3")
+
+	err := f.mfa.Disable(ctx, dto.MFACodeRequest{Code: "123456"})
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	f.clock.Advance(30 * time.Second)
+	require.NoError(t, f.mfa.Disable(ctx, dto.MFACodeRequest{Code: totpAt(t, secret, f.clock.Now())}))
+	assert.Nil(t, f.user.MFA)
+
+	err = f.mfa.Disable(ctx, dto.MFACodeRequest{Code: "123456"})
+	assert.ErrorIs(t, err, service.ErrMFANotEnrolled)
+}
+
+func TestAESCipher(t *testing.T) {
+	cipher, err := secrets.SetupAESCipher([]byte(strings.Repeat("k", 32)))
+	require.NoError(t, err)
+
+	sealed, err := cipher.Encrypt([]byte("totp secret"))
+	require.NoError(t, err)
+	opened, err := cipher.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "totp secret", string(opened))
+
+	other, err := secrets.SetupAESCipher([]byte(strings.Repeat("x", 32)))
+	require.NoError(t, err)
+	_, err = other.Decrypt(sealed)
+	assert.ErrorIs(t, err, secrets.ErrCiphertextInvalid)
+
+	_, err = secrets.SetupAESCipher([]byte("short"))
+	assert.Error(t, err)
+}
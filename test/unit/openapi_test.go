@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bowe99/phone-usage-service/internal/api/docs"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Routes that serve the documentation itself
+var undocumentedRoutes = map[string]bool{
+	"GET /openapi.json":   true,
+	"GET /docs/*filepath": true,
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+// Handlers are never called, so the router can be built without them
+func newDocumentedRouter() *gin.Engine {
+	return router.SetupRouter(nil, router.Config{GinMode: gin.TestMode}, router.Handlers{})
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDocument {
+	t.Helper()
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(docs.OpenAPI, &doc))
+	return &doc
+}
+
+func TestOpenAPI_MatchesRegisteredRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "expected an OpenAPI 3 document, got %q", doc.OpenAPI)
+
+	// Deprecated versions alias routes that are documented under their successor
+	for _, version := range router.Versions(router.Handlers{}) {
+		if !version.Deprecated() {
+			continue
+		}
+		for _, route := range version.Routes {
+			undocumentedRoutes[route.Method+" "+version.Prefix+route.Path] = true
+			for former, path := range version.Renamed {
+				if path == route.Path {
+					undocumentedRoutes[route.Method+" "+version.Prefix+former] = true
+				}
+			}
+		}
+	}
+
+	pathParam := regexp.MustCompile(`:(\w+)`)
+	var registered []string
+	for _, route := range newDocumentedRouter().Routes() {
+		key := route.Method + " " + route.Path
+		if undocumentedRoutes[key] {
+			continue
+		}
+		registered = append(registered, route.Method+" "+pathParam.ReplaceAllString(route.Path, "{$1}"))
+	}
+
+	var documented []string
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, registered, documented, "gin routes and openapi.json diverge; update the annotations and run make docs")
+}
+
+func TestOpenAPI_ReferencesResolve(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	refs := regexp.MustCompile(`"\$ref":\s*"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(docs.OpenAPI), -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, ref[1], "unresolved schema reference")
+	}
+}
+
+func TestOpenAPI_ServesSpecAndDocsUI(t *testing.T) {
+	r := newDocumentedRouter()
+
+	for path, contentType := range map[string]string{
+		"/openapi.json":                "application/json",
+		"/docs/":                       "text/html",
+		"/docs/swagger-initializer.js": "text/javascript",
+		"/docs/swagger-ui-bundle.js":   "javascript",
+		"/docs/swagger-ui.css":         "text/css",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Header().Get("Content-Type"), contentType, path)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/swagger-initializer.js", nil))
+	assert.Contains(t, w.Body.String(), "../openapi.json")
+}
@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/bowe99/phone-usage-service/internal/infra/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyFile writes content to a key file.
+func keyFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pii-keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// testKey is its ID repeated to 32 bytes.
+func testKey(id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(id, 32/len(id))))
+}
+
+func setupEnvelope(t *testing.T, current string, ids ...string) *secrets.Envelope {
+	t.Helper()
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, `"`+id+`":"`+testKey(id)+`"`)
+	}
+	provider, err := secrets.SetupFileKeyProvider(keyFile(t,
+		`{"currentKeyId":"`+current+`","keys":{`+strings.Join(keys, ",")+`},"indexKey":"`+testKey("ix")+`","searchKey":"`+testKey("sx")+`"}`))
+	require.NoError(t, err)
+	return secrets.SetupEnvelope(provider)
+}
+
+func TestEnvelope_EncryptsFieldsUnderDataKeys(t *testing.T) {
+	envelope := setupEnvelope(t, "k1", "k1")
+
+	dataKey, err := envelope.NewDataKey()
+	require.NoError(t, err)
+	assert.Equal(t, "k1", dataKey.KeyID)
+
+	sealed, err := dataKey.Encrypt("email", "ada@example.com")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "ada")
+
+	opened, err := envelope.OpenDataKey(dataKey.KeyID, dataKey.Wrapped)
+	require.NoError(t, err)
+	email, err := opened.Decrypt("email", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", email)
+
+	// A value moved into another field does not decrypt
+	_, err = opened.Decrypt("firstName", sealed)
+	assert.ErrorIs(t, err, secrets.ErrCiphertextInvalid)
+
+	// Nor does it under another document's key
+	other, err := envelope.NewDataKey()
+	require.NoError(t, err)
+	_, err = other.Decrypt("email", sealed)
+	assert.ErrorIs(t, err, secrets.ErrCiphertextInvalid)
+
+	empty, err := dataKey.Encrypt("pendingEmail", "")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestEnvelope_RewrapsAfterRotation(t *testing.T) {
+	before := setupEnvelope(t, "k1", "k1")
+	dataKey, err := before.NewDataKey()
+	require.NoError(t, err)
+	sealed, err := dataKey.Encrypt("lastName", "Lovelace")
+	require.NoError(t, err)
+
+	after := setupEnvelope(t, "k2", "k1", "k2")
+	opened, err := after.OpenDataKey(dataKey.KeyID, dataKey.Wrapped)
+	require.NoError(t, err)
+	rewrapped, err := after.Rewrap(opened)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rewrapped.KeyID)
+
+	// Once rewrapped, the field opens without the retired key
+	retired := setupEnvelope(t, "k2", "k2")
+	_, err = retired.OpenDataKey(dataKey.KeyID, dataKey.Wrapped)
+	assert.ErrorIs(t, err, repository.ErrEncryptionKeyNotFound)
+	current, err := retired.OpenDataKey(rewrapped.KeyID, rewrapped.Wrapped)
+	require.NoError(t, err)
+	lastName, err := current.Decrypt("lastName", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "Lovelace", lastName)
+
+	// A wrapped key cannot be passed off as wrapped by another key
+	_, err = after.OpenDataKey("k2", dataKey.Wrapped)
+	assert.Error(t, err)
+}
+
+func TestEnvelope_BlindIndex(t *testing.T) {
+	envelope := setupEnvelope(t, "k1", "k1")
+	rotated := setupEnvelope(t, "k2", "k2")
+
+	// Rotating data keys leaves the index as it is
+	assert.Equal(t, envelope.BlindIndex("ada@example.com"), rotated.BlindIndex("ada@example.com"))
+	assert.NotEqual(t, envelope.BlindIndex("ada@example.com"), envelope.BlindIndex("bob@example.com"))
+	assert.Len(t, envelope.BlindIndex("ada@example.com"), 64)
+
+	// Search prefixes are indexed under a key of their own
+	assert.NotEqual(t, envelope.BlindIndex("ada"), envelope.SearchIndex("ada"))
+	assert.Equal(t, envelope.SearchIndex("ada"), rotated.SearchIndex("ada"))
+}
+
+func TestSetupFileKeyProvider_Validates(t *testing.T) {
+	for name, content := range map[string]string{
+		"not json":        `currentKeyId: k1`,
+		"unknown current": `{"currentKeyId":"k2","keys":{"k1":"` + testKey("k1") + `"},"indexKey":"` + testKey("ix") + `","searchKey":"` + testKey("sx") + `"}`,
+		"short key":       `{"currentKeyId":"k1","keys":{"k1":"c2hvcnQ="},"indexKey":"` + testKey("ix") + `","searchKey":"` + testKey("sx") + `"}`,
+		"no index key":    `{"currentKeyId":"k1","keys":{"k1":"` + testKey("k1") + `"},"searchKey":"` + testKey("sx") + `"}`,
+		"no search key":   `{"currentKeyId":"k1","keys":{"k1":"` + testKey("k1") + `"},"indexKey":"` + testKey("ix") + `"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := secrets.SetupFileKeyProvider(keyFile(t, content))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := secrets.SetupFileKeyProvider(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
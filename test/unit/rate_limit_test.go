@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/middleware"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_RateLimitsPerGroupAcrossVersions(t *testing.T) {
+	r := router.SetupRouter(nil, router.Config{
+		GinMode: gin.TestMode,
+		Auth:    setupTestAPIKey("pus_reader", model.ScopeUsageRead),
+		RateLimits: router.RateLimits{
+			Store:   repository.SetupMemoryRateLimitStore(),
+			Default: model.RateLimit{Rate: 100, Burst: 100},
+			Groups:  map[string]model.RateLimit{"cycles": {Rate: 0.01, Burst: 2}},
+		},
+	}, router.Handlers{
+		Cycle: handler.SetupCycleHandler(service.SetupCycleService(new(MockCycleRepository))),
+	})
+
+	post := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-API-Key", "pus_reader")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("/api/v1/cycles/history", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusBadRequest, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=200", first.Header().Get("RateLimit-Policy"))
+
+	// The legacy alias draws from the same bucket, under its former path too
+	second := post("/api/cycle/history", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, "0", second.Header().Get("RateLimit-Remaining"))
+
+	limited := post("/api/v1/cycles/history", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "100", limited.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"rate limit exceeded"}`, limited.Body.String())
+
+	// Buckets follow the key, not the address it calls from
+	moved := post("/api/v1/cycles/history", "10.0.0.2:1234")
+	assert.Equal(t, http.StatusTooManyRequests, moved.Code)
+}
+
+func TestRateLimit_FallsBackToClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", middleware.RateLimit(repository.SetupMemoryRateLimitStore(), "test", model.RateLimit{Rate: 0.01, Burst: 1}),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, get("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusNoContent, get("10.0.0.2:1234"))
+}
+
+func TestRateLimit_PrefersAuthenticatedIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-User"); id != "" {
+			c.Set(middleware.ContextUserID, id)
+		}
+	})
+	r.GET("/", middleware.RateLimit(repository.SetupMemoryRateLimitStore(), "test", model.RateLimit{Rate: 0.01, Burst: 1}),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	get := func(user string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, get("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, get("alice"))
+	assert.Equal(t, http.StatusNoContent, get("bob"), "users behind the same IP are limited separately")
+}
+
+func TestMemoryRateLimitStore_Refills(t *testing.T) {
+	ctx := context.Background()
+	store := repository.SetupMemoryRateLimitStore()
+	limit := model.RateLimit{Rate: 50, Burst: 1}
+
+	result, err := store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	time.Sleep(result.RetryAfter + 5*time.Millisecond)
+
+	result, err = store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
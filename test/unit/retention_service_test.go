@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRetention() (*service.RetentionService, *MockUserRepository, *MockSessionRepository, *MockAuditRepository, *fakeClock) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	audit, auditRepo := setupAcceptingAudit()
+	clock := newFakeClock()
+
+	svc := service.SetupRetentionService(userRepo, sessionRepo, service.RetentionOptions{
+		Window: 30 * 24 * time.Hour,
+		Clock:  clock,
+	}, audit)
+	auditRepo.On("RedactFields", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return svc, userRepo, sessionRepo, auditRepo, clock
+}
+
+func TestRetentionService_DeleteUser(t *testing.T) {
+	svc, userRepo, sessionRepo, auditRepo, clock := setupRetention()
+	userRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", Email: "john@example.com"}, nil)
+	userRepo.On("SoftDelete", mock.Anything, "u1", clock.now).Return(nil)
+	sessionRepo.On("DeleteByUserID", mock.Anything, "u1").Return(nil)
+
+	// Customers can only delete their own account
+	assert.ErrorIs(t, svc.DeleteUser(asUser("u2", model.RoleCustomer), "u1"), service.ErrPermissionDenied)
+	userRepo.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything)
+
+	require.NoError(t, svc.DeleteUser(asUser("u1", model.RoleCustomer), "u1"))
+	userRepo.AssertExpectations(t)
+	sessionRepo.AssertExpectations(t)
+	auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		return entry.Action == model.AuditActionUserDelete && entry.Actor == "user:u1" && entry.Target == "user:u1"
+	}))
+}
+
+func TestRetentionService_DeleteUser_NotFound(t *testing.T) {
+	svc, userRepo, _, _, _ := setupRetention()
+	userRepo.On("GetByID", mock.Anything, "u1").Return(nil, repository.ErrUserNotFound)
+
+	assert.ErrorIs(t, svc.DeleteUser(asUser("u3", model.RoleAdmin), "u1"), repository.ErrUserNotFound)
+}
+
+func TestRetentionService_AnonymizeDeletedUsers(t *testing.T) {
+	svc, userRepo, _, auditRepo, clock := setupRetention()
+	userRepo.On("GetDeletedBefore", mock.Anything, clock.now.Add(-30*24*time.Hour)).
+		Return([]*model.User{{ID: "u1"}, {ID: "u2"}}, nil)
+	userRepo.On("Anonymize", mock.Anything, "u1", clock.now).Return(nil)
+	userRepo.On("Anonymize", mock.Anything, "u2", clock.now).Return(errors.New("connection reset"))
+
+	anonymized, failures, err := svc.AnonymizeDeletedUsers(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, anonymized)
+	assert.Len(t, failures, 1)
+	assert.Contains(t, failures, "u2")
+	auditRepo.AssertNumberOfCalls(t, "Append", 1)
+	auditRepo.AssertCalled(t, "Append", mock.Anything, mock.MatchedBy(func(entry *model.AuditEntry) bool {
+		// Nothing of what was removed is copied into the audit log
+		return entry.Action == model.AuditActionUserAnonymize && entry.Target == "user:u1" &&
+			entry.Actor == "system" && entry.Before == nil && entry.After == nil
+	}))
+	// Earlier entries about the user lose their personal data too
+	auditRepo.AssertCalled(t, "RedactFields", mock.Anything, "user:u1",
+		[]string{"email", "firstName", "lastName", "pendingEmail"})
+	auditRepo.AssertNotCalled(t, "RedactFields", mock.Anything, "user:u2", mock.Anything)
+}
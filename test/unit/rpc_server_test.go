@@ -0,0 +1,246 @@
+package unit
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/bowe99/phone-usage-service/api/proto/phoneusage/v1"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/api/rpc"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type rpcFixture struct {
+	userRepo  *MockUserRepository
+	cycleRepo *MockCycleRepository
+	usageRepo *MockDailyUsageRepository
+	listener  *bufconn.Listener
+	conn      *grpc.ClientConn
+}
+
+func setupRPC(t *testing.T) *rpcFixture {
+	t.Helper()
+	return setupRateLimitedRPC(t, router.RateLimits{})
+}
+
+func setupRateLimitedRPC(t *testing.T, rateLimits router.RateLimits) *rpcFixture {
+	t.Helper()
+	f := &rpcFixture{
+		userRepo:  new(MockUserRepository),
+		cycleRepo: new(MockCycleRepository),
+		usageRepo: new(MockDailyUsageRepository),
+	}
+	server, _ := rpc.SetupServer(
+		setupTestAPIKey("pus_rpc", model.Scopes...),
+		nil,
+		rateLimits,
+		service.SetupUserService(f.userRepo, nil, nil),
+		service.SetupCycleService(f.cycleRepo),
+		service.SetupDailyUsageService(f.usageRepo, f.cycleRepo, new(MockCycleSummaryRepository), nil, new(MockUsageEventBroker), nil),
+	)
+
+	f.listener = bufconn.Listen(1 << 20)
+	go server.Serve(f.listener)
+	t.Cleanup(server.Stop)
+
+	f.conn = f.dial(t, grpc.WithPerRPCCredentials(apiKeyCredentials("pus_rpc")))
+	return f
+}
+
+func (f *rpcFixture) dial(t *testing.T, opts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return f.listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// apiKeyCredentials sends an API key with every call of a test client.
+type apiKeyCredentials string
+
+func (c apiKeyCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"x-api-key": string(c)}, nil
+}
+
+func (c apiKeyCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func TestRPC_ExportCycleHistory_Streams(t *testing.T) {
+	f := setupRPC(t)
+	cycles := []*model.Cycle{
+		{ID: "cycle2", StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC)},
+		{ID: "cycle1", StartDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	f.cycleRepo.On("StreamByMDN", mock.Anything, "5551234567", mock.Anything).Return(cycles, nil)
+
+	stream, err := pb.NewCycleServiceClient(f.conn).ExportCycleHistory(context.Background(), &pb.GetCycleHistoryRequest{UserId: "user123", Mdn: "5551234567"})
+	require.NoError(t, err)
+
+	var ids []string
+	for {
+		cycle, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, cycle.GetCycleId())
+	}
+	assert.Equal(t, []string{"cycle2", "cycle1"}, ids)
+}
+
+func TestRPC_ValidationUsesRESTRules(t *testing.T) {
+	f := setupRPC(t)
+	client := pb.NewDailyUsageServiceClient(f.conn)
+
+	_, err := client.GetCurrentCycleUsage(context.Background(), &pb.LineOwnerRequest{UserId: "user123", Mdn: "555"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RecordUsage(context.Background(), &pb.RecordUsageRequest{UserId: "user123", Mdn: "5551234567", UsedMb: 10})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	f.cycleRepo.AssertNotCalled(t, "GetCurrentCycle")
+}
+
+func TestRPC_MapsApplicationErrors(t *testing.T) {
+	f := setupRPC(t)
+	f.cycleRepo.On("GetByID", mock.Anything, "missing").Return(nil, repository.ErrCycleNotFound)
+	f.cycleRepo.On("GetByID", mock.Anything, "other").Return(&model.Cycle{ID: "other", MDN: "5559999999"}, nil)
+	client := pb.NewDailyUsageServiceClient(f.conn)
+
+	_, err := client.GetCycleSummary(context.Background(), &pb.GetCycleSummaryRequest{Mdn: "5551234567", CycleId: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetCycleSummary(context.Background(), &pb.GetCycleSummaryRequest{Mdn: "5551234567", CycleId: "other"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	f.userRepo.On("GetByID", mock.Anything, "ghost").Return(nil, repository.ErrUserNotFound)
+	_, err = pb.NewUserServiceClient(f.conn).UpdateUser(context.Background(), &pb.UpdateUserRequest{Id: "ghost", FirstName: "John"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestRPC_ExportUsage(t *testing.T) {
+	f := setupRPC(t)
+	from := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	records := []*model.DailyUsage{
+		{MDN: "5551234567", UserID: "user123", UsageDate: from, UsedInMB: 120},
+		{MDN: "5551234567", UserID: "user456", UsageDate: to, UsedInMB: 80},
+	}
+	f.usageRepo.On("StreamByMDN", mock.Anything, "5551234567", from, mock.AnythingOfType("time.Time"), mock.Anything).Return(records, nil)
+
+	stream, err := pb.NewDailyUsageServiceClient(f.conn).ExportUsage(context.Background(), &pb.ExportUsageRequest{
+		Mdn: "5551234567", From: timestamppb.New(from), To: timestamppb.New(to),
+	})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "user123", first.GetUserId())
+	assert.Equal(t, 120.0, first.GetUsedMb())
+	assert.True(t, first.GetDate().AsTime().Equal(from))
+
+	second, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "user456", second.GetUserId())
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRPC_HealthCheck(t *testing.T) {
+	f := setupRPC(t)
+
+	resp, err := healthpb.NewHealthClient(f.conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: pb.DailyUsageService_ServiceDesc.ServiceName})
+
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestRPC_RequiresAPIKey(t *testing.T) {
+	f := setupRPC(t)
+	req := &pb.GetCycleHistoryRequest{UserId: "user123", Mdn: "5551234567"}
+
+	_, err := pb.NewCycleServiceClient(f.dial(t)).GetCycleHistory(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	wrongKey := f.dial(t, grpc.WithPerRPCCredentials(apiKeyCredentials("pus_wrong")))
+	_, err = pb.NewCycleServiceClient(wrongKey).GetCycleHistory(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Health checks stay open for load balancers
+	_, err = healthpb.NewHealthClient(f.dial(t)).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	f.cycleRepo.AssertNotCalled(t, "GetByMDN", mock.Anything, mock.Anything)
+}
+
+func TestRPC_EveryMethodDeclaresScopes(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{pb.UserService_ServiceDesc, pb.CycleService_ServiceDesc, pb.DailyUsageService_ServiceDesc} {
+		var methods []string
+		for _, m := range desc.Methods {
+			methods = append(methods, m.MethodName)
+		}
+		for _, s := range desc.Streams {
+			methods = append(methods, s.StreamName)
+		}
+		for _, name := range methods {
+			_, ok := rpc.MethodScopes("/" + desc.ServiceName + "/" + name)
+			assert.True(t, ok, "%s/%s has no scopes declared", desc.ServiceName, name)
+			_, ok = rpc.MethodGroup("/" + desc.ServiceName + "/" + name)
+			assert.True(t, ok, "%s/%s has no rate limit group", desc.ServiceName, name)
+		}
+	}
+}
+
+func TestRPC_RateLimitsByGroup(t *testing.T) {
+	f := setupRateLimitedRPC(t, router.RateLimits{
+		Store:   repository.SetupMemoryRateLimitStore(),
+		Default: model.RateLimit{Rate: 100, Burst: 100},
+		Groups:  map[string]model.RateLimit{"cycles": {Rate: 0.01, Burst: 1}},
+	})
+	f.cycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{}, nil)
+	client := pb.NewCycleServiceClient(f.conn)
+	req := &pb.GetCycleHistoryRequest{UserId: "user123", Mdn: "5551234567"}
+
+	var header metadata.MD
+	_, err := client.GetCycleHistory(context.Background(), req, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	_, err = client.GetCycleHistory(context.Background(), req, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	// Unauthenticated callers are limited by address before being rejected
+	anonymous := pb.NewCycleServiceClient(f.dial(t))
+	_, err = anonymous.GetCycleHistory(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = anonymous.GetCycleHistory(context.Background(), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Other groups have their own buckets
+	_, err = pb.NewDailyUsageServiceClient(f.conn).GetCycleSummary(context.Background(), &pb.GetCycleSummaryRequest{Mdn: "555"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/statement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementService_GetStatement(t *testing.T) {
+	mockUserRepo := new(MockUserRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockUsageRepo := new(MockDailyUsageRepository)
+	statementService := service.SetupStatementService(mockUserRepo, mockCycleRepo, mockUsageRepo)
+
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		MDN:       "5551234567",
+		StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 11, 30, 23, 59, 59, 0, time.UTC),
+		UserID:    "user123",
+	}
+	user := &model.User{ID: "user123", FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"}
+	usage := []*model.DailyUsage{
+		{UsageDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), UsedInMB: 250.5},
+		{UsageDate: time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), UsedInMB: 320.7},
+		{UsageDate: time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC), UsedInMB: 180.3},
+	}
+
+	mockCycleRepo.On("GetByID", mock.Anything, "cycle1").Return(cycle, nil)
+	mockUserRepo.On("GetByID", mock.Anything, "user123").Return(user, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, "user123", "5551234567", cycle.StartDate, cycle.EndDate).Return(usage, nil)
+
+	result, err := statementService.GetStatement(context.Background(), dto.GetStatementRequest{MDN: "5551234567", CycleID: "cycle1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "John", result.User.FirstName)
+	assert.Len(t, result.Usage, 3)
+	assert.InDelta(t, 751.5, result.TotalUsage, 0.0001)
+	assert.Equal(t, 320.7, result.PeakUsage)
+	assert.Equal(t, time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), *result.PeakDate)
+}
+
+func TestStatementService_GetStatement_WrongLine(t *testing.T) {
+	mockCycleRepo := new(MockCycleRepository)
+	statementService := service.SetupStatementService(new(MockUserRepository), mockCycleRepo, new(MockDailyUsageRepository))
+
+	mockCycleRepo.On("GetByID", mock.Anything, "cycle1").Return(&model.Cycle{ID: "cycle1", MDN: "5559999999"}, nil)
+
+	result, err := statementService.GetStatement(context.Background(), dto.GetStatementRequest{MDN: "5551234567", CycleID: "cycle1"})
+
+	assert.ErrorIs(t, err, service.ErrCycleNotOnLine)
+	assert.Nil(t, result)
+}
+
+func testStatement() *model.Statement {
+	peakDate := time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC)
+	usage := make([]*model.DailyUsageResponse, 0, 60)
+	for i := 0; i < 60; i++ {
+		usage = append(usage, &model.DailyUsageResponse{Date: time.Date(2024, 11, 1+i, 0, 0, 0, 0, time.UTC), Usage: float64(i)})
+	}
+	return &model.Statement{
+		User:        &model.UserResponse{FirstName: "Zoë", LastName: "O'Brien (Jr)", Email: "zoe@example.com"},
+		MDN:         "5551234567",
+		Cycle:       &model.CycleResponse{CycleID: "cycle1", StartDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+		Usage:       usage,
+		TotalUsage:  1770,
+		PeakUsage:   59,
+		PeakDate:    &peakDate,
+		GeneratedAt: time.Date(2024, 12, 31, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestStatementRenderer_RenderPDF(t *testing.T) {
+	renderer, err := statement.SetupRenderer("")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, renderer.RenderPDF(&out, testStatement()))
+
+	pdf := out.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	// 60 rows do not fit on one page
+	assert.Contains(t, pdf, "/Count 2")
+	// Parentheses are escaped and Latin-1 characters survive
+	assert.Contains(t, pdf, `O'Brien \(Jr\)`)
+	assert.Contains(t, pdf, "Zo\xeb")
+}
+
+func TestStatementRenderer_TemplateOverride(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "statement.html.tmpl"), []byte(`<p>{{.MDN}} {{mb .TotalUsage}}</p>`), 0o644))
+
+	renderer, err := statement.SetupRenderer(dir)
+	require.NoError(t, err)
+
+	var html bytes.Buffer
+	require.NoError(t, renderer.RenderHTML(&html, testStatement()))
+	assert.Equal(t, "<p>5551234567 1770.00 MB</p>", html.String())
+
+	// The PDF template was not overridden, so the built-in one is still used
+	var pdf bytes.Buffer
+	require.NoError(t, renderer.RenderPDF(&pdf, testStatement()))
+	assert.Contains(t, pdf.String(), "(Usage statement) Tj")
+}
@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/bowe99/phone-usage-service/internal/application/dtos"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUsageAnalyticsRepository struct {
+	mock.Mock
+}
+
+func (m *MockUsageAnalyticsRepository) GetTopConsumers(ctx context.Context, startDate, endDate time.Time, limit int) ([]*model.LineUsageTotal, error) {
+	args := m.Called(ctx, startDate, endDate, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.LineUsageTotal), args.Error(1)
+}
+
+func (m *MockUsageAnalyticsRepository) GetUsagePercentiles(ctx context.Context, startDate, endDate time.Time) (*model.UsagePercentiles, error) {
+	args := m.Called(ctx, startDate, endDate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UsagePercentiles), args.Error(1)
+}
+
+func (m *MockUsageAnalyticsRepository) GetUsageHistogram(ctx context.Context, startDate, endDate time.Time, buckets int) ([]*model.UsageHistogramBucket, error) {
+	args := m.Called(ctx, startDate, endDate, buckets)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UsageHistogramBucket), args.Error(1)
+}
+
+func TestUsageAnalyticsService_GetTopConsumers(t *testing.T) {
+	mockRepo := new(MockUsageAnalyticsRepository)
+	analyticsService := service.SetupUsageAnalyticsService(mockRepo)
+
+	req := dto.GetTopConsumersRequest{
+		UsageDateRange: dto.UsageDateRange{
+			From: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	expected := []*model.LineUsageTotal{
+		{MDN: "5551234567", TotalMB: 9000, DaysWithUsage: 30},
+		{MDN: "5559876543", TotalMB: 4500, DaysWithUsage: 28},
+	}
+
+	// The end date is inclusive, so the whole of November 30th is queried
+	mockRepo.On("GetTopConsumers", mock.Anything,
+		time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 11, 30, 23, 59, 59, 999999999, time.UTC),
+		10,
+	).Return(expected, nil)
+
+	result, err := analyticsService.GetTopConsumers(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUsageAnalyticsService_RequiresAnalyticsPermission(t *testing.T) {
+	mockRepo := new(MockUsageAnalyticsRepository)
+	analyticsService := service.SetupUsageAnalyticsService(mockRepo)
+	req := dto.GetUsagePercentilesRequest{
+		UsageDateRange: dto.UsageDateRange{
+			From: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// Reading every line's usage does not extend to figures across all lines
+	reader := service.WithPrincipal(context.Background(), model.APIKeyPrincipal(&model.APIKey{ID: "key1", Scopes: []string{model.ScopeUsageRead}}))
+	for _, ctx := range []context.Context{reader, asUser("u2", model.RoleSupport)} {
+		_, err := analyticsService.GetUsagePercentiles(ctx, req)
+		assert.ErrorIs(t, err, service.ErrPermissionDenied)
+	}
+	mockRepo.AssertNotCalled(t, "GetUsagePercentiles", mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("GetUsagePercentiles", mock.Anything, mock.Anything, mock.Anything).Return(&model.UsagePercentiles{}, nil)
+	analyst := service.WithPrincipal(context.Background(), model.APIKeyPrincipal(&model.APIKey{ID: "key2", Scopes: []string{model.ScopeAnalyticsRead}}))
+	for _, ctx := range []context.Context{analyst, asUser("u3", model.RoleAdmin)} {
+		_, err := analyticsService.GetUsagePercentiles(ctx, req)
+		assert.NoError(t, err)
+	}
+}
+
+func TestUsageAnalyticsService_InvalidDateRange(t *testing.T) {
+	mockRepo := new(MockUsageAnalyticsRepository)
+	analyticsService := service.SetupUsageAnalyticsService(mockRepo)
+
+	reversed := dto.GetUsagePercentilesRequest{
+		UsageDateRange: dto.UsageDateRange{
+			From: time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	_, err := analyticsService.GetUsagePercentiles(context.Background(), reversed)
+	assert.ErrorIs(t, err, service.ErrInvalidDateRange)
+
+	tooLong := dto.GetUsageHistogramRequest{
+		UsageDateRange: dto.UsageDateRange{
+			From: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	_, err = analyticsService.GetUsageHistogram(context.Background(), tooLong)
+	assert.ErrorIs(t, err, service.ErrInvalidDateRange)
+
+	mockRepo.AssertNotCalled(t, "GetUsagePercentiles", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "GetUsageHistogram", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
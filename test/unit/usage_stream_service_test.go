@@ -0,0 +1,239 @@
+package unit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/infra/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockUsageEventBroker is a mock implementation of UsageEventBroker
+type MockUsageEventBroker struct {
+	mock.Mock
+}
+
+func (m *MockUsageEventBroker) Publish(ctx context.Context, event *model.UsageEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockUsageEventBroker) Subscribe(ctx context.Context, mdn, lastEventID string) (<-chan *model.UsageEvent, error) {
+	args := m.Called(ctx, mdn, lastEventID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(chan *model.UsageEvent), args.Error(1)
+}
+
+func receiveEvent(t *testing.T, events <-chan *model.UsageEvent) *model.UsageEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "stream closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+func TestUsageStreamService_SubscribeUsage_ThresholdCrossing(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockBroker := new(MockUsageEventBroker)
+	streamService := service.SetupUsageStreamService(mockUsageRepo, mockCycleRepo, mockBroker, []float64{1000, 500})
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	cycle := &model.Cycle{
+		ID:        "cycle1",
+		MDN:       "5551234567",
+		StartDate: today.AddDate(0, 0, -5),
+		EndDate:   today.AddDate(0, 0, 25),
+		UserID:    "user123",
+	}
+	raw := make(chan *model.UsageEvent, 4)
+
+	mockBroker.On("Subscribe", mock.Anything, "5551234567", "").Return(raw, nil)
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{cycle}, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, "user123", "5551234567", cycle.StartDate, cycle.EndDate).
+		Return([]*model.DailyUsage{
+			{MDN: "5551234567", UserID: "user123", UsageDate: today.AddDate(0, 0, -1), UsedInMB: 300},
+			{MDN: "5551234567", UserID: "user123", UsageDate: today, UsedInMB: 100},
+		}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := streamService.SubscribeUsage(ctx, "5551234567", "")
+	require.NoError(t, err)
+
+	// Today goes from 100 to 250: the cycle climbs from 400 past 500
+	raw <- &model.UsageEvent{ID: "1", Type: model.UsageEventDailyUsage, MDN: "5551234567", UserID: "user123", UsageDate: today, DailyUsage: 250}
+
+	usage := receiveEvent(t, events)
+	assert.Equal(t, model.UsageEventDailyUsage, usage.Type)
+	assert.Equal(t, "cycle1", usage.CycleID)
+	assert.Equal(t, 550.0, usage.CycleUsage)
+
+	crossed := receiveEvent(t, events)
+	assert.Equal(t, model.UsageEventThreshold, crossed.Type)
+	// Each event has its own ID, and resuming after a threshold event
+	// resumes after the usage event that caused it
+	assert.Equal(t, "1:1", crossed.ID)
+	assert.Equal(t, 500.0, crossed.ThresholdMB)
+
+	// Re-sending the same value changes nothing and crosses nothing
+	raw <- &model.UsageEvent{ID: "2", Type: model.UsageEventDailyUsage, MDN: "5551234567", UserID: "user123", UsageDate: today, DailyUsage: 250}
+	raw <- &model.UsageEvent{ID: "3", Type: model.UsageEventDailyUsage, MDN: "5551234567", UserID: "user123", UsageDate: today, DailyUsage: 800}
+
+	assert.Equal(t, "2", receiveEvent(t, events).ID)
+	assert.Equal(t, 1100.0, receiveEvent(t, events).CycleUsage)
+	crossed = receiveEvent(t, events)
+	assert.Equal(t, model.UsageEventThreshold, crossed.Type)
+	assert.Equal(t, 1000.0, crossed.ThresholdMB)
+
+	close(raw)
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestUsageStreamService_SubscribeUsage_NoCurrentCycle(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockBroker := new(MockUsageEventBroker)
+	streamService := service.SetupUsageStreamService(mockUsageRepo, mockCycleRepo, mockBroker, nil)
+
+	mockBroker.On("Subscribe", mock.Anything, "5551234567", "").Return(make(chan *model.UsageEvent), nil)
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{}, nil)
+
+	events, err := streamService.SubscribeUsage(context.Background(), "5551234567", "")
+
+	assert.ErrorIs(t, err, service.ErrNoCyclesFound)
+	assert.Nil(t, events)
+	mockUsageRepo.AssertNotCalled(t, "GetByDateRange")
+}
+
+func TestUsageStreamService_SubscribeUsage_ResumesAfterThresholdEvents(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	mockBroker := new(MockUsageEventBroker)
+	streamService := service.SetupUsageStreamService(mockUsageRepo, mockCycleRepo, mockBroker, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	cycle := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123", StartDate: today.AddDate(0, 0, -5), EndDate: today.AddDate(0, 0, 25)}
+	mockBroker.On("Subscribe", mock.Anything, "5551234567", "7").Return(make(chan *model.UsageEvent), nil)
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{cycle}, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, "user123", "5551234567", cycle.StartDate, cycle.EndDate).Return([]*model.DailyUsage{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := streamService.SubscribeUsage(ctx, "5551234567", "7:2")
+
+	require.NoError(t, err)
+	mockBroker.AssertExpectations(t)
+}
+
+// TestUsageStreamService_SubscribeUsage_SharedEvents runs two subscribers
+// that track different cycles of a line off the same broker events; run it
+// with -race.
+func TestUsageStreamService_SubscribeUsage_SharedEvents(t *testing.T) {
+	mockUsageRepo := new(MockDailyUsageRepository)
+	mockCycleRepo := new(MockCycleRepository)
+	broker := repository.SetupUsageEventBroker(nil, false)
+	streamService := service.SetupUsageStreamService(mockUsageRepo, mockCycleRepo, broker, nil)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	// The line's cycle is replaced between the two subscriptions
+	first := &model.Cycle{ID: "cycle1", MDN: "5551234567", UserID: "user123", StartDate: today.AddDate(0, 0, -5), EndDate: today.AddDate(0, 0, 25)}
+	second := &model.Cycle{ID: "cycle2", MDN: "5551234567", UserID: "user123", StartDate: today, EndDate: today.AddDate(0, 0, 30)}
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{first}, nil).Once()
+	mockCycleRepo.On("GetByMDN", mock.Anything, "5551234567").Return([]*model.Cycle{second}, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, "user123", "5551234567", first.StartDate, first.EndDate).
+		Return([]*model.DailyUsage{{UsageDate: today.AddDate(0, 0, -1), UsedInMB: 300}}, nil)
+	mockUsageRepo.On("GetByDateRange", mock.Anything, "user123", "5551234567", second.StartDate, second.EndDate).
+		Return([]*model.DailyUsage{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	firstEvents, err := streamService.SubscribeUsage(ctx, "5551234567", "")
+	require.NoError(t, err)
+	secondEvents, err := streamService.SubscribeUsage(ctx, "5551234567", "")
+	require.NoError(t, err)
+
+	for i := 1; i <= 20; i++ {
+		require.NoError(t, broker.Publish(ctx, &model.UsageEvent{
+			Type: model.UsageEventDailyUsage, MDN: "5551234567", UserID: "user123", UsageDate: today, DailyUsage: float64(i),
+		}))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 20; i++ {
+			event := receiveEvent(t, firstEvents)
+			assert.Equal(t, "cycle1", event.CycleID)
+			assert.Equal(t, 300+float64(i), event.CycleUsage)
+		}
+	}()
+	for i := 1; i <= 20; i++ {
+		event := receiveEvent(t, secondEvents)
+		assert.Equal(t, "cycle2", event.CycleID)
+		assert.Equal(t, float64(i), event.CycleUsage)
+	}
+	<-done
+}
+
+func TestMemoryUsageEventBroker_ResumesAfterLastEventID(t *testing.T) {
+	broker := repository.SetupUsageEventBroker(nil, false)
+	ctx := context.Background()
+
+	// Lines nobody watches keep no history
+	require.NoError(t, broker.Publish(ctx, &model.UsageEvent{MDN: "5551234567", DailyUsage: 0}))
+
+	connected, disconnect := context.WithCancel(ctx)
+	events, err := broker.Subscribe(connected, "5551234567", "0")
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, broker.Publish(ctx, &model.UsageEvent{MDN: "5551234567", DailyUsage: float64(i)}))
+	}
+	require.NoError(t, broker.Publish(ctx, &model.UsageEvent{MDN: "5559999999", DailyUsage: 99}))
+	first := receiveEvent(t, events)
+	assert.Equal(t, 1.0, first.DailyUsage)
+
+	// The client drops after the first event and reconnects within the
+	// resume window
+	disconnect()
+	assert.Eventually(t, func() bool {
+		_, ok := <-events
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err = broker.Subscribe(ctx, "5551234567", first.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2.0, receiveEvent(t, events).DailyUsage)
+	third := receiveEvent(t, events)
+	assert.Equal(t, 3.0, third.DailyUsage)
+
+	require.NoError(t, broker.Publish(ctx, &model.UsageEvent{MDN: "5551234567", DailyUsage: 4}))
+	live := receiveEvent(t, events)
+	assert.Equal(t, 4.0, live.DailyUsage)
+	thirdID, _ := strconv.Atoi(third.ID)
+	liveID, _ := strconv.Atoi(live.ID)
+	assert.Greater(t, liveID, thirdID)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, ok := <-events
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
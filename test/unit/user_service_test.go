@@ -2,13 +2,22 @@ package unit
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
 	"github.com/bowe99/phone-usage-service/internal/application/dtos"
 	"github.com/bowe99/phone-usage-service/internal/application/service"
 	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/bowe99/phone-usage-service/internal/domain/repository"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockUserRepository struct {
@@ -46,9 +55,60 @@ func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateRoles(ctx context.Context, id string, roles []string) error {
+	args := m.Called(ctx, id, roles)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetMFA(ctx context.Context, id string, mfa *model.MFA) error {
+	args := m.Called(ctx, id, mfa)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	args := m.Called(ctx, id, step)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, id string, hash string) error {
+	args := m.Called(ctx, id, hash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetDeletedBefore(ctx context.Context, before time.Time) ([]*model.User, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Anonymize(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Search(ctx context.Context, filter model.UserFilter) ([]*model.User, string, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).([]*model.User), args.String(1), args.Error(2)
+}
+
+func (m *MockUserRepository) Rekey(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := service.SetupUserService(mockRepo)
+	userService := service.SetupUserService(mockRepo, nil, nil)
 
 	req := dto.CreateUserRequest{
 		FirstName: "John",
@@ -75,10 +135,29 @@ func TestUserService_CreateUser(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUserService_CreateUser_EmailTaken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := service.SetupUserService(mockRepo, nil, nil)
+
+	req := dto.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		Password:  "password123",
+	}
+
+	mockRepo.On("GetByEmail", mock.Anything, req.Email).Return(&model.User{ID: "507f1f77bcf86cd799439011", Email: req.Email}, nil)
+
+	_, err := userService.CreateUser(context.Background(), req)
+
+	assert.ErrorIs(t, err, service.ErrEmailAlreadyExists)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestUserService_UpdateUserProfile(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	userService := service.SetupUserService(mockRepo)
+	userService := service.SetupUserService(mockRepo, nil, nil)
 
 	userID := "507f1f77bcf86cd799439011"
 	existingUser := &model.User{
@@ -95,6 +174,7 @@ func TestUserService_UpdateUserProfile(t *testing.T) {
 	}
 
 	mockRepo.On("GetByID", mock.Anything, userID).Return(existingUser, nil)
+	mockRepo.On("GetByEmail", mock.Anything, req.Email).Return(nil, repository.ErrUserNotFound)
 	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
 
 	result, err := userService.UpdateUserProfile(context.Background(), userID, req)
@@ -103,6 +183,174 @@ func TestUserService_UpdateUserProfile(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Equal(t, req.FirstName, result.FirstName)
 	assert.Equal(t, req.LastName, result.LastName)
-	assert.Equal(t, req.Email, result.Email)
+	// The new address waits for confirmation
+	assert.Equal(t, "john.doe@example.com", result.Email)
+	assert.Equal(t, req.Email, result.PendingEmail)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestUserService_ListUsers(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := service.SetupUserService(mockRepo, nil, nil)
+	lastWeek := time.Date(2024, 4, 24, 0, 0, 0, 0, time.UTC)
+
+	mockRepo.On("Search", mock.Anything, model.UserFilter{
+		Name:        "Smith",
+		CreatedFrom: lastWeek,
+		Sort:        model.UserSortCreatedAt,
+		Descending:  true,
+		Limit:       50,
+	}).Return([]*model.User{{ID: "u1", LastName: "Smith", Password: "hash"}}, "next", nil)
+	mockRepo.On("Search", mock.Anything, model.UserFilter{
+		Sort:  model.UserSortUpdatedAt,
+		After: "next",
+		Limit: 10,
+	}).Return([]*model.User{}, "", nil)
+
+	_, err := userService.ListUsers(asUser("u1", model.RoleCustomer), dto.ListUsersRequest{})
+	assert.ErrorIs(t, err, service.ErrPermissionDenied)
+
+	_, err = userService.ListUsers(asUser("u2", model.RoleSupport), dto.ListUsersRequest{
+		CreatedFrom: lastWeek, CreatedTo: lastWeek.Add(-time.Hour),
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidDateRange)
+
+	page, err := userService.ListUsers(asUser("u2", model.RoleSupport), dto.ListUsersRequest{Name: " Smith ", CreatedFrom: lastWeek})
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Equal(t, "Smith", page.Users[0].LastName)
+	assert.Equal(t, "next", page.NextCursor)
+
+	page, err = userService.ListUsers(asUser("u3", model.RoleAdmin), dto.ListUsersRequest{Sort: "updatedAt", Cursor: "next", Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Users)
+	assert.Empty(t, page.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_PatchUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := service.SetupUserService(mockRepo, nil, nil)
+
+	mockRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{
+		ID: "u1", FirstName: "John", LastName: "Doe", Email: "john@example.com", PendingEmail: "jd@example.com", Version: 3,
+	}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *model.User) bool {
+		return user.FirstName == "John" && user.LastName == "Smith" && user.PendingEmail == "" && user.Version == 3
+	})).Return(nil)
+
+	stale := int64(2)
+	_, err := userService.PatchUser(context.Background(), "u1", dto.PatchUserRequest{IfMatch: &stale})
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+
+	lastName, current := "Smith", int64(3)
+	result, err := userService.PatchUser(context.Background(), "u1", dto.PatchUserRequest{
+		LastName: &lastName, ClearPendingEmail: true, IfMatch: &current,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "John", result.FirstName)
+	assert.Empty(t, result.PendingEmail)
+	mockRepo.AssertExpectations(t)
+
+	// Internal callers act for no user, so they have no profile
+	_, err = userService.GetCurrentUser(context.Background())
+	assert.ErrorIs(t, err, service.ErrPermissionDenied)
+}
+
+func TestUserService_UpdateUserProfile_RetriesConcurrentUpdates(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := service.SetupUserService(mockRepo, nil, nil)
+
+	mockRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", FirstName: "John", LastName: "Doe", Version: 1}, nil).Once()
+	mockRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", FirstName: "John", LastName: "Roe", Version: 2}, nil)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *model.User) bool { return user.Version == 1 })).
+		Return(repository.ErrConcurrentModification)
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *model.User) bool { return user.Version == 2 })).
+		Return(nil).Once()
+
+	// Without a version, the change is applied to what the user is now
+	result, err := userService.UpdateUserProfile(context.Background(), "u1", dto.UpdateUserRequest{FirstName: "Jane"})
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", result.FirstName)
+	assert.Equal(t, "Roe", result.LastName)
+	mockRepo.AssertNumberOfCalls(t, "Update", 2)
+
+	// A caller naming a version is told rather than overruled
+	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *model.User) bool { return user.Version == 2 })).
+		Return(repository.ErrConcurrentModification).Once()
+	version := int64(2)
+	_, err = userService.UpdateUserProfile(context.Background(), "u1", dto.UpdateUserRequest{FirstName: "Jane", IfMatch: &version})
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+	mockRepo.AssertNumberOfCalls(t, "Update", 3)
+}
+
+func TestUserService_UpdateUserProfile_LegacyVersion(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := service.SetupUserService(mockRepo, nil, nil)
+
+	// Users stored before versioning are at version 0
+	mockRepo.On("GetByID", mock.Anything, "u1").Return(&model.User{ID: "u1", FirstName: "John", LastName: "Doe"}, nil)
+	mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).
+		Return(repository.ErrConcurrentModification).Once()
+
+	// A caller naming version 0 is told rather than overruled, too
+	version := int64(0)
+	_, err := userService.UpdateUserProfile(context.Background(), "u1", dto.UpdateUserRequest{FirstName: "Jane", IfMatch: &version})
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+
+	version = 1
+	_, err = userService.UpdateUserProfile(context.Background(), "u1", dto.UpdateUserRequest{FirstName: "Jane", IfMatch: &version})
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestRouter_UserETags(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	user := &model.User{ID: "u1", FirstName: "John", LastName: "Doe", Email: "john@example.com", PendingEmail: "jd@example.com",
+		Roles: []string{model.RoleCustomer}, Version: 3}
+	userRepo.On("GetByID", mock.Anything, "u1").Return(user, nil)
+	userRepo.On("Update", mock.Anything, mock.AnythingOfType("*model.User")).
+		Run(func(args mock.Arguments) { args.Get(1).(*model.User).Version++ }).
+		Return(nil)
+	sessionRepo.On("GetByHash", mock.Anything, sha256Hex("pss_customer")).Return(&model.Session{ID: "s1", UserID: "u1"}, nil)
+
+	r := router.SetupRouter(nil, router.Config{
+		GinMode: gin.TestMode,
+		Auth:    service.SetupAuthService(userRepo, sessionRepo, nil, nil, nil, time.Hour),
+	}, router.Handlers{
+		User: handler.SetupUserHandler(service.SetupUserService(userRepo, nil, nil), nil),
+	})
+
+	serve := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer pss_customer")
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	me := serve(http.MethodGet, "/api/v1/users/me", "", "")
+	require.Equal(t, http.StatusOK, me.Code)
+	assert.Equal(t, `"3"`, me.Header().Get("ETag"))
+	assert.Contains(t, me.Body.String(), `"email":"john@example.com"`)
+	assert.Equal(t, `"3"`, serve(http.MethodGet, "/api/v1/users/u1", "", "").Header().Get("ETag"))
+
+	// Someone else changed the user since version 2 was read
+	assert.Equal(t, http.StatusPreconditionFailed, serve(http.MethodPatch, "/api/v1/users/u1", `"2"`, `{"lastName":"Smith"}`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(http.MethodPatch, "/api/v1/users/u1", `W/"3"`, `{"lastName":"Smith"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPatch, "/api/v1/users/u1", `"3"`, `{"firstName":null}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPatch, "/api/v1/users/u1", `"3"`, `{"lastName":"S"}`).Code)
+	userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+
+	patched := serve(http.MethodPatch, "/api/v1/users/u1", `"3"`, `{"lastName":"Smith","pendingEmail":null}`)
+	require.Equal(t, http.StatusOK, patched.Code)
+	assert.Equal(t, `"4"`, patched.Header().Get("ETag"))
+	assert.Equal(t, "Smith", user.LastName)
+	assert.Empty(t, user.PendingEmail)
+}
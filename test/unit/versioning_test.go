@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bowe99/phone-usage-service/internal/api/handler"
+	"github.com/bowe99/phone-usage-service/internal/api/router"
+	"github.com/bowe99/phone-usage-service/internal/application/service"
+	"github.com/bowe99/phone-usage-service/internal/domain/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_LegacyRoutesAliasV1WithDeprecationHeaders(t *testing.T) {
+	r := router.SetupRouter(nil, router.Config{GinMode: gin.TestMode, Auth: setupTestAPIKey("pus_reader", model.ScopeUsageRead)}, router.Handlers{
+		Cycle: handler.SetupCycleHandler(service.SetupCycleService(new(MockCycleRepository))),
+	})
+
+	// An invalid body is rejected before the repository is touched
+	current := httptest.NewRecorder()
+	r.ServeHTTP(current, authorized(httptest.NewRequest(http.MethodPost, "/api/v1/cycles/history", strings.NewReader(`{}`)), "pus_reader"))
+	assert.Equal(t, http.StatusBadRequest, current.Code)
+	assert.Empty(t, current.Header().Get("Deprecation"))
+	assert.Empty(t, current.Header().Get("Sunset"))
+
+	legacy := httptest.NewRecorder()
+	r.ServeHTTP(legacy, authorized(httptest.NewRequest(http.MethodPost, "/api/cycle/history", strings.NewReader(`{}`)), "pus_reader"))
+	assert.Equal(t, http.StatusBadRequest, legacy.Code)
+	assert.Regexp(t, `^@\d+$`, legacy.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", legacy.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/cycles/history>; rel="successor-version"`, legacy.Header().Get("Link"))
+
+	// Only the legacy routes keep the former path
+	plural := httptest.NewRecorder()
+	r.ServeHTTP(plural, authorized(httptest.NewRequest(http.MethodPost, "/api/cycles/history", strings.NewReader(`{}`)), "pus_reader"))
+	assert.Equal(t, http.StatusBadRequest, plural.Code)
+	assert.Equal(t, `</api/v1/cycles/history>; rel="successor-version"`, plural.Header().Get("Link"))
+	former := httptest.NewRecorder()
+	r.ServeHTTP(former, authorized(httptest.NewRequest(http.MethodPost, "/api/v1/cycle/history", strings.NewReader(`{}`)), "pus_reader"))
+	assert.Equal(t, http.StatusNotFound, former.Code)
+}
+
+func TestRoutes_WithReplacesAndAdds(t *testing.T) {
+	original := func(c *gin.Context) { c.String(http.StatusOK, "v1") }
+	replacement := func(c *gin.Context) { c.String(http.StatusOK, "v2") }
+	v1 := router.Routes{
+		{Method: http.MethodGet, Path: "/a", Handler: original},
+		{Method: http.MethodGet, Path: "/b", Handler: original},
+	}
+
+	v2 := v1.With(
+		router.Route{Method: http.MethodGet, Path: "/a", Handler: replacement},
+		router.Route{Method: http.MethodPost, Path: "/c", Handler: replacement},
+	).Without(http.MethodGet, "/b")
+
+	assert.Len(t, v1, 2, "the predecessor is left untouched")
+	assert.Len(t, v2, 2)
+	assert.Equal(t, "/a", v2[0].Path)
+	assert.Equal(t, "/c", v2[1].Path)
+
+	engine := gin.New()
+	for _, route := range v2 {
+		engine.Handle(route.Method, route.Path, route.Handler)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a", nil))
+	assert.Equal(t, "v2", w.Body.String())
+}